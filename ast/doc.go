@@ -0,0 +1,4 @@
+// Package ast defines the syntax tree of Lox programs, and printers and a JSON encoding for it.
+package ast
+
+//go:generate go run ../tool/generate_ast -output ast.go
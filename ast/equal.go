@@ -0,0 +1,229 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// Equal reports whether a and b are the same tree, ignoring the positions of their nodes and tokens,
+// so that a tree built by hand can be compared with a parsed one.
+func Equal(a, b Expr) bool {
+	if !sameNode(a, b) {
+		return false
+	}
+	return equalExprs(Children(a), Children(b))
+}
+
+// EqualStmts reports whether a and b are the same statements, ignoring positions like Equal.
+func EqualStmts(a, b []Stmt) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameNode(a[i], b[i]) || !equalExprs(StmtExprs(a[i]), StmtExprs(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalExprs(a, b []Expr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameNode reports whether a and b are nodes of the same type with the same operators and values,
+// without looking at their children.
+func sameNode(a, b interface{}) bool {
+	switch a := a.(type) {
+	case Binary:
+		b, ok := b.(Binary)
+		return ok && a.Operator.Type == b.Operator.Type
+	case Logical:
+		b, ok := b.(Logical)
+		return ok && a.Operator.Type == b.Operator.Type
+	case Comparison:
+		b, ok := b.(Comparison)
+		if !ok || len(a.Operators) != len(b.Operators) {
+			return false
+		}
+		for i := range a.Operators {
+			if a.Operators[i].Type != b.Operators[i].Type {
+				return false
+			}
+		}
+		return true
+	case Unary:
+		b, ok := b.(Unary)
+		return ok && a.Operator.Type == b.Operator.Type
+	case Literal:
+		b, ok := b.(Literal)
+		return ok && a.Value == b.Value
+	case Variable:
+		b, ok := b.(Variable)
+		return ok && a.Name.Lexeme == b.Name.Lexeme
+	case Assign:
+		b, ok := b.(Assign)
+		return ok && a.Name.Lexeme == b.Name.Lexeme
+	case VarStmt:
+		b, ok := b.(VarStmt)
+		return ok && a.Name.Lexeme == b.Name.Lexeme
+	case Get:
+		b, ok := b.(Get)
+		return ok && a.Name.Lexeme == b.Name.Lexeme
+	case Set:
+		b, ok := b.(Set)
+		return ok && a.Name.Lexeme == b.Name.Lexeme
+	case Call:
+		b, ok := b.(Call)
+		return ok && len(a.Arguments) == len(b.Arguments)
+	}
+	return fmt.Sprintf("%T", a) == fmt.Sprintf("%T", b)
+}
+
+// Dump returns the tree of expr with one node per line, its children indented below it:
+//
+//	Binary +
+//	  Literal 1
+//	  Literal 2
+func Dump(expr Expr) string {
+	var builder strings.Builder
+	dump(&builder, expr, Children(expr), 0)
+	return builder.String()
+}
+
+// DumpStmts returns the trees of statements, like Dump.
+func DumpStmts(statements []Stmt) string {
+	var builder strings.Builder
+	for _, stmt := range statements {
+		dump(&builder, stmt, StmtExprs(stmt), 0)
+	}
+	return builder.String()
+}
+
+func dump(builder *strings.Builder, node interface{}, children []Expr, depth int) {
+	builder.WriteString(strings.Repeat("  ", depth))
+	builder.WriteString(label(node))
+	builder.WriteString("\n")
+	for _, child := range children {
+		dump(builder, child, Children(child), depth+1)
+	}
+}
+
+// label describes a node without its children: its type, and its operators or value.
+func label(node interface{}) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "ast.")
+	switch node := node.(type) {
+	case Binary:
+		return name + " " + symbol(node.Operator)
+	case Logical:
+		return name + " " + symbol(node.Operator)
+	case Comparison:
+		for _, operator := range node.Operators {
+			name += " " + symbol(operator)
+		}
+		return name
+	case Unary:
+		return name + " " + symbol(node.Operator)
+	case Literal:
+		switch value := node.Value.(type) {
+		case nil:
+			return name + " nil"
+		case string:
+			return name + " " + strconv.Quote(value)
+		case float64:
+			// tell floats apart from integers
+			text := strconv.FormatFloat(value, 'g', -1, 64)
+			if !strings.ContainsAny(text, ".eIN") {
+				text += ".0"
+			}
+			return name + " " + text
+		}
+		return fmt.Sprintf("%s %v", name, node.Value)
+	case Variable:
+		return name + " " + node.Name.Lexeme
+	case Assign:
+		return name + " " + node.Name.Lexeme
+	case Get:
+		return name + " " + node.Name.Lexeme
+	case Set:
+		return name + " " + node.Name.Lexeme
+	case VarStmt:
+		return name + " " + node.Name.Lexeme
+	}
+	return name
+}
+
+// symbol returns the lexeme of an operator, or the name of its type for a token built without one.
+func symbol(operator token.Token) string {
+	if operator.Lexeme != "" {
+		return operator.Lexeme
+	}
+	return operator.Type.String()
+}
+
+// Diff returns a line diff of the dumps of a and b, or "" if they are Equal.
+// Lines only in the dump of a start with "- ", lines only in the dump of b with "+ ", and common lines with "  ".
+func Diff(a, b Expr) string {
+	if Equal(a, b) {
+		return ""
+	}
+	return diffLines(Dump(a), Dump(b))
+}
+
+// DiffStmts returns a line diff of statements, like Diff.
+func DiffStmts(a, b []Stmt) string {
+	if EqualStmts(a, b) {
+		return ""
+	}
+	return diffLines(DumpStmts(a), DumpStmts(b))
+}
+
+// diffLines diffs two texts line by line, keeping their longest common subsequence of lines.
+func diffLines(a, b string) string {
+	x := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	y := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+	// common[i][j] is the length of the longest common subsequence of x[i:] and y[j:]
+	common := make([][]int, len(x)+1)
+	for i := range common {
+		common[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+	var builder strings.Builder
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			builder.WriteString("  " + x[i] + "\n")
+			i++
+			j++
+		case j == len(y) || i < len(x) && common[i+1][j] >= common[i][j+1]:
+			builder.WriteString("- " + x[i] + "\n")
+			i++
+		default:
+			builder.WriteString("+ " + y[j] + "\n")
+			j++
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,78 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+func TestDiff(t *testing.T) {
+	number := func(n int64) Expr { return Literal{Value: n} }
+	types := map[string]token.TokenType{"+": token.PLUS, "*": token.STAR, "/": token.SLASH}
+	binary := func(lexeme string, left, right Expr) Expr {
+		return Binary{Operator: token.Token{Type: types[lexeme], Lexeme: lexeme}, Left: left, Right: right}
+	}
+	negate := func(right Expr) Expr {
+		return Unary{Operator: token.Token{Type: token.MINUS, Lexeme: "-"}, Right: right}
+	}
+	// tree is (1 + 2) * -a
+	tree := binary("*", binary("+", number(1), number(2)), negate(Variable{Name: token.Token{Lexeme: "a"}}))
+	tests := []struct {
+		name string
+		a, b Expr
+		want string
+	}{
+		{"equal trees", tree, tree, ""},
+		{
+			"positions",
+			Literal{Value: int64(1), Span: token.Span{StartOffset: 3, EndOffset: 4, Line: 1, Col: 4}},
+			number(1),
+			"",
+		},
+		{
+			"a literal",
+			tree,
+			binary("*", binary("+", number(1), number(3)), negate(Variable{Name: token.Token{Lexeme: "a"}})),
+			"  Binary *\n    Binary +\n      Literal 1\n-     Literal 2\n+     Literal 3\n    Unary -\n      Variable a\n",
+		},
+		{
+			"an integer and a float",
+			number(2),
+			Literal{Value: 2.0},
+			"- Literal 2\n+ Literal 2.0\n",
+		},
+		{
+			"an operator",
+			tree,
+			binary("/", binary("+", number(1), number(2)), negate(Variable{Name: token.Token{Lexeme: "a"}})),
+			"- Binary *\n+ Binary /\n    Binary +\n      Literal 1\n      Literal 2\n    Unary -\n      Variable a\n",
+		},
+		{
+			"a node removed",
+			tree,
+			binary("*", binary("+", number(1), number(2)), Variable{Name: token.Token{Lexeme: "a"}}),
+			"  Binary *\n    Binary +\n      Literal 1\n      Literal 2\n-   Unary -\n-     Variable a\n+   Variable a\n",
+		},
+		{
+			"a node added",
+			binary("+", number(1), number(2)),
+			binary("+", number(1), negate(number(2))),
+			"  Binary +\n    Literal 1\n-   Literal 2\n+   Unary -\n+     Literal 2\n",
+		},
+	}
+	for _, test := range tests {
+		if got := Diff(test.a, test.b); got != test.want {
+			t.Errorf("%s: the diff is\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+
+	statements := []Stmt{PrintStmt{Expr: number(1)}, PrintStmt{Expr: number(2)}}
+	if got := DiffStmts(statements, statements); got != "" {
+		t.Errorf("equal statements have the diff\n%s", got)
+	}
+	want := "  PrintStmt\n    Literal 1\n+ ExpressionStmt\n+   Literal 3\n  PrintStmt\n    Literal 2\n"
+	more := []Stmt{statements[0], ExpressionStmt{Expr: number(3)}, statements[1]}
+	if got := DiffStmts(statements, more); got != want {
+		t.Errorf("a statement added has the diff\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,416 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// Marshal encodes statements as JSON. Every node is an object with its "type" and "span",
+// so Unmarshal gives back the same tree, positions included.
+func Marshal(statements []Stmt) ([]byte, error) {
+	encoder := &astEncoder{}
+	nodes := make([]*jsonNode, len(statements))
+	for i, stmt := range statements {
+		nodes[i] = encoder.stmt(stmt)
+	}
+	return json.MarshalIndent(nodes, "", "  ")
+}
+
+// Unmarshal decodes statements encoded by Marshal.
+func Unmarshal(data []byte) ([]Stmt, error) {
+	var nodes []*jsonNode
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// numbers are kept as text so that integers do not go through float64
+	decoder.UseNumber()
+	if err := decoder.Decode(&nodes); err != nil {
+		return nil, err
+	}
+	statements := make([]Stmt, len(nodes))
+	for i, node := range nodes {
+		stmt, err := node.stmt()
+		if err != nil {
+			return nil, err
+		}
+		statements[i] = stmt
+	}
+	return statements, nil
+}
+
+type jsonNode struct {
+	Type      string      `json:"type"`
+	Span      token.Span  `json:"span"`
+	Name      *jsonToken  `json:"name,omitempty"`
+	Operator  *jsonToken  `json:"operator,omitempty"`
+	Operators []jsonToken `json:"operators,omitempty"`
+	Left      *jsonNode   `json:"left,omitempty"`
+	Right     *jsonNode   `json:"right,omitempty"`
+	Expr      *jsonNode   `json:"expr,omitempty"`
+	Operands  []*jsonNode `json:"operands,omitempty"`
+	Value     *jsonValue  `json:"value,omitempty"`
+	Callee    *jsonNode   `json:"callee,omitempty"`
+	Paren     *jsonToken  `json:"paren,omitempty"`
+	Arguments []*jsonNode `json:"arguments,omitempty"`
+	Object    *jsonNode   `json:"object,omitempty"`
+}
+
+type jsonToken struct {
+	Type    string     `json:"type"`
+	Lexeme  string     `json:"lexeme"`
+	Literal *jsonValue `json:"literal,omitempty"`
+	Span    token.Span `json:"span"`
+}
+
+// jsonValue is a literal value with its kind, which JSON alone cannot tell for integers and floats.
+type jsonValue struct {
+	Kind  string      `json:"kind"`
+	Value interface{} `json:"value"`
+}
+
+// astEncoder turns nodes into their JSON form.
+type astEncoder struct {
+}
+
+func (enc *astEncoder) stmt(stmt Stmt) *jsonNode {
+	return AcceptStmt[*jsonNode](stmt, enc)
+}
+
+func (enc *astEncoder) expr(expr Expr) *jsonNode {
+	return AcceptExpr[*jsonNode](expr, enc)
+}
+
+func (enc *astEncoder) VisitExpressionStmt(stmt ExpressionStmt) *jsonNode {
+	return &jsonNode{Type: "ExpressionStmt", Span: stmt.Span, Expr: enc.expr(stmt.Expr)}
+}
+
+func (enc *astEncoder) VisitPrintStmt(stmt PrintStmt) *jsonNode {
+	return &jsonNode{Type: "PrintStmt", Span: stmt.Span, Expr: enc.expr(stmt.Expr)}
+}
+
+func (enc *astEncoder) VisitSpawnStmt(stmt SpawnStmt) *jsonNode {
+	return &jsonNode{Type: "SpawnStmt", Span: stmt.Span, Expr: enc.expr(stmt.Call)}
+}
+
+func (enc *astEncoder) VisitVarStmt(stmt VarStmt) *jsonNode {
+	node := &jsonNode{Type: "VarStmt", Span: stmt.Span, Name: encodeToken(stmt.Name)}
+	if stmt.Initializer != nil {
+		node.Expr = enc.expr(stmt.Initializer)
+	}
+	return node
+}
+
+func (enc *astEncoder) VisitBinaryExpr(expr Binary) *jsonNode {
+	return &jsonNode{
+		Type:     "Binary",
+		Span:     expr.Span,
+		Operator: encodeToken(expr.Operator),
+		Left:     enc.expr(expr.Left),
+		Right:    enc.expr(expr.Right),
+	}
+}
+
+func (enc *astEncoder) VisitComparisonExpr(expr Comparison) *jsonNode {
+	node := &jsonNode{Type: "Comparison", Span: expr.Span}
+	for _, operator := range expr.Operators {
+		node.Operators = append(node.Operators, *encodeToken(operator))
+	}
+	for _, operand := range expr.Operands {
+		node.Operands = append(node.Operands, enc.expr(operand))
+	}
+	return node
+}
+
+func (enc *astEncoder) VisitGroupingExpr(expr Grouping) *jsonNode {
+	return &jsonNode{Type: "Grouping", Span: expr.Span, Expr: enc.expr(expr.Expr)}
+}
+
+func (enc *astEncoder) VisitLiteralExpr(expr Literal) *jsonNode {
+	return &jsonNode{Type: "Literal", Span: expr.Span, Value: encodeValue(expr.Value)}
+}
+
+func (enc *astEncoder) VisitLogicalExpr(expr Logical) *jsonNode {
+	return &jsonNode{
+		Type:     "Logical",
+		Span:     expr.Span,
+		Operator: encodeToken(expr.Operator),
+		Left:     enc.expr(expr.Left),
+		Right:    enc.expr(expr.Right),
+	}
+}
+
+func (enc *astEncoder) VisitUnaryExpr(expr Unary) *jsonNode {
+	return &jsonNode{
+		Type:     "Unary",
+		Span:     expr.Span,
+		Operator: encodeToken(expr.Operator),
+		Right:    enc.expr(expr.Right),
+	}
+}
+
+func (enc *astEncoder) VisitVariableExpr(expr Variable) *jsonNode {
+	return &jsonNode{Type: "Variable", Span: expr.Span, Name: encodeToken(expr.Name)}
+}
+
+func (enc *astEncoder) VisitAssignExpr(expr Assign) *jsonNode {
+	return &jsonNode{Type: "Assign", Span: expr.Span, Name: encodeToken(expr.Name), Expr: enc.expr(expr.Value)}
+}
+
+func (enc *astEncoder) VisitGetExpr(expr Get) *jsonNode {
+	return &jsonNode{Type: "Get", Span: expr.Span, Object: enc.expr(expr.Object), Name: encodeToken(expr.Name)}
+}
+
+func (enc *astEncoder) VisitSetExpr(expr Set) *jsonNode {
+	return &jsonNode{Type: "Set", Span: expr.Span, Object: enc.expr(expr.Object), Name: encodeToken(expr.Name), Expr: enc.expr(expr.Value)}
+}
+
+func (enc *astEncoder) VisitCallExpr(expr Call) *jsonNode {
+	node := &jsonNode{Type: "Call", Span: expr.Span, Callee: enc.expr(expr.Callee), Paren: encodeToken(expr.Paren)}
+	for _, argument := range expr.Arguments {
+		node.Arguments = append(node.Arguments, enc.expr(argument))
+	}
+	return node
+}
+
+func encodeToken(tok token.Token) *jsonToken {
+	encoded := &jsonToken{Type: tok.Type.String(), Lexeme: tok.Lexeme, Span: tok.Span}
+	if tok.Literal != nil {
+		encoded.Literal = encodeValue(tok.Literal)
+	}
+	return encoded
+}
+
+func encodeValue(value interface{}) *jsonValue {
+	switch value := value.(type) {
+	case nil:
+		return &jsonValue{Kind: "nil"}
+	case bool:
+		return &jsonValue{Kind: "bool", Value: value}
+	case int64:
+		return &jsonValue{Kind: "int", Value: value}
+	case float64:
+		return &jsonValue{Kind: "float", Value: value}
+	case string:
+		return &jsonValue{Kind: "string", Value: value}
+	}
+	panic(fmt.Sprintf("unknown literal value: %v", value))
+}
+
+func (node *jsonNode) stmt() (Stmt, error) {
+	if node == nil {
+		return nil, fmt.Errorf("missing statement")
+	}
+	switch node.Type {
+	case "ExpressionStmt":
+		expr, err := node.Expr.expr()
+		if err != nil {
+			return nil, err
+		}
+		return ExpressionStmt{Expr: expr, Span: node.Span}, nil
+	case "PrintStmt":
+		expr, err := node.Expr.expr()
+		if err != nil {
+			return nil, err
+		}
+		return PrintStmt{Expr: expr, Span: node.Span}, nil
+	case "SpawnStmt":
+		expr, err := node.Expr.expr()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := expr.(Call); !ok {
+			return nil, fmt.Errorf("spawn of %T, not of a call", expr)
+		}
+		return SpawnStmt{Call: expr, Span: node.Span}, nil
+	case "VarStmt":
+		name, err := node.Name.tok()
+		if err != nil {
+			return nil, err
+		}
+		stmt := VarStmt{Name: name, Span: node.Span}
+		if node.Expr != nil {
+			if stmt.Initializer, err = node.Expr.expr(); err != nil {
+				return nil, err
+			}
+		}
+		return stmt, nil
+	}
+	return nil, fmt.Errorf("unknown statement type %q", node.Type)
+}
+
+func (node *jsonNode) expr() (Expr, error) {
+	if node == nil {
+		return nil, fmt.Errorf("missing expression")
+	}
+	switch node.Type {
+	case "Binary", "Logical":
+		operator, err := node.Operator.tok()
+		if err != nil {
+			return nil, err
+		}
+		left, err := node.Left.expr()
+		if err != nil {
+			return nil, err
+		}
+		right, err := node.Right.expr()
+		if err != nil {
+			return nil, err
+		}
+		if node.Type == "Logical" {
+			return Logical{Operator: operator, Left: left, Right: right, Span: node.Span}, nil
+		}
+		return Binary{Operator: operator, Left: left, Right: right, Span: node.Span}, nil
+	case "Comparison":
+		if len(node.Operands) != len(node.Operators)+1 {
+			return nil, fmt.Errorf("comparison with %d operators needs %d operands", len(node.Operators), len(node.Operators)+1)
+		}
+		expr := Comparison{Span: node.Span}
+		for i := range node.Operators {
+			operator, err := node.Operators[i].tok()
+			if err != nil {
+				return nil, err
+			}
+			expr.Operators = append(expr.Operators, operator)
+		}
+		for _, operand := range node.Operands {
+			operand, err := operand.expr()
+			if err != nil {
+				return nil, err
+			}
+			expr.Operands = append(expr.Operands, operand)
+		}
+		return expr, nil
+	case "Grouping":
+		expr, err := node.Expr.expr()
+		if err != nil {
+			return nil, err
+		}
+		return Grouping{Expr: expr, Span: node.Span}, nil
+	case "Literal":
+		value, err := node.Value.value()
+		if err != nil {
+			return nil, err
+		}
+		return Literal{Value: value, Span: node.Span}, nil
+	case "Unary":
+		operator, err := node.Operator.tok()
+		if err != nil {
+			return nil, err
+		}
+		right, err := node.Right.expr()
+		if err != nil {
+			return nil, err
+		}
+		return Unary{Operator: operator, Right: right, Span: node.Span}, nil
+	case "Variable":
+		name, err := node.Name.tok()
+		if err != nil {
+			return nil, err
+		}
+		return Variable{Name: name, Span: node.Span}, nil
+	case "Assign":
+		name, err := node.Name.tok()
+		if err != nil {
+			return nil, err
+		}
+		value, err := node.Expr.expr()
+		if err != nil {
+			return nil, err
+		}
+		return Assign{Name: name, Value: value, Span: node.Span}, nil
+	case "Get", "Set":
+		object, err := node.Object.expr()
+		if err != nil {
+			return nil, err
+		}
+		name, err := node.Name.tok()
+		if err != nil {
+			return nil, err
+		}
+		if node.Type == "Get" {
+			return Get{Object: object, Name: name, Span: node.Span}, nil
+		}
+		value, err := node.Expr.expr()
+		if err != nil {
+			return nil, err
+		}
+		return Set{Object: object, Name: name, Value: value, Span: node.Span}, nil
+	case "Call":
+		callee, err := node.Callee.expr()
+		if err != nil {
+			return nil, err
+		}
+		paren, err := node.Paren.tok()
+		if err != nil {
+			return nil, err
+		}
+		expr := Call{Callee: callee, Paren: paren, Span: node.Span}
+		for _, argument := range node.Arguments {
+			argument, err := argument.expr()
+			if err != nil {
+				return nil, err
+			}
+			expr.Arguments = append(expr.Arguments, argument)
+		}
+		return expr, nil
+	}
+	return nil, fmt.Errorf("unknown expression type %q", node.Type)
+}
+
+func (tok *jsonToken) tok() (token.Token, error) {
+	if tok == nil {
+		return token.Token{}, fmt.Errorf("missing token")
+	}
+	typ, ok := tokenTypes[tok.Type]
+	if !ok {
+		return token.Token{}, fmt.Errorf("unknown token type %q", tok.Type)
+	}
+	decoded := token.Token{Type: typ, Lexeme: tok.Lexeme, Span: tok.Span}
+	if tok.Literal != nil {
+		literal, err := tok.Literal.value()
+		if err != nil {
+			return token.Token{}, err
+		}
+		decoded.Literal = literal
+	}
+	return decoded, nil
+}
+
+func (value *jsonValue) value() (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("missing value")
+	}
+	switch value.Kind {
+	case "nil":
+		return nil, nil
+	case "bool":
+		if v, ok := value.Value.(bool); ok {
+			return v, nil
+		}
+	case "string":
+		if v, ok := value.Value.(string); ok {
+			return v, nil
+		}
+	case "int":
+		if v, ok := value.Value.(json.Number); ok {
+			return v.Int64()
+		}
+	case "float":
+		if v, ok := value.Value.(json.Number); ok {
+			return v.Float64()
+		}
+	default:
+		return nil, fmt.Errorf("unknown value kind %q", value.Kind)
+	}
+	return nil, fmt.Errorf("bad %s value %v", value.Kind, value.Value)
+}
+
+// tokenTypes maps the names of the token types back to them.
+var tokenTypes = func() map[string]token.TokenType {
+	types := make(map[string]token.TokenType)
+	for typ := token.TokenType(0); typ <= token.EOF; typ++ {
+		types[typ.String()] = typ
+	}
+	return types
+}()
@@ -0,0 +1,57 @@
+package ast_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+)
+
+// TestJSONRoundTrip checks that Unmarshal gives back the statements Marshal encoded, with the positions
+// of their nodes and tokens, for every kind of node.
+func TestJSONRoundTrip(t *testing.T) {
+	sources := []string{
+		"print 1; print 2.0; print 1e300; print 0xFF; print \"s\\t\"; print \"\"; print nil; print true; print false;",
+		"print 9223372036854775807; print 0.1; print -0.0; print !true;",
+		"var a; var b = 1;\nprint a = b = 2;\nprint a ?? b or a and b;",
+		"print 1 + 2 * (3 - 4) / 5; print 1 < 2 <= 3 == true != false;",
+		"o.p = f(1, \"two\")(3).q;\nprint o.p.q;\nspawn f(o);\nf();",
+		"print\n  1\n  +\n  2;",
+	}
+	sources = append(sources, parser.FuzzSeeds...)
+	for _, source := range sources {
+		statements, errs := parser.ParseSource([]byte(source))
+		if len(errs) > 0 {
+			continue
+		}
+		data, err := ast.Marshal(statements)
+		if err != nil {
+			t.Fatalf("%q: %v", source, err)
+		}
+		again, err := ast.Unmarshal(data)
+		if err != nil {
+			t.Fatalf("%q: the JSON\n%s\ndoes not decode: %v", source, data, err)
+		}
+		if !ast.EqualStmts(statements, again) {
+			t.Errorf("%q came back as\n%s", source, ast.DiffStmts(statements, again))
+		} else if len(statements) > 0 && !reflect.DeepEqual(statements, again) {
+			t.Errorf("%q came back with other positions or literals:\n%#v\nwant\n%#v", source, again, statements)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	inputs := []string{
+		"",
+		"{}",
+		`[{"type": "Nope"}]`,
+		`[{"type": "PrintStmt"}]`,
+		`[{"type": "PrintStmt", "expr": {"type": "Literal", "value": {"kind": "int", "value": 1.5}}}]`,
+	}
+	for _, input := range inputs {
+		if statements, err := ast.Unmarshal([]byte(input)); err == nil {
+			t.Errorf("%s decoded as %#v", input, statements)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Printer prints an expression as a parenthesized, Lisp like tree.
+type Printer struct {
+}
+
+// Print returns the tree of expr.
+func (astp Printer) Print(expr Expr) string {
+	return AcceptExpr[string](expr, astp)
+}
+
+func (astp Printer) VisitBinaryExpr(expr Binary) string {
+	return astp.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+func (astp Printer) VisitComparisonExpr(expr Comparison) string {
+	var builder strings.Builder
+	builder.WriteString("(chain ")
+	builder.WriteString(astp.Print(expr.Operands[0]))
+	for i, operator := range expr.Operators {
+		builder.WriteString(" ")
+		builder.WriteString(operator.Lexeme)
+		builder.WriteString(" ")
+		builder.WriteString(astp.Print(expr.Operands[i+1]))
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
+
+func (astp Printer) VisitGroupingExpr(expr Grouping) string {
+	return astp.parenthesize("group", expr.Expr)
+}
+
+func (astp Printer) VisitLiteralExpr(expr Literal) string {
+	if expr.Value == nil {
+		return "nil"
+	}
+	return fmt.Sprintf("%v", expr.Value)
+}
+
+func (astp Printer) VisitLogicalExpr(expr Logical) string {
+	return astp.parenthesize(expr.Operator.Lexeme, expr.Left, expr.Right)
+}
+
+func (astp Printer) VisitUnaryExpr(expr Unary) string {
+	return astp.parenthesize(expr.Operator.Lexeme, expr.Right)
+}
+
+func (astp Printer) VisitVariableExpr(expr Variable) string {
+	return expr.Name.Lexeme
+}
+
+func (astp Printer) VisitAssignExpr(expr Assign) string {
+	return astp.parenthesize("= "+expr.Name.Lexeme, expr.Value)
+}
+
+func (astp Printer) VisitCallExpr(expr Call) string {
+	return astp.parenthesize("call", append([]Expr{expr.Callee}, expr.Arguments...)...)
+}
+
+func (astp Printer) VisitGetExpr(expr Get) string {
+	return astp.parenthesize("."+expr.Name.Lexeme, expr.Object)
+}
+
+func (astp Printer) VisitSetExpr(expr Set) string {
+	return astp.parenthesize("= ."+expr.Name.Lexeme, expr.Object, expr.Value)
+}
+
+func (astp Printer) parenthesize(name string, exprs ...Expr) string {
+	var builder strings.Builder
+	builder.WriteString("(")
+	builder.WriteString(name)
+	for _, expr := range exprs {
+		builder.WriteString(" ")
+		builder.WriteString(astp.Print(expr))
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
@@ -0,0 +1,67 @@
+package ast
+
+import "fmt"
+
+// Children returns the child expressions of expr, in source order.
+func Children(expr Expr) []Expr {
+	return exprChildExprs(expr)
+}
+
+// WithChildren returns a copy of expr whose child expressions are children, given in the order Children returns them.
+// expr itself is not changed, so trees can share the nodes that a rewrite does not change.
+func WithChildren(expr Expr, children []Expr) Expr {
+	if n := len(exprChildExprs(expr)); n != len(children) {
+		panic(fmt.Sprintf("%T has %d children, not %d", expr, n, len(children)))
+	}
+	return withExprChildExprs(expr, children)
+}
+
+// StmtExprs returns the expressions of stmt, in source order.
+func StmtExprs(stmt Stmt) []Expr {
+	return stmtChildExprs(stmt)
+}
+
+// WithStmtExprs returns a copy of stmt whose expressions are exprs, given in the order StmtExprs returns them.
+func WithStmtExprs(stmt Stmt, exprs []Expr) Stmt {
+	if n := len(stmtChildExprs(stmt)); n != len(exprs) {
+		panic(fmt.Sprintf("%T has %d expressions, not %d", stmt, n, len(exprs)))
+	}
+	return withStmtChildExprs(stmt, exprs)
+}
+
+// Rewrite rewrites expr bottom up: it rewrites its children, and calls f with a copy of expr that has the
+// rewritten children. It returns what f returns, which is the node itself when f has nothing to change.
+// A pass like constant folding is then only a function of a node whose children are already folded.
+func Rewrite(expr Expr, f func(Expr) Expr) Expr {
+	children := exprChildExprs(expr)
+	if len(children) > 0 {
+		rewritten := make([]Expr, len(children))
+		for i, child := range children {
+			rewritten[i] = Rewrite(child, f)
+		}
+		expr = withExprChildExprs(expr, rewritten)
+	}
+	return f(expr)
+}
+
+// RewriteStmt returns a copy of stmt whose expressions are rewritten by Rewrite with f.
+func RewriteStmt(stmt Stmt, f func(Expr) Expr) Stmt {
+	exprs := stmtChildExprs(stmt)
+	if len(exprs) == 0 {
+		return stmt
+	}
+	rewritten := make([]Expr, len(exprs))
+	for i, expr := range exprs {
+		rewritten[i] = Rewrite(expr, f)
+	}
+	return withStmtChildExprs(stmt, rewritten)
+}
+
+// RewriteStmts rewrites every statement with RewriteStmt.
+func RewriteStmts(statements []Stmt, f func(Expr) Expr) []Stmt {
+	rewritten := make([]Stmt, len(statements))
+	for i, stmt := range statements {
+		rewritten[i] = RewriteStmt(stmt, f)
+	}
+	return rewritten
+}
@@ -0,0 +1,198 @@
+package ast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// SourcePrinter turns an AST back into Lox source with canonical spacing:
+// one statement per line and single spaces around binary operators.
+// It adds the parentheses that precedence requires, so trees built by hand print correctly too.
+type SourcePrinter struct {
+}
+
+// Print returns the source of statements.
+func (sp SourcePrinter) Print(statements []Stmt) string {
+	var builder strings.Builder
+	for _, stmt := range statements {
+		builder.WriteString(AcceptStmt[string](stmt, sp))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// PrintExpr returns the source of expr.
+func (sp SourcePrinter) PrintExpr(expr Expr) string {
+	return AcceptExpr[string](expr, sp)
+}
+
+func (sp SourcePrinter) VisitExpressionStmt(stmt ExpressionStmt) string {
+	return sp.PrintExpr(stmt.Expr) + ";"
+}
+
+func (sp SourcePrinter) VisitPrintStmt(stmt PrintStmt) string {
+	return "print " + sp.PrintExpr(stmt.Expr) + ";"
+}
+
+func (sp SourcePrinter) VisitSpawnStmt(stmt SpawnStmt) string {
+	return "spawn " + sp.PrintExpr(stmt.Call) + ";"
+}
+
+func (sp SourcePrinter) VisitVarStmt(stmt VarStmt) string {
+	if stmt.Initializer == nil {
+		return "var " + stmt.Name.Lexeme + ";"
+	}
+	return "var " + stmt.Name.Lexeme + " = " + sp.PrintExpr(stmt.Initializer) + ";"
+}
+
+// Precedence levels of the grammar, from the loosest to the tightest binding.
+const (
+	precedenceAssignment = iota + 1
+	precedenceCoalesce
+	precedenceOr
+	precedenceAnd
+	precedenceEquality
+	precedenceComparison
+	precedenceTerm
+	precedenceFactor
+	precedenceUnary
+	precedenceCall
+	precedencePrimary
+)
+
+// binaryPrecedence returns the precedence level of a binary operator.
+func binaryPrecedence(operator token.TokenType) int {
+	switch operator {
+	case token.QUESTION_QUESTION:
+		return precedenceCoalesce
+	case token.OR:
+		return precedenceOr
+	case token.AND:
+		return precedenceAnd
+	case token.EQUAL_EQUAL, token.BANG_EQUAL:
+		return precedenceEquality
+	case token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL:
+		return precedenceComparison
+	case token.PLUS, token.MINUS:
+		return precedenceTerm
+	}
+	return precedenceFactor
+}
+
+func precedence(expr Expr) int {
+	switch expr := expr.(type) {
+	case Binary:
+		return binaryPrecedence(expr.Operator.Type)
+	case Logical:
+		return binaryPrecedence(expr.Operator.Type)
+	case Comparison:
+		return precedenceComparison
+	case Unary:
+		return precedenceUnary
+	case Assign, Set:
+		return precedenceAssignment
+	case Call, Get:
+		return precedenceCall
+	}
+	return precedencePrimary
+}
+
+// operand prints expr, in parentheses if it binds looser than min.
+func (sp SourcePrinter) operand(expr Expr, min int) string {
+	text := sp.PrintExpr(expr)
+	if precedence(expr) < min {
+		return "(" + text + ")"
+	}
+	return text
+}
+
+// binary prints a left associative binary expression.
+// Comparisons do not associate: their operands are one level tighter,
+// so that (a < b) < c does not print as the chain a < b < c.
+func (sp SourcePrinter) binary(operator token.Token, left, right Expr) string {
+	level := binaryPrecedence(operator.Type)
+	leftMin := level
+	if level == precedenceComparison {
+		leftMin++
+	}
+	return sp.operand(left, leftMin) + " " + operator.Lexeme + " " + sp.operand(right, level+1)
+}
+
+func (sp SourcePrinter) VisitBinaryExpr(expr Binary) string {
+	return sp.binary(expr.Operator, expr.Left, expr.Right)
+}
+
+func (sp SourcePrinter) VisitComparisonExpr(expr Comparison) string {
+	var builder strings.Builder
+	builder.WriteString(sp.operand(expr.Operands[0], precedenceComparison+1))
+	for i, operator := range expr.Operators {
+		builder.WriteString(" " + operator.Lexeme + " ")
+		builder.WriteString(sp.operand(expr.Operands[i+1], precedenceComparison+1))
+	}
+	return builder.String()
+}
+
+func (sp SourcePrinter) VisitGroupingExpr(expr Grouping) string {
+	return "(" + sp.PrintExpr(expr.Expr) + ")"
+}
+
+func (sp SourcePrinter) VisitLiteralExpr(expr Literal) string {
+	switch value := expr.Value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return `"` + value + `"`
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case float64:
+		format := byte('f')
+		if math.Abs(value) >= 1e21 {
+			format = 'g'
+		}
+		// keep a float a float when the source is scanned again
+		text := strconv.FormatFloat(value, format, -1, 64)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		return text
+	}
+	return fmt.Sprint(expr.Value)
+}
+
+func (sp SourcePrinter) VisitLogicalExpr(expr Logical) string {
+	return sp.binary(expr.Operator, expr.Left, expr.Right)
+}
+
+func (sp SourcePrinter) VisitUnaryExpr(expr Unary) string {
+	return expr.Operator.Lexeme + sp.operand(expr.Right, precedenceUnary)
+}
+
+func (sp SourcePrinter) VisitVariableExpr(expr Variable) string {
+	return expr.Name.Lexeme
+}
+
+// VisitAssignExpr prints an assignment, which is right associative: a = b = c needs no parentheses.
+func (sp SourcePrinter) VisitAssignExpr(expr Assign) string {
+	return expr.Name.Lexeme + " = " + sp.operand(expr.Value, precedenceAssignment)
+}
+
+func (sp SourcePrinter) VisitGetExpr(expr Get) string {
+	return sp.operand(expr.Object, precedenceCall) + "." + expr.Name.Lexeme
+}
+
+// VisitSetExpr prints an assignment to a property, right associative like VisitAssignExpr.
+func (sp SourcePrinter) VisitSetExpr(expr Set) string {
+	return sp.operand(expr.Object, precedenceCall) + "." + expr.Name.Lexeme + " = " + sp.operand(expr.Value, precedenceAssignment)
+}
+
+func (sp SourcePrinter) VisitCallExpr(expr Call) string {
+	arguments := make([]string, len(expr.Arguments))
+	for i, argument := range expr.Arguments {
+		arguments[i] = sp.PrintExpr(argument)
+	}
+	return sp.operand(expr.Callee, precedenceCall) + "(" + strings.Join(arguments, ", ") + ")"
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/compiled"
+	"github.com/gadumitrachioaiei/go-lox/diag"
+	"github.com/gadumitrachioaiei/go-lox/transpile"
+	"github.com/gadumitrachioaiei/go-lox/vm"
+)
+
+// modulePath is the module of lox, whose compiled package the programs of lox build import.
+const modulePath = "github.com/gadumitrachioaiei/go-lox"
+
+// buildFile translates the script at path to Go and compiles it with the Go toolchain to an executable
+// at output, or only writes the Go source if output ends with .go. Without output, the executable is named
+// after the script, in the current directory.
+func buildFile(path, output string, options compiled.Options) {
+	source, statements := parseFile(path)
+	code, err := transpile.Go(filepath.Base(path), source, statements, options)
+	if err != nil {
+		log.Fatalf("translating to Go: %v", err)
+	}
+	if output == "" {
+		output = strings.TrimSuffix(filepath.Base(path), ".lox")
+	}
+	if strings.HasSuffix(output, ".go") {
+		if err := ioutil.WriteFile(output, code, 0o644); err != nil {
+			log.Fatalf("writing Go source: %v", err)
+		}
+		return
+	}
+	if err := compileGo(code, output); err != nil {
+		log.Fatalf("building %s: %v", output, err)
+	}
+}
+
+// compileGo builds the main package code to the executable output, in a module of its own that requires
+// the module of lox.
+func compileGo(code []byte, output string) error {
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return err
+	}
+	requirement, err := loxModule()
+	if err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "lox-build")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	goMod := "module lox-build\n\ngo 1.18\n\n" + requirement
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(goMod), 0o644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), code, 0o644); err != nil {
+		return err
+	}
+	// -mod=mod writes the go.sum of a released version
+	cmd := exec.Command("go", "build", "-mod=mod", "-o", output, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off")
+	cmd.Stdout, cmd.Stderr = stderr, stderr
+	return cmd.Run()
+}
+
+// loxModule returns the go.mod lines that require the module of lox: its source, if the current directory
+// is in it, as when working on lox, or else the released version lox was built from.
+func loxModule() (string, error) {
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", modulePath).Output()
+	if dir := strings.TrimSpace(string(out)); err == nil && dir != "" {
+		return fmt.Sprintf("require %s v0.0.0\n\nreplace %s => %s\n", modulePath, modulePath, dir), nil
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path != modulePath || info.Main.Version == "(devel)" || strings.HasSuffix(info.Main.Version, "+dirty") {
+		return "", fmt.Errorf("lox is not a released version, so it must run in the source of %s", modulePath)
+	}
+	return fmt.Sprintf("require %s %s\n", modulePath, info.Main.Version), nil
+}
+
+// compileFile compiles the script at path to bytecode, which it writes in the .loxc format to output,
+// or next to the script, with the .loxc extension.
+func compileFile(path, output string) {
+	source, statements := parseFile(path)
+	chunk, err := vm.Compile(statements)
+	if err != nil {
+		fmt.Fprintln(stderr, diag.Format(source, err))
+		os.Exit(exitSyntaxError)
+	}
+	data, err := vm.Marshal(chunk, source)
+	if err != nil {
+		log.Fatalf("encoding bytecode: %v", err)
+	}
+	if output == "" {
+		output = strings.TrimSuffix(path, ".lox") + ".loxc"
+	}
+	if err := ioutil.WriteFile(output, data, 0o644); err != nil {
+		log.Fatalf("writing bytecode: %v", err)
+	}
+}
+
+// runCompiledFile runs the chunk of the .loxc file at path with machine.
+func runCompiledFile(machine *vm.VM, path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatalf("reading file: %v", err)
+	}
+	chunk, source, err := vm.Unmarshal(data)
+	if err != nil {
+		log.Fatalf("reading %s: %v", path, err)
+	}
+	if _, err := machine.Run(chunk); err != nil && !reportRuntimeError(source, err) {
+		setExitStatus(exitRuntimeError)
+	}
+}
+
+// disassembleFile prints the bytecode of the file at path: the chunk of a .loxc file, or else of the
+// script it compiles.
+func disassembleFile(path string) {
+	var chunk *vm.Chunk
+	var source string
+	if strings.HasSuffix(path, ".loxc") {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatalf("reading file: %v", err)
+		}
+		if chunk, source, err = vm.Unmarshal(data); err != nil {
+			log.Fatalf("reading %s: %v", path, err)
+		}
+	} else {
+		var statements []ast.Stmt
+		source, statements = parseFile(path)
+		var err error
+		if chunk, err = vm.Compile(statements); err != nil {
+			fmt.Fprintln(stderr, diag.Format(source, err))
+			os.Exit(exitSyntaxError)
+		}
+	}
+	fmt.Fprint(stdout, vm.Disassemble(chunk, source))
+}
@@ -0,0 +1,176 @@
+// Package compiled is the runtime of the Go programs that lox build translates Lox scripts to.
+// A program uses the values and the operators of the tree-walking interpreter, so it gives the same
+// results and errors, and a runtime error stops it with its message and source line, like the CLI.
+package compiled
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gadumitrachioaiei/go-lox/diag"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// exitRuntimeError is the exit status of a program stopped by a runtime error, as in the reference implementation.
+const exitRuntimeError = 70
+
+// Options are the options of the interpreter that the script was translated with.
+type Options struct {
+	CoerceStrings bool
+	AllowFiles    bool
+}
+
+// Program runs the translated statements of a script. Its methods are the expressions and statements of Lox,
+// and panic with the runtime errors, which Main recovers, so that each Lox expression is a Go expression.
+type Program struct {
+	intr    interp.Interpreter
+	globals *interp.Environment
+}
+
+// failure carries a runtime error from the method that found it to Main.
+type failure struct {
+	err error
+}
+
+// Main runs the translated script run, of source, and exits with status 70 after reporting its runtime
+// error on stderr, if one stops it, or with the status of exit, if the script calls it.
+// The arguments of the program are those of the script.
+func Main(source string, options Options, run func(p *Program)) {
+	intr := interp.New()
+	intr.CoerceStrings = options.CoerceStrings
+	intr.AllowFiles = options.AllowFiles
+	intr.Args = os.Args[1:]
+	intr = intr.BeginRun()
+	p := &Program{intr: intr, globals: intr.Globals()}
+	err := p.run(run)
+	var exit interp.ExitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.Code)
+	}
+	if err != nil {
+		fmt.Fprintln(intr.ErrorOutput(), diag.Format(source, err))
+		var re interp.RuntimeError
+		if errors.As(err, &re) {
+			fmt.Fprint(intr.ErrorOutput(), re.StackTrace())
+		}
+		os.Exit(exitRuntimeError)
+	}
+}
+
+// run calls run and returns the runtime error that stopped it.
+func (p *Program) run(run func(p *Program)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			f, ok := r.(failure)
+			if !ok {
+				panic(r)
+			}
+			err = f.err
+		}
+	}()
+	run(p)
+	return nil
+}
+
+// check returns value, or stops the program with err.
+func check(value interp.Value, err error) interp.Value {
+	if err != nil {
+		panic(failure{err})
+	}
+	return value
+}
+
+// Slot returns the slot of the global name, which its statements and expressions use.
+func (p *Program) Slot(name string) int {
+	return p.globals.Slot(name)
+}
+
+// Define is a var statement of the global of slot.
+func (p *Program) Define(slot int, value interp.Value) {
+	p.globals.DefineSlot(slot, value)
+}
+
+// Print is a print statement.
+func (p *Program) Print(value interp.Value) {
+	fmt.Fprintln(p.intr.Output(), value)
+}
+
+// Spawn is a spawn statement.
+func (p *Program) Spawn(callee interp.Value, paren token.Token, arguments ...interp.Value) {
+	if err := p.intr.Spawn(callee, arguments, paren); err != nil {
+		panic(failure{err})
+	}
+}
+
+// Get is the value of the global of slot, whose name is reported if it is not defined.
+func (p *Program) Get(slot int, name token.Token) interp.Value {
+	return check(p.globals.GetSlot(slot, name))
+}
+
+// Assign is an assignment to the global of slot.
+func (p *Program) Assign(slot int, name token.Token, value interp.Value) interp.Value {
+	if err := p.globals.AssignSlot(slot, name, value); err != nil {
+		panic(failure{err})
+	}
+	return value
+}
+
+func (p *Program) Unary(operator token.Token, right interp.Value) interp.Value {
+	return check(p.intr.Unary(operator, right))
+}
+
+func (p *Program) Binary(operator token.Token, left, right interp.Value) interp.Value {
+	return check(p.intr.Binary(operator, left, right))
+}
+
+// Logical is an and, an or or a ?? operator, which only evaluates right if left does not decide the result.
+func (p *Program) Logical(operator token.Token, left interp.Value, right func() interp.Value) interp.Value {
+	switch operator.Type {
+	case token.QUESTION_QUESTION:
+		if !left.IsNil() {
+			return left
+		}
+	case token.OR:
+		if left.Truthy() {
+			return left
+		}
+	case token.AND:
+		if !left.Truthy() {
+			return left
+		}
+	}
+	return right()
+}
+
+// Comparison is a chain of comparisons, like a < b <= c, which stops at the first one that is false: each
+// operand after first is only evaluated if the comparisons before it hold.
+func (p *Program) Comparison(first interp.Value, operators []token.Token, operands ...func() interp.Value) interp.Value {
+	left := first
+	for i, operator := range operators {
+		right := operands[i]()
+		if !p.Binary(operator, left, right).AsBool() {
+			return interp.Bool(false)
+		}
+		left = right
+	}
+	return interp.Bool(true)
+}
+
+func (p *Program) Call(callee interp.Value, paren token.Token, arguments ...interp.Value) interp.Value {
+	return check(p.intr.Call(callee, arguments, paren))
+}
+
+// GetProperty is the property name of object, read through cache, the cache of the expression.
+func (p *Program) GetProperty(object interp.Value, name token.Token, cache *interp.PropertyCache) interp.Value {
+	return check(p.intr.GetPropertyCached(object, name, cache))
+}
+
+// SetProperty is an assignment to the property name of object, like GetProperty.
+func (p *Program) SetProperty(object interp.Value, name token.Token, value interp.Value, cache *interp.PropertyCache) interp.Value {
+	if err := p.intr.SetPropertyCached(object, name, value, cache); err != nil {
+		panic(failure{err})
+	}
+	return value
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// coverage records which lines of a file run, through the hooks of the tree-walking interpreter.
+type coverage struct {
+	path string
+	// counts has a count for each line where a node starts: how many times a node that is not
+	// inside another node of the line ran, which is how many times a statement of one line ran
+	counts map[int]int
+	// running are the lines of the nodes being run, the innermost last
+	running []int
+}
+
+func newCoverage(path string) *coverage {
+	return &coverage{path: path, counts: make(map[int]int)}
+}
+
+// fileCoverage records the lines of the file that run, with -coverage.
+var fileCoverage *coverage
+
+// add makes the lines of the nodes of statements count, before they run.
+func (c *coverage) add(statements []ast.Stmt) {
+	var addExpr func(expr ast.Expr)
+	addExpr = func(expr ast.Expr) {
+		c.line(expr.SourceSpan().Line)
+		for _, child := range ast.Children(expr) {
+			addExpr(child)
+		}
+	}
+	for _, stmt := range statements {
+		c.line(stmt.SourceSpan().Line)
+		for _, expr := range ast.StmtExprs(stmt) {
+			addExpr(expr)
+		}
+	}
+}
+
+func (c *coverage) line(line int) {
+	if _, ok := c.counts[line]; !ok {
+		c.counts[line] = 0
+	}
+}
+
+func (c *coverage) hooks() *interp.Hooks {
+	return &interp.Hooks{
+		OnEnterNode: func(node interface{}, span token.Span) {
+			if n := len(c.running); n == 0 || c.running[n-1] != span.Line {
+				c.counts[span.Line]++
+			}
+			c.running = append(c.running, span.Line)
+		},
+		OnExitNode: func(node interface{}, span token.Span, value interp.Value, err error) {
+			c.running = c.running[:len(c.running)-1]
+		},
+	}
+}
+
+// hit returns the number of lines that ran.
+func (c *coverage) hit() int {
+	hit := 0
+	for _, count := range c.counts {
+		if count > 0 {
+			hit++
+		}
+	}
+	return hit
+}
+
+// reportCoverage writes the coverage of source, if it is recorded, on stderr in the format of -coverage.
+func reportCoverage(source string) {
+	switch {
+	case fileCoverage == nil:
+	case *coverageFormat == "lcov":
+		fileCoverage.writeLCOV(stderr)
+	default:
+		fileCoverage.writeListing(stderr, source)
+	}
+}
+
+// writeListing writes the source with the count of each line before it, ##### for the lines that
+// did not run and - for those without code, like gcov, and then the share of the lines that ran.
+func (c *coverage) writeListing(w io.Writer, source string) {
+	lines := bufio.NewScanner(strings.NewReader(source))
+	for line := 1; lines.Scan(); line++ {
+		count, ok := c.counts[line]
+		switch {
+		case !ok:
+			fmt.Fprintf(w, "%9s: %s\n", "-", lines.Text())
+		case count == 0:
+			fmt.Fprintf(w, "%9s: %s\n", "#####", lines.Text())
+		default:
+			fmt.Fprintf(w, "%9d: %s\n", count, lines.Text())
+		}
+	}
+	percent := 100.0
+	if len(c.counts) > 0 {
+		percent = 100 * float64(c.hit()) / float64(len(c.counts))
+	}
+	fmt.Fprintf(w, "%s: %.1f%% of %d lines ran\n", c.path, percent, len(c.counts))
+}
+
+// writeLCOV writes the counts as a tracefile of lcov.
+func (c *coverage) writeLCOV(w io.Writer) {
+	fmt.Fprintf(w, "TN:\nSF:%s\n", c.path)
+	for line := 1; len(c.counts) > 0 && line <= maxLine(c.counts); line++ {
+		if count, ok := c.counts[line]; ok {
+			fmt.Fprintf(w, "DA:%d,%d\n", line, count)
+		}
+	}
+	fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", len(c.counts), c.hit())
+}
+
+func maxLine(counts map[int]int) int {
+	max := 0
+	for line := range counts {
+		if line > max {
+			max = line
+		}
+	}
+	return max
+}
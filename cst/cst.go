@@ -0,0 +1,220 @@
+// Package cst builds a lossless concrete syntax tree of a Lox program.
+// Its tokens keep the whitespace and comments around them as trivia,
+// so a tool can rewrite part of a program and print it back without losing the user's formatting.
+package cst
+
+import (
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// TriviaKind tells what a piece of trivia is.
+type TriviaKind int
+
+const (
+	Whitespace TriviaKind = iota
+	Comment
+	// Skipped is source text the scanner could not turn into a token, like an unterminated string.
+	Skipped
+)
+
+// Trivia is a piece of source text between two tokens.
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}
+
+// Token is a token with its trivia.
+// The trailing trivia of a token runs up to the end of its line, the rest belongs to the next token.
+type Token struct {
+	token.Token
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+// Syntax is an ast.Stmt or an ast.Expr.
+type Syntax interface {
+	SourceSpan() token.Span
+}
+
+// Element is a *Node or a *Token.
+type Element interface {
+	isElement()
+	writeTo(builder *strings.Builder)
+}
+
+// Node is a node of the syntax tree with all its tokens and child nodes, in source order.
+type Node struct {
+	// Syntax is the AST node. It is nil for the root of the tree.
+	Syntax   Syntax
+	Children []Element
+}
+
+func (t *Token) isElement() {}
+
+func (n *Node) isElement() {}
+
+func (t *Token) writeTo(builder *strings.Builder) {
+	for _, trivia := range t.Leading {
+		builder.WriteString(trivia.Text)
+	}
+	builder.WriteString(t.Lexeme)
+	for _, trivia := range t.Trailing {
+		builder.WriteString(trivia.Text)
+	}
+}
+
+func (n *Node) writeTo(builder *strings.Builder) {
+	for _, child := range n.Children {
+		child.writeTo(builder)
+	}
+}
+
+// String returns the source text of the token, trivia included.
+func (t *Token) String() string {
+	var builder strings.Builder
+	t.writeTo(&builder)
+	return builder.String()
+}
+
+// String returns the source text of the node, trivia included.
+// For the root of the tree it is the whole source the tree was parsed from.
+func (n *Node) String() string {
+	var builder strings.Builder
+	n.writeTo(&builder)
+	return builder.String()
+}
+
+// Parse parses source into a concrete syntax tree, whose root holds the statements.
+// It is lossless even with syntax errors: the tokens of statements that do not parse
+// are children of the root, and the text that does not scan is trivia.
+func Parse(source string) (*Node, []error) {
+	return ParseWithConfig(source, scanner.Config{})
+}
+
+// ParseWithConfig is like Parse, but scans with config. Comments are always kept.
+func ParseWithConfig(source string, config scanner.Config) (*Node, []error) {
+	config.EmitComments = true
+	s := scanner.NewWithConfig(source, config)
+	tokens, errs := s.ScanTokens()
+	statements, parseErrs := parser.New(tokens).Parse()
+	errs = append(errs, parseErrs...)
+
+	b := &builder{tokens: attachTrivia(source, tokens)}
+	root := &Node{}
+	for _, stmt := range statements {
+		b.tokensBefore(root, stmt.SourceSpan().StartOffset)
+		root.Children = append(root.Children, b.node(stmt))
+	}
+	for ; b.next < len(b.tokens); b.next++ {
+		root.Children = append(root.Children, b.tokens[b.next])
+	}
+	return root, errs
+}
+
+// builder hands out the tokens, in order, to the nodes that cover them.
+type builder struct {
+	tokens []*Token
+	next   int
+}
+
+func (b *builder) node(syntax Syntax) *Node {
+	node := &Node{Syntax: syntax}
+	for _, child := range children(syntax) {
+		b.tokensBefore(node, child.SourceSpan().StartOffset)
+		node.Children = append(node.Children, b.node(child))
+	}
+	b.tokensBefore(node, syntax.SourceSpan().EndOffset)
+	return node
+}
+
+// tokensBefore adds to node the tokens that start before offset.
+func (b *builder) tokensBefore(node *Node, offset int) {
+	for ; b.next < len(b.tokens) && b.tokens[b.next].StartOffset < offset; b.next++ {
+		node.Children = append(node.Children, b.tokens[b.next])
+	}
+}
+
+// children returns the child nodes of syntax, in source order.
+func children(syntax Syntax) []Syntax {
+	var exprs []ast.Expr
+	switch syntax := syntax.(type) {
+	case ast.Stmt:
+		exprs = ast.StmtExprs(syntax)
+	case ast.Expr:
+		exprs = ast.Children(syntax)
+	}
+	nodes := make([]Syntax, len(exprs))
+	for i, expr := range exprs {
+		nodes[i] = expr
+	}
+	return nodes
+}
+
+// attachTrivia turns the text between the tokens and the comment tokens into trivia of the other tokens.
+func attachTrivia(source string, tokens []token.Token) []*Token {
+	var result []*Token
+	var pending []Trivia
+	end := 0
+	for _, tok := range tokens {
+		pending = append(pending, gapTrivia(source[end:tok.StartOffset])...)
+		end = tok.EndOffset
+		if tok.Type == token.COMMENT {
+			pending = append(pending, Trivia{Kind: Comment, Text: tok.Lexeme})
+			continue
+		}
+		if len(result) > 0 {
+			previous := result[len(result)-1]
+			n := trailingLength(pending)
+			previous.Trailing = pending[:n:n]
+			pending = pending[n:]
+		}
+		result = append(result, &Token{Token: tok, Leading: pending})
+		pending = nil
+	}
+	return result
+}
+
+// trailingLength returns how many of trivia are on the line of the token before them,
+// up to and including the line break.
+func trailingLength(trivia []Trivia) int {
+	for i, t := range trivia {
+		if t.Kind == Whitespace && strings.HasSuffix(t.Text, "\n") {
+			return i + 1
+		}
+	}
+	return len(trivia)
+}
+
+// gapTrivia splits text without tokens into runs of whitespace, each ending at a line break,
+// and runs of skipped text.
+func gapTrivia(text string) []Trivia {
+	var trivia []Trivia
+	for len(text) > 0 {
+		kind, n := Whitespace, 0
+		if !isWhitespace(text[0]) {
+			kind = Skipped
+			for n < len(text) && !isWhitespace(text[n]) {
+				n++
+			}
+		} else {
+			for n < len(text) && isWhitespace(text[n]) {
+				n++
+				if text[n-1] == '\n' {
+					break
+				}
+			}
+		}
+		trivia = append(trivia, Trivia{Kind: kind, Text: text[:n]})
+		text = text[n:]
+	}
+	return trivia
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
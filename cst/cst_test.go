@@ -0,0 +1,69 @@
+package cst
+
+import (
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/parser"
+)
+
+// TestLossless checks that the tree prints back as the source it was parsed from, whatever the source.
+func TestLossless(t *testing.T) {
+	sources := []string{
+		"",
+		" \t\r\n",
+		"print 1;",
+		"  print   1 +\n\t2 ;  \n\n",
+		"\r\nvar a = 1;\r\nprint a;\r\n",
+		"// only a comment",
+		"/* only a block comment */",
+		"print 1; // trailing\n// leading\nprint 2; /* block\n comment */ print 3;",
+		"/**/print/**/1/**/;/**/",
+		"print 1 // comment without a newline at the end",
+		"print (1 +;\nprint 2;",
+		"var = 1; print 2;",
+		"print @ 1;",
+		`print "unterminated`,
+		"print 1; /* unterminated",
+		"1__2 ; 0x ;",
+		"print é;",
+	}
+	sources = append(sources, parser.FuzzSeeds...)
+	for _, source := range sources {
+		root, _ := Parse(source)
+		if got := root.String(); got != source {
+			t.Errorf("%q printed back as %q", source, got)
+		}
+	}
+}
+
+// TestTrivia checks what the text between the tokens becomes.
+func TestTrivia(t *testing.T) {
+	root, _ := Parse("print 1; // one\n@ print 2;")
+	var trivia []Trivia
+	var walk func(node *Node)
+	walk = func(node *Node) {
+		for _, child := range node.Children {
+			switch child := child.(type) {
+			case *Node:
+				walk(child)
+			case *Token:
+				trivia = append(trivia, child.Leading...)
+				trivia = append(trivia, child.Trailing...)
+			}
+		}
+	}
+	walk(root)
+	want := []Trivia{
+		{Whitespace, " "}, {Whitespace, " "}, {Comment, "// one"}, {Whitespace, "\n"},
+		{Skipped, "@"}, {Whitespace, " "}, {Whitespace, " "},
+	}
+	if len(trivia) != len(want) {
+		t.Fatalf("the trivia are %q, want %q", trivia, want)
+	}
+	for i := range want {
+		if trivia[i] != want[i] {
+			t.Errorf("the trivia are %q, want %q", trivia, want)
+			break
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/diag"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// dapSession serves the Debug Adapter Protocol, so that editors can debug a script run by the tree-walking
+// interpreter. It reads requests from in and writes responses and events to out, while the script runs
+// on another goroutine and pauses before statements, through the hooks of the interpreter.
+type dapSession struct {
+	// intr runs the script, and its copy without hooks evaluates the expressions of the editor
+	intr   interp.Interpreter
+	in     *bufio.Reader
+	out    io.Writer
+	path   string
+	source string
+	// statements are the launched script, which runs when the editor is done configuring the session
+	statements []ast.Stmt
+	// resume wakes the paused script
+	resume chan struct{}
+
+	// mu guards what follows, which both goroutines use, and the writes to out
+	mu  sync.Mutex
+	seq int
+	// breakpoints are the lines to pause at
+	breakpoints map[int]bool
+	// stepping pauses at the next statement, for the reason stopReason
+	stepping   bool
+	stopReason string
+	// paused is whether the script waits on resume, before the statement of line
+	paused bool
+	line   int
+	// last is the line of the last statement, so that a breakpoint pauses once on a line of several statements
+	last int
+}
+
+// dapRequest is a request of the editor.
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type dapResponse struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type dapEvent struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+// dapThread is the thread of the script, which is the only one.
+const dapThread = 1
+
+// dapGlobals is the reference of the variables of the global scope, the only one.
+const dapGlobals = 1
+
+type dapObject = map[string]interface{}
+
+func newDAPSession(intr interp.Interpreter, in io.Reader, out io.Writer) *dapSession {
+	s := &dapSession{in: bufio.NewReader(in), out: out, resume: make(chan struct{}), breakpoints: make(map[int]bool)}
+	intr.Stdout = dapOutput{s, "stdout"}
+	intr.Stderr = dapOutput{s, "stderr"}
+	intr.Hooks = &interp.Hooks{OnEnterNode: s.enter}
+	s.intr = intr
+	return s
+}
+
+// serve handles requests until the editor disconnects or closes the connection.
+func (s *dapSession) serve() error {
+	for {
+		request, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		body, err := s.handle(request)
+		response := dapResponse{Type: "response", RequestSeq: request.Seq, Success: err == nil, Command: request.Command, Body: body}
+		if err != nil {
+			response.Message = err.Error()
+		}
+		s.send(&response, &response.Seq)
+		switch request.Command {
+		case "initialize":
+			s.event("initialized", nil)
+		case "configurationDone":
+			go s.run()
+		case "continue", "stepOut", "next", "stepIn":
+			// after the response, so that the events of the script follow it
+			if response.Success {
+				s.resume <- struct{}{}
+			}
+		case "disconnect", "terminate":
+			return nil
+		}
+	}
+}
+
+// read reads a request: a Content-Length header, an empty line and the JSON of the request.
+func (s *dapSession) read() (dapRequest, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return dapRequest{}, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return dapRequest{}, fmt.Errorf("bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return dapRequest{}, fmt.Errorf("message without Content-Length")
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(s.in, data); err != nil {
+		return dapRequest{}, err
+	}
+	var request dapRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return dapRequest{}, fmt.Errorf("decoding request: %v", err)
+	}
+	return request, nil
+}
+
+// send writes message after setting its sequence number at seq.
+func (s *dapSession) send(message interface{}, seq *int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	*seq = s.seq
+	data, err := json.Marshal(message)
+	if err != nil {
+		panic(fmt.Sprintf("encoding %T: %v", message, err))
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (s *dapSession) event(name string, body interface{}) {
+	event := dapEvent{Type: "event", Event: name, Body: body}
+	s.send(&event, &event.Seq)
+}
+
+// handle runs request and returns the body of its response, or the error that makes it fail.
+func (s *dapSession) handle(request dapRequest) (interface{}, error) {
+	var arguments struct {
+		Program     string `json:"program"`
+		StopOnEntry bool   `json:"stopOnEntry"`
+		Source      struct {
+			Path string `json:"path"`
+		} `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+		Expression string `json:"expression"`
+	}
+	if len(request.Arguments) > 0 {
+		if err := json.Unmarshal(request.Arguments, &arguments); err != nil {
+			return nil, fmt.Errorf("decoding the arguments of %s: %v", request.Command, err)
+		}
+	}
+	switch request.Command {
+	case "initialize":
+		return dapObject{"supportsConfigurationDoneRequest": true, "supportsEvaluateForHovers": true, "supportsTerminateRequest": true}, nil
+	case "launch":
+		return nil, s.launch(arguments.Program, arguments.StopOnEntry)
+	case "attach":
+		return nil, fmt.Errorf("attach is not supported: launch the script instead")
+	case "setBreakpoints":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.breakpoints = make(map[int]bool)
+		lines := strings.Count(s.source, "\n") + 1
+		breakpoints := []dapObject{}
+		for _, breakpoint := range arguments.Breakpoints {
+			verified := filepath.Clean(arguments.Source.Path) == filepath.Clean(s.path) && breakpoint.Line >= 1 && breakpoint.Line <= lines
+			if verified {
+				s.breakpoints[breakpoint.Line] = true
+			}
+			breakpoints = append(breakpoints, dapObject{"verified": verified, "line": breakpoint.Line})
+		}
+		return dapObject{"breakpoints": breakpoints}, nil
+	case "configurationDone", "disconnect", "terminate":
+		return nil, nil
+	case "threads":
+		return dapObject{"threads": []dapObject{{"id": dapThread, "name": "main"}}}, nil
+	case "stackTrace":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.paused {
+			return nil, fmt.Errorf("the script is running")
+		}
+		frame := dapObject{"id": 1, "name": "<script>", "line": s.line, "column": 1,
+			"source": dapObject{"name": filepath.Base(s.path), "path": s.path}}
+		return dapObject{"stackFrames": []dapObject{frame}, "totalFrames": 1}, nil
+	case "scopes":
+		return dapObject{"scopes": []dapObject{{"name": "Globals", "variablesReference": dapGlobals, "expensive": false}}}, nil
+	case "variables":
+		if err := s.checkPaused(); err != nil {
+			return nil, err
+		}
+		globals := s.intr.Globals()
+		variables := []dapObject{}
+		for _, name := range globals.Names() {
+			value, _ := globals.Get(token.Token{Lexeme: name})
+			variables = append(variables, dapObject{"name": name, "value": value.String(), "variablesReference": 0})
+		}
+		return dapObject{"variables": variables}, nil
+	case "evaluate":
+		if err := s.checkPaused(); err != nil {
+			return nil, err
+		}
+		value, err := s.evaluate(arguments.Expression)
+		if err != nil {
+			return nil, err
+		}
+		return dapObject{"result": value.String(), "variablesReference": 0}, nil
+	case "continue", "stepOut":
+		// there are no functions to step out of, so stepping out of the script runs it to its end
+		return dapObject{"allThreadsContinued": true}, s.continueRun(false, "")
+	case "next", "stepIn":
+		return nil, s.continueRun(true, "step")
+	case "pause":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.stepping, s.stopReason = true, "pause"
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown command %q", request.Command)
+}
+
+// launch parses the script at path, which runs after the configuration of the session.
+func (s *dapSession) launch(path string, stopOnEntry bool) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	source := string(data)
+	sc := scanner.NewWithConfig(source, scanner.Config{Keywords: keywords})
+	tokens, errs := sc.ScanTokens()
+	statements, parseErrs := parser.New(tokens).Parse()
+	if errs = append(errs, parseErrs...); len(errs) > 0 {
+		return fmt.Errorf("%s", diag.Format(source, errs[0]))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path, s.source, s.statements = path, source, statements
+	s.stepping, s.stopReason = stopOnEntry, "entry"
+	return nil
+}
+
+func (s *dapSession) checkPaused() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.paused {
+		return fmt.Errorf("the script is running")
+	}
+	return nil
+}
+
+// continueRun readies the paused script to resume, to pause again at the next statement if step.
+func (s *dapSession) continueRun(step bool, reason string) error {
+	s.mu.Lock()
+	if !s.paused {
+		s.mu.Unlock()
+		return fmt.Errorf("the script is not paused")
+	}
+	s.paused = false
+	s.stepping, s.stopReason = step, reason
+	s.mu.Unlock()
+	return nil
+}
+
+// evaluate evaluates an expression of the editor in the globals of the paused script.
+func (s *dapSession) evaluate(text string) (interp.Value, error) {
+	sc := scanner.NewWithConfig(text, scanner.Config{Keywords: keywords})
+	tokens, errs := sc.ScanTokens()
+	if len(errs) > 0 {
+		return interp.Nil(), errs[0]
+	}
+	expr, err := parser.New(tokens).ParseExpression()
+	if err != nil {
+		return interp.Nil(), err
+	}
+	intr := s.intr
+	intr.Hooks = nil
+	return intr.Evaluate(expr)
+}
+
+// run runs the script and tells the editor how it ended.
+func (s *dapSession) run() {
+	exitCode := 0
+	var exit interp.ExitError
+	if err := s.intr.Interpret(s.statements); errors.As(err, &exit) {
+		exitCode = exit.Code
+	} else if err != nil {
+		exitCode = exitRuntimeError
+		fmt.Fprintln(s.intr.ErrorOutput(), diag.Format(s.source, err))
+	}
+	s.event("exited", dapObject{"exitCode": exitCode})
+	s.event("terminated", nil)
+}
+
+// enter pauses the script before a statement, when it steps or reaches a breakpoint.
+func (s *dapSession) enter(node interface{}, span token.Span) {
+	if _, ok := node.(ast.Stmt); !ok {
+		return
+	}
+	s.mu.Lock()
+	line := span.Line
+	reason := s.stopReason
+	if !s.stepping {
+		reason = ""
+		if s.breakpoints[line] && line != s.last {
+			reason = "breakpoint"
+		}
+	}
+	s.last = line
+	if reason == "" {
+		s.mu.Unlock()
+		return
+	}
+	s.stepping = false
+	s.paused, s.line = true, line
+	s.mu.Unlock()
+	s.event("stopped", dapObject{"reason": reason, "threadId": dapThread, "allThreadsStopped": true})
+	<-s.resume
+}
+
+// dapOutput sends what the script writes to the editor, as output events of category.
+type dapOutput struct {
+	session  *dapSession
+	category string
+}
+
+func (o dapOutput) Write(p []byte) (int, error) {
+	o.session.event("output", dapObject{"category": o.category, "output": string(p)})
+	return len(p), nil
+}
+
+// serveDAP serves one debugging session for an editor on stdin and stdout.
+func serveDAP(intr interp.Interpreter) {
+	if err := newDAPSession(intr, os.Stdin, os.Stdout).serve(); err != nil {
+		fmt.Fprintln(stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gadumitrachioaiei/go-lox/interp"
+)
+
+// dapClient is the editor end of a DAP session, which checks that the messages of the session are numbered
+// in order.
+type dapClient struct {
+	t        *testing.T
+	requests io.Writer
+	messages chan dapObject
+	seq      int
+	// next is the sequence number the next message of the session must have
+	next int
+	// output is what the script printed, from the output events read so far
+	output strings.Builder
+}
+
+func newDAPClient(t *testing.T, requests io.Writer, responses io.Reader) *dapClient {
+	c := &dapClient{t: t, requests: requests, messages: make(chan dapObject), next: 1}
+	// the session writes events while the script runs, so it is read all the time
+	go func() {
+		defer close(c.messages)
+		in := bufio.NewReader(responses)
+		for {
+			header, err := in.ReadString('\n')
+			if err != nil {
+				return
+			}
+			length, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+			if err != nil {
+				panic(fmt.Sprintf("bad header %q", header))
+			}
+			data := make([]byte, len("\r\n")+length)
+			if _, err := io.ReadFull(in, data); err != nil {
+				return
+			}
+			var message dapObject
+			if err := json.Unmarshal(data[2:], &message); err != nil {
+				panic(fmt.Sprintf("bad message %q: %v", data, err))
+			}
+			c.messages <- message
+		}
+	}()
+	return c
+}
+
+// request sends a request and returns its sequence number.
+func (c *dapClient) request(command string, arguments interface{}) int {
+	c.t.Helper()
+	c.seq++
+	data, err := json.Marshal(dapObject{"seq": c.seq, "type": "request", "command": command, "arguments": arguments})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := fmt.Fprintf(c.requests, "Content-Length: %d\r\n\r\n%s", len(data), data); err != nil {
+		c.t.Fatal(err)
+	}
+	return c.seq
+}
+
+// read returns the next message that is not an output event, after checking its sequence number.
+func (c *dapClient) read() dapObject {
+	c.t.Helper()
+	for {
+		var message dapObject
+		select {
+		case m, ok := <-c.messages:
+			if !ok {
+				c.t.Fatalf("the session ended after printing %q", c.output.String())
+			}
+			message = m
+		case <-time.After(10 * time.Second):
+			c.t.Fatalf("no message from the session after printing %q", c.output.String())
+		}
+		if seq := message["seq"]; seq != float64(c.next) {
+			c.t.Fatalf("the message %v has the sequence number %v, want %d", message, seq, c.next)
+		}
+		c.next++
+		if message["event"] != "output" {
+			return message
+		}
+		c.output.WriteString(message["body"].(dapObject)["output"].(string))
+	}
+}
+
+// response reads the response to the request of seq, and returns its body.
+func (c *dapClient) response(command string, seq int) dapObject {
+	c.t.Helper()
+	message := c.read()
+	if message["type"] != "response" || message["command"] != command || message["request_seq"] != float64(seq) || message["success"] != true {
+		c.t.Fatalf("got %v, want a successful response to %s %d", message, command, seq)
+	}
+	body, _ := message["body"].(dapObject)
+	return body
+}
+
+// event reads an event of name, and returns its body.
+func (c *dapClient) event(name string) dapObject {
+	c.t.Helper()
+	message := c.read()
+	if message["type"] != "event" || message["event"] != name {
+		c.t.Fatalf("got %v, want the event %s", message, name)
+	}
+	body, _ := message["body"].(dapObject)
+	return body
+}
+
+// stopped reads a stopped event for reason, and checks what the script printed until then.
+func (c *dapClient) stopped(reason, output string) {
+	c.t.Helper()
+	if body := c.event("stopped"); body["reason"] != reason {
+		c.t.Fatalf("the script stopped for %v, want %s", body["reason"], reason)
+	}
+	if c.output.String() != output {
+		c.t.Errorf("the script printed %q before it stopped for %s, want %q", c.output.String(), reason, output)
+	}
+}
+
+func TestDAPSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.lox")
+	// the breakpoint is on the third line, of two statements
+	source := "var a = 1;\nprint a;\nvar b = a + 1; print b;\nprint \"end\";\n"
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	requests, requestsWriter := io.Pipe()
+	responses, responsesWriter := io.Pipe()
+	session := newDAPSession(interp.New(), requests, responsesWriter)
+	served := make(chan error, 1)
+	go func() { served <- session.serve() }()
+	c := newDAPClient(t, requestsWriter, responses)
+
+	if body := c.response("initialize", c.request("initialize", dapObject{"adapterID": "lox"})); body["supportsConfigurationDoneRequest"] != true {
+		t.Errorf("initialize returned %v", body)
+	}
+	c.event("initialized")
+	c.response("launch", c.request("launch", dapObject{"program": path, "stopOnEntry": true}))
+	body := c.response("setBreakpoints", c.request("setBreakpoints", dapObject{
+		"source":      dapObject{"path": path},
+		"breakpoints": []dapObject{{"line": 3}, {"line": 9}},
+	}))
+	if got := fmt.Sprint(body["breakpoints"]); got != "[map[line:3 verified:true] map[line:9 verified:false]]" {
+		t.Errorf("setBreakpoints returned the breakpoints %s", got)
+	}
+	c.response("configurationDone", c.request("configurationDone", nil))
+	c.stopped("entry", "")
+
+	c.response("continue", c.request("continue", dapObject{"threadId": dapThread}))
+	c.stopped("breakpoint", "1\n")
+	body = c.response("variables", c.request("variables", dapObject{"variablesReference": dapGlobals}))
+	variables := map[string]interface{}{}
+	for _, variable := range body["variables"].([]interface{}) {
+		variable := variable.(dapObject)
+		variables[variable["name"].(string)] = variable["value"]
+	}
+	if variables["a"] != "1" || variables["b"] != nil {
+		t.Errorf("at the breakpoint, a is %v and b is %v, want 1 and undefined", variables["a"], variables["b"])
+	}
+	if body := c.response("evaluate", c.request("evaluate", dapObject{"expression": "a + 41"})); body["result"] != "42" {
+		t.Errorf("a + 41 evaluated to %v, want 42", body["result"])
+	}
+
+	// the second statement of the line of the breakpoint runs without pausing again
+	c.response("continue", c.request("continue", dapObject{"threadId": dapThread}))
+	if body := c.event("exited"); body["exitCode"] != float64(0) {
+		t.Errorf("the script exited with %v", body["exitCode"])
+	}
+	if got := c.output.String(); got != "1\n2\nend\n" {
+		t.Errorf("the script printed %q", got)
+	}
+	c.event("terminated")
+	c.response("disconnect", c.request("disconnect", nil))
+	if err := <-served; err != nil {
+		t.Errorf("the session ended with %v", err)
+	}
+}
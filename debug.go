@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/diag"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// debugger pauses a run of the tree-walking interpreter before statements, through its hooks, and reads
+// commands to inspect it from its input.
+type debugger struct {
+	// intr evaluates the expressions typed at a pause, in the globals of the run, without the hooks
+	intr  interp.Interpreter
+	path  string
+	lines []string
+	input *bufio.Scanner
+	out   io.Writer
+	// breakpoints are the lines to pause at
+	breakpoints map[int]bool
+	// stepping pauses at the next statement
+	stepping bool
+	// last is the line of the last statement, so that a breakpoint pauses once on a line of several statements
+	last int
+}
+
+// debuggerHelp lists the commands of the debugger.
+const debuggerHelp = `break [file:]line   pause before the statements of line (b)
+delete [file:]line  remove the breakpoint on line (d)
+step                run the next statement and pause (s)
+next                step over the next statement; there are no calls of Lox functions to step into yet (n)
+continue            run until a breakpoint (c)
+print expression    print the value of expression (p)
+locals              print the variables of the current frame, which is the global one (l)
+quit                stop the program (q)`
+
+// newDebugger returns a debugger that runs the code of path with intr, reading commands from input.
+// It pauses before the first statement.
+func newDebugger(intr interp.Interpreter, path, source string, input io.Reader, out io.Writer) *debugger {
+	intr.Hooks = nil
+	return &debugger{
+		intr:        intr,
+		path:        path,
+		lines:       strings.Split(source, "\n"),
+		input:       bufio.NewScanner(input),
+		out:         out,
+		breakpoints: make(map[int]bool),
+		stepping:    true,
+	}
+}
+
+func (d *debugger) hooks() *interp.Hooks {
+	return &interp.Hooks{
+		OnEnterNode: func(node interface{}, span token.Span) {
+			if _, ok := node.(ast.Stmt); !ok {
+				return
+			}
+			line := span.Line
+			if d.stepping || (d.breakpoints[line] && line != d.last) {
+				d.pause(line)
+			}
+			d.last = line
+		},
+	}
+}
+
+// pause shows the statement about to run on line and runs commands until one resumes the program.
+func (d *debugger) pause(line int) {
+	d.stepping = false
+	if line <= len(d.lines) {
+		fmt.Fprintf(d.out, "%s:%d: %s\n", filepath.Base(d.path), line, strings.TrimSpace(d.lines[line-1]))
+	}
+	for {
+		fmt.Fprint(d.out, "(debug) ")
+		if !d.input.Scan() {
+			// without commands the program runs to its end
+			d.breakpoints = nil
+			fmt.Fprintln(d.out)
+			return
+		}
+		command, argument, _ := strings.Cut(strings.TrimSpace(d.input.Text()), " ")
+		argument = strings.TrimSpace(argument)
+		switch command {
+		case "":
+		case "break", "b", "delete", "d":
+			line, err := d.breakpointLine(argument)
+			if err != nil {
+				fmt.Fprintln(d.out, err)
+				break
+			}
+			if command == "break" || command == "b" {
+				d.breakpoints[line] = true
+			} else {
+				delete(d.breakpoints, line)
+			}
+		case "step", "s", "next", "n":
+			d.stepping = true
+			return
+		case "continue", "c":
+			return
+		case "print", "p":
+			d.print(argument)
+		case "locals", "l":
+			globals := d.intr.Globals()
+			for _, name := range globals.Names() {
+				value, _ := globals.Get(token.Token{Lexeme: name})
+				fmt.Fprintf(d.out, "%s = %v\n", name, value)
+			}
+		case "quit", "q":
+			os.Exit(0)
+		case "help", "h":
+			fmt.Fprintln(d.out, debuggerHelp)
+		default:
+			fmt.Fprintf(d.out, "unknown command %q, try help\n", command)
+		}
+	}
+}
+
+// breakpointLine returns the line of a breakpoint given as line or file:line, where file is the path
+// of the program or its base name.
+func (d *debugger) breakpointLine(argument string) (int, error) {
+	if i := strings.LastIndex(argument, ":"); i >= 0 {
+		if file := argument[:i]; file != d.path && file != filepath.Base(d.path) {
+			return 0, fmt.Errorf("no file %s, only %s", file, d.path)
+		}
+		argument = argument[i+1:]
+	}
+	line, err := strconv.Atoi(argument)
+	if err != nil || line < 1 || line > len(d.lines) {
+		return 0, fmt.Errorf("no line %q in %s", argument, d.path)
+	}
+	return line, nil
+}
+
+// print evaluates an expression typed at a pause.
+func (d *debugger) print(text string) {
+	s := scanner.NewWithConfig(text, scanner.Config{Keywords: keywords})
+	tokens, errs := s.ScanTokens()
+	if len(errs) > 0 {
+		fmt.Fprintln(d.out, diag.Format(text, errs[0]))
+		return
+	}
+	expr, err := parser.New(tokens).ParseExpression()
+	if err == nil {
+		var value interp.Value
+		if value, err = d.intr.Evaluate(expr); err == nil {
+			fmt.Fprintln(d.out, value)
+			return
+		}
+	}
+	fmt.Fprintln(d.out, diag.Format(text, err))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+)
+
+func TestDebugger(t *testing.T) {
+	// the breakpoint is on the third line, of two statements
+	const source = "var a = 1;\nprint a;\nvar b = a + 1; print b;\nprint \"end\";\n"
+	commands := []string{
+		// the pause before the first statement
+		"locals", "b 3", "b 4", "d 4", "b other.lox:2", "s",
+		// the pause of the step
+		"p a + 1", "c",
+		// the pause of the breakpoint
+		"l", "c",
+	}
+	var out bytes.Buffer
+	intr := interp.New()
+	intr.Stdout, intr.Stderr = &out, &out
+	d := newDebugger(intr, "script.lox", source, strings.NewReader(strings.Join(commands, "\n")+"\n"), &out)
+	intr.Hooks = d.hooks()
+	tokens, _ := scan(source)
+	statements, errs := parser.New(tokens).Parse()
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	if err := intr.Interpret(statements); err != nil {
+		t.Fatal(err)
+	}
+
+	// locals lists the builtins too, which are left out, but for the prompt before them
+	var lines []string
+	for _, line := range strings.SplitAfter(out.String(), "\n") {
+		if !strings.HasSuffix(line, " = <native fn>\n") {
+			lines = append(lines, line)
+		} else if strings.HasPrefix(line, "(debug) ") {
+			lines = append(lines, "(debug) ")
+		}
+	}
+	want := "script.lox:1: var a = 1;\n" +
+		"(debug) (debug) (debug) (debug) (debug) no file other.lox, only script.lox\n" +
+		"(debug) script.lox:2: print a;\n" +
+		"(debug) 2\n" +
+		"(debug) 1\n" +
+		"script.lox:3: var b = a + 1; print b;\n" +
+		"(debug) a = 1\n" +
+		"(debug) 2\n" +
+		"end\n"
+	if got := strings.Join(lines, ""); got != want {
+		t.Errorf("the debugger printed\n%s\nwant\n%s", got, want)
+	}
+}
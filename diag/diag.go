@@ -0,0 +1,59 @@
+// Package diag renders errors together with the source they are about.
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// spanned is implemented by the scan, parse and runtime errors, which know their span of the source.
+type spanned interface {
+	SourceSpan() token.Span
+}
+
+// Format returns the message of err. When err has a span of source, the message is followed by the line
+// where the span starts and a ^~~~ underline below the span, cut at the end of the line:
+//
+//	line 1: Operands must be numbers, got string and number for '-'
+//	  1 | print "a" - 1;
+//	    |           ^
+func Format(source string, err error) string {
+	var s spanned
+	if !errors.As(err, &s) {
+		return err.Error()
+	}
+	span := s.SourceSpan()
+	if span.StartOffset < 0 || span.StartOffset > len(source) || span.EndOffset < span.StartOffset {
+		return err.Error()
+	}
+	lineStart := strings.LastIndexByte(source[:span.StartOffset], '\n') + 1
+	lineEnd := len(source)
+	if i := strings.IndexByte(source[span.StartOffset:], '\n'); i >= 0 {
+		lineEnd = span.StartOffset + i
+	}
+	line := strings.TrimSuffix(source[lineStart:lineEnd], "\r")
+
+	width := span.EndOffset
+	if width > lineStart+len(line) {
+		width = lineStart + len(line)
+	}
+	width -= span.StartOffset
+	if width < 1 {
+		width = 1
+	}
+	// keep the tabs before the span, so that the underline lines up with it
+	indent := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, source[lineStart:span.StartOffset])
+
+	number := fmt.Sprint(span.Line)
+	gutter := strings.Repeat(" ", len(number))
+	return fmt.Sprintf("%s\n  %s | %s\n  %s | %s^%s",
+		err.Error(), number, line, gutter, indent, strings.Repeat("~", width-1))
+}
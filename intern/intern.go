@@ -0,0 +1,44 @@
+// Package intern keeps one copy of each distinct string, like the identifiers and string constants of a program.
+package intern
+
+import "strings"
+
+// Table interns strings: the strings it returns for equal inputs share their bytes, so comparing them
+// does not read the bytes, and each one is stored once however many times it occurs. It also numbers them
+// in the order they are first interned, so that a constant pool can refer to a string by its ID.
+// The zero value is an empty table. A Table is not safe for concurrent use.
+type Table struct {
+	ids     map[string]int
+	strings []string
+}
+
+// Intern returns the copy of s in the table, adding it if it is not there.
+func (t *Table) Intern(s string) string {
+	return t.strings[t.ID(s)]
+}
+
+// ID returns the ID of s, adding it to the table if it is not there.
+func (t *Table) ID(s string) int {
+	if id, ok := t.ids[s]; ok {
+		return id
+	}
+	if t.ids == nil {
+		t.ids = make(map[string]int)
+	}
+	// s is often a substring of a whole source file, which the table should not keep alive
+	s = strings.Clone(s)
+	id := len(t.strings)
+	t.ids[s] = id
+	t.strings = append(t.strings, s)
+	return id
+}
+
+// String returns the string with the given ID.
+func (t *Table) String(id int) string {
+	return t.strings[id]
+}
+
+// Len returns the number of strings in the table.
+func (t *Table) Len() int {
+	return len(t.strings)
+}
@@ -0,0 +1,182 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Instance is a value with properties, which Lox code reads and writes with a dot, like point.x.
+// An error of Get or Set becomes a runtime error at the name of the property.
+type Instance interface {
+	Get(name string) (Value, error)
+	Set(name string, value Value) error
+}
+
+// Bind returns a value that gives Lox code access to x, a pointer to a struct or a map with string keys,
+// as an instance. A struct has its exported fields and methods as properties, converted like the arguments
+// and the results of Register; methods are called with the arguments of a native function. The lox tag of
+// a field renames its property, hides it with "-", or makes it read-only with ",readonly":
+//
+//	type Point struct {
+//		X  float64 `lox:"x"`
+//		ID int     `lox:"id,readonly"`
+//		db *sql.DB
+//	}
+//
+// A map has its keys as properties, which assigning creates.
+func Bind(x interface{}) (Value, error) {
+	v := reflect.ValueOf(x)
+	switch {
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.Struct && !v.IsNil():
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String && !v.IsNil():
+	default:
+		return Nil(), fmt.Errorf("cannot bind %T: it is not a non-nil pointer to a struct or map with string keys", x)
+	}
+	return Object(&boundObject{v: v}), nil
+}
+
+// boundObject is a Go value made an Instance by Bind.
+type boundObject struct {
+	v reflect.Value
+}
+
+func (b *boundObject) String() string {
+	return "<" + b.v.Type().String() + ">"
+}
+
+// boundField is a field of a struct exposed as a property.
+type boundField struct {
+	index    []int
+	readOnly bool
+}
+
+// boundFields caches the properties of the fields of the struct types, by type.
+var boundFields sync.Map
+
+// fields returns the properties of the fields of struct type t, by name.
+func fields(t reflect.Type) map[string]boundField {
+	if cached, ok := boundFields.Load(t); ok {
+		return cached.(map[string]boundField)
+	}
+	properties := make(map[string]boundField)
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("lox"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = boundField{index: field.Index, readOnly: options == "readonly"}
+	}
+	boundFields.Store(t, properties)
+	return properties
+}
+
+// PropertyCache remembers where a site, like an instruction of the VM, found its property on the struct
+// type of the last instance it used, so that using an instance of the same type again skips looking up
+// the name. Go types do not change, so an entry holds until the site meets another type; maps are not
+// cached, as their keys do change. A cache belongs to a single property name. Its zero value is empty.
+type PropertyCache struct {
+	typ   reflect.Type
+	field boundField
+	// method is the index of the method of the property, or -1 for a field
+	method int
+}
+
+// lookup returns where the property name of the struct is, from cache if it has its type, which it
+// fills otherwise. cache may be nil.
+func (b *boundObject) lookup(name string, cache *PropertyCache) (PropertyCache, bool) {
+	t := b.v.Type()
+	if cache != nil && cache.typ == t {
+		return *cache, true
+	}
+	found := PropertyCache{typ: t, method: -1}
+	if field, ok := fields(t.Elem())[name]; ok {
+		found.field = field
+	} else if method, ok := t.MethodByName(name); ok {
+		found.method = method.Index
+	} else {
+		return PropertyCache{}, false
+	}
+	if cache != nil {
+		*cache = found
+	}
+	return found, true
+}
+
+func (b *boundObject) Get(name string) (Value, error) {
+	return b.get(name, nil)
+}
+
+func (b *boundObject) Set(name string, value Value) error {
+	return b.set(name, value, nil)
+}
+
+func (b *boundObject) get(name string, cache *PropertyCache) (Value, error) {
+	if b.v.Kind() == reflect.Map {
+		value := b.v.MapIndex(reflect.ValueOf(name).Convert(b.v.Type().Key()))
+		if !value.IsValid() {
+			return Nil(), undefinedProperty(name)
+		}
+		return fromReflect(value), nil
+	}
+	property, ok := b.lookup(name, cache)
+	if !ok {
+		return Nil(), undefinedProperty(name)
+	}
+	if property.method >= 0 {
+		native, err := nativeFunction(name, b.v.Method(property.method).Interface())
+		if err != nil {
+			return Nil(), fmt.Errorf("Cannot use method '%s': %v.", name, err)
+		}
+		return Object(native), nil
+	}
+	value, err := b.v.Elem().FieldByIndexErr(property.field.index)
+	if err != nil {
+		return Nil(), nilEmbedded(name)
+	}
+	return fromReflect(value), nil
+}
+
+func (b *boundObject) set(name string, value Value, cache *PropertyCache) error {
+	if b.v.Kind() == reflect.Map {
+		x, err := toGo(value, b.v.Type().Elem())
+		if err != nil {
+			return fmt.Errorf("Property '%s' %v.", name, err)
+		}
+		b.v.SetMapIndex(reflect.ValueOf(name).Convert(b.v.Type().Key()), x)
+		return nil
+	}
+	property, ok := b.lookup(name, cache)
+	if !ok || property.method >= 0 {
+		return undefinedProperty(name)
+	}
+	field := property.field
+	if field.readOnly {
+		return fmt.Errorf("Property '%s' is read-only.", name)
+	}
+	target, err := b.v.Elem().FieldByIndexErr(field.index)
+	if err != nil {
+		return nilEmbedded(name)
+	}
+	x, err := toGo(value, target.Type())
+	if err != nil {
+		return fmt.Errorf("Property '%s' %v.", name, err)
+	}
+	target.Set(x)
+	return nil
+}
+
+func undefinedProperty(name string) error {
+	return fmt.Errorf("Undefined property '%s'.", name)
+}
+
+func nilEmbedded(name string) error {
+	return fmt.Errorf("Property '%s' is in a nil embedded struct.", name)
+}
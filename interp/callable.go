@@ -0,0 +1,197 @@
+package interp
+
+import (
+	"fmt"
+	"time"
+)
+
+// Callable is a value that Lox code can call.
+type Callable interface {
+	// Arity is the number of arguments the callable takes.
+	Arity() int
+	// Call runs the callable with arguments, of which there are Arity.
+	Call(intr Interpreter, arguments []Value) (Value, error)
+}
+
+// NativeFunction is a function of the interpreter written in Go.
+// An error returned by Func becomes a runtime error at the call.
+type NativeFunction struct {
+	Name    string
+	NumArgs int
+	// Variadic makes the function take NumArgs arguments or more.
+	Variadic bool
+	Func     func(arguments []Value) (Value, error)
+	// call replaces Func for the builtins that need the interpreter, like bench
+	call func(intr Interpreter, arguments []Value) (Value, error)
+}
+
+func (fn *NativeFunction) Arity() int {
+	return fn.NumArgs
+}
+
+func (fn *NativeFunction) Call(intr Interpreter, arguments []Value) (Value, error) {
+	if fn.call != nil {
+		return fn.call(intr, arguments)
+	}
+	return fn.Func(arguments)
+}
+
+func (fn *NativeFunction) String() string {
+	return "<native fn>"
+}
+
+// callableName returns the name of fn in stack traces.
+func callableName(fn Callable) string {
+	if native, ok := fn.(*NativeFunction); ok {
+		return native.Name
+	}
+	return "<fn>"
+}
+
+// argumentName returns how the errors of a builtin name its argument i of count: "The argument" of the
+// only one, or else like "The second argument".
+func argumentName(i, count int) string {
+	if count == 1 {
+		return "The argument"
+	}
+	return "The " + [...]string{"first", "second", "third"}[i] + " argument"
+}
+
+// integerArgument returns the argument i of the builtin name, which must be an integer.
+func integerArgument(name string, arguments []Value, i int) (int64, error) {
+	argument := arguments[i]
+	if argument.Kind() != IntKind {
+		got := typeName(argument)
+		if argument.IsNumber() {
+			got = argument.String()
+		}
+		return 0, fmt.Errorf("%s of %s must be an integer, got %s.", argumentName(i, len(arguments)), name, got)
+	}
+	return argument.AsInt(), nil
+}
+
+// builtins are the native functions defined in the globals of every interpreter.
+var builtins = []*NativeFunction{
+	{
+		Name: "clock",
+		// seconds since the epoch, to time Lox code
+		Func: func(arguments []Value) (Value, error) {
+			return Float(float64(time.Now().UnixNano()) / float64(time.Second)), nil
+		},
+	},
+	{
+		Name:    "bench",
+		NumArgs: 2,
+		// bench(fn, iterations) calls fn, which takes no arguments, iterations times, and returns
+		// the average seconds of a call
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			fn, ok := arguments[0].AsObject().(Callable)
+			if !ok || fn.Arity() != 0 {
+				return Nil(), fmt.Errorf("The first argument of bench must be a function without parameters, got %s.", typeName(arguments[0]))
+			}
+			if arguments[1].Kind() != IntKind || arguments[1].AsInt() < 1 {
+				return Nil(), fmt.Errorf("The second argument of bench must be a positive integer.")
+			}
+			iterations := arguments[1].AsInt()
+			start := time.Now()
+			for i := int64(0); i < iterations; i++ {
+				if _, err := fn.Call(intr, nil); err != nil {
+					return Nil(), err
+				}
+			}
+			return Float(time.Since(start).Seconds() / float64(iterations)), nil
+		},
+	},
+	{
+		Name: "argCount",
+		// argCount() is the number of the arguments of the code, its Args
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			return Int(int64(len(intr.Args))), nil
+		},
+	},
+	{
+		Name:    "arg",
+		NumArgs: 1,
+		// arg(i) is the argument i of the code, from 0 to argCount() - 1
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			if len(intr.Args) == 0 {
+				return Nil(), fmt.Errorf("The code has no arguments for arg.")
+			}
+			i, err := indexArgument("arg", arguments, 0, len(intr.Args)-1)
+			if err != nil {
+				return Nil(), err
+			}
+			return String(intr.Args[i]), nil
+		},
+	},
+	{
+		Name:    "exit",
+		NumArgs: 1,
+		// exit(status) ends the run, with an ExitError of status, from 0 to 255
+		Func: func(arguments []Value) (Value, error) {
+			status, err := integerArgument("exit", arguments, 0)
+			if err != nil {
+				return Nil(), err
+			}
+			if status < 0 || status > 255 {
+				return Nil(), fmt.Errorf("The argument of exit must be from 0 to 255, got %d.", status)
+			}
+			return Nil(), ExitError{Code: int(status)}
+		},
+	},
+	{
+		Name:    "type",
+		NumArgs: 1,
+		// type(value) is the name of the type of value, as the runtime errors name it: "nil", "bool",
+		// "number", "string", "function", "channel", "array" or "instance". There is no isInstance yet, as
+		// there are no classes to test an instance against.
+		Func: func(arguments []Value) (Value, error) {
+			return String(typeName(arguments[0])), nil
+		},
+	},
+	{
+		Name:    "arity",
+		NumArgs: 1,
+		// arity(fn) is the number of arguments of the function fn, the least of them if it takes more, like format
+		Func: func(arguments []Value) (Value, error) {
+			fn, ok := arguments[0].AsObject().(Callable)
+			if !ok {
+				return Nil(), fmt.Errorf("The argument of arity must be a function, got %s.", typeName(arguments[0]))
+			}
+			return Int(int64(fn.Arity())), nil
+		},
+	},
+}
+
+// newGlobals returns an environment with the builtins.
+func newGlobals() *Environment {
+	env := NewEnvironment()
+	for _, fn := range builtins {
+		env.Define(fn.Name, Object(fn))
+	}
+	for _, fn := range channelBuiltins {
+		env.Define(fn.Name, Object(fn))
+	}
+	for _, fn := range mathBuiltins {
+		env.Define(fn.Name, Object(fn))
+	}
+	for _, fn := range stringBuiltins {
+		env.Define(fn.Name, Object(fn))
+	}
+	for _, fn := range ioBuiltins {
+		env.Define(fn.Name, Object(fn))
+	}
+	for _, fn := range formatBuiltins {
+		env.Define(fn.Name, Object(fn))
+	}
+	for _, fn := range randomBuiltins {
+		env.Define(fn.Name, Object(fn))
+	}
+	for _, fn := range timeBuiltins {
+		env.Define(fn.Name, Object(fn))
+	}
+	for _, fn := range jsonBuiltins {
+		env.Define(fn.Name, Object(fn))
+	}
+	return env
+}
@@ -0,0 +1,30 @@
+package interp
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestType(t *testing.T) {
+	intr := New()
+	intr.Globals().Define("text", String(`{"a": [1]}`))
+	source := `var o = jsonParse(text);
+print type(nil); print type(true); print type(1); print type(2.5); print type("s");
+print type(clock); print type(chan()); print type(o); print type(get(o, "a"));`
+	want := "nil\nbool\nnumber\nnumber\nstring\nfunction\nchannel\ninstance\narray\n"
+	if got := run(t, intr, source); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestArity(t *testing.T) {
+	got := run(t, New(), "print arity(clock); print arity(substr); print arity(format);")
+	if want := "0\n3\n1\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	err := New().EvalContext(context.Background(), "arity(true);")
+	if err == nil || !strings.HasSuffix(err.Error(), "The argument of arity must be a function, got bool.") {
+		t.Errorf("arity(true) failed with %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Channel passes values between the tasks of spawn. It is made by the builtin chan, and has no buffer:
+// send waits for a task to receive, and receive for a task to send.
+type Channel struct {
+	tasks *taskGroup
+	// senders and receivers are the tasks waiting on the channel, the first to wait first, locked by tasks
+	senders, receivers []*waiter
+}
+
+func (ch *Channel) String() string {
+	return "<channel>"
+}
+
+// channelBuiltins are the builtins of channels. A task waiting on one stops with the error of the context
+// of the run when it is done, and with a deadlock error when every task waits.
+var channelBuiltins = []*NativeFunction{
+	{
+		Name: "chan",
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			return Object(&Channel{tasks: intr.tasks}), nil
+		},
+	},
+	{
+		Name:    "send",
+		NumArgs: 2,
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			ch, err := channelArgument("send", arguments[0])
+			if err != nil {
+				return Nil(), err
+			}
+			_, err = ch.pass(intr, &ch.senders, &ch.receivers, arguments[1])
+			return Nil(), err
+		},
+	},
+	{
+		Name:    "receive",
+		NumArgs: 1,
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			ch, err := channelArgument("receive", arguments[0])
+			if err != nil {
+				return Nil(), err
+			}
+			return ch.pass(intr, &ch.receivers, &ch.senders, Nil())
+		},
+	},
+}
+
+// channelArgument returns the channel a builtin named name got as its first argument.
+func channelArgument(name string, argument Value) (*Channel, error) {
+	ch, ok := argument.AsObject().(*Channel)
+	if !ok {
+		return nil, fmt.Errorf("The first argument of %s must be a channel, got %s.", name, typeName(argument))
+	}
+	return ch, nil
+}
+
+// errDeadlock is the error of the tasks waiting on channels when all of them do.
+var errDeadlock = errors.New("Deadlock: every task is waiting on a channel.")
+
+// pass meets the first task of others, the tasks waiting on the other side of the channel, or waits in
+// mine for one: a sender gives value, and a receiver returns the value it gets.
+func (ch *Channel) pass(intr Interpreter, mine, others *[]*waiter, value Value) (Value, error) {
+	g := ch.tasks
+	g.mu.Lock()
+	if len(*others) > 0 {
+		other := (*others)[0]
+		*others = (*others)[1:]
+		received := other.value
+		other.value = value
+		g.wake(other, nil)
+		g.mu.Unlock()
+		return received, nil
+	}
+	w := &waiter{value: value, ch: ch, done: make(chan struct{})}
+	*mine = append(*mine, w)
+	g.waiting[w] = true
+	g.checkDeadlock()
+	g.mu.Unlock()
+
+	var cancelled <-chan struct{}
+	if intr.ctx != nil {
+		cancelled = intr.ctx.Done()
+	}
+	select {
+	case <-w.done:
+	case <-cancelled:
+		g.mu.Lock()
+		if g.waiting[w] {
+			g.remove(w)
+			w.err = intr.ctx.Err()
+		}
+		// else another task met it, or found the deadlock, before it could stop
+		g.mu.Unlock()
+	}
+	if w.err == errDeadlock && intr.ctx != nil && intr.ctx.Err() != nil {
+		// the other tasks stopped with the run, which is not a deadlock
+		return Nil(), intr.ctx.Err()
+	}
+	return w.value, w.err
+}
+
+// waiter is a task waiting on a channel with send or receive.
+type waiter struct {
+	// value is what a sender sends, or what a receiver got
+	value Value
+	// err is why it stopped waiting without another task, nil if one met it
+	err  error
+	ch   *Channel
+	done chan struct{}
+}
+
+// taskGroup are the tasks of the runs of an interpreter, shared like its globals, which it counts to find
+// the deadlocks of their channels. The main task of a run counts as running, even between runs, so that
+// a REPL line can still send to the tasks of the lines before it.
+type taskGroup struct {
+	mu sync.Mutex
+	// spawned are the tasks of spawn that have not returned
+	spawned int
+	// waiting are the tasks waiting on a channel
+	waiting map[*waiter]bool
+}
+
+func newTaskGroup() *taskGroup {
+	return &taskGroup{waiting: make(map[*waiter]bool)}
+}
+
+// spawn counts a task of spawn, until the function it returns is called, when the task returns.
+func (g *taskGroup) spawn() func() {
+	g.mu.Lock()
+	g.spawned++
+	g.mu.Unlock()
+	return func() {
+		g.mu.Lock()
+		g.spawned--
+		g.checkDeadlock()
+		g.mu.Unlock()
+	}
+}
+
+// checkDeadlock stops the waiting tasks with errDeadlock if every task waits, as none could ever wake them.
+// g is locked.
+func (g *taskGroup) checkDeadlock() {
+	if len(g.waiting) == 0 || len(g.waiting) < g.spawned+1 {
+		return
+	}
+	for w := range g.waiting {
+		g.remove(w)
+		g.wake(w, errDeadlock)
+	}
+}
+
+// wake ends the wait of w, with err if no task met it. g is locked.
+func (g *taskGroup) wake(w *waiter, err error) {
+	delete(g.waiting, w)
+	w.err = err
+	close(w.done)
+}
+
+// remove takes w out of the waiting tasks and of the queue of its channel, without waking it. g is locked.
+func (g *taskGroup) remove(w *waiter) {
+	delete(g.waiting, w)
+	for _, queue := range []*[]*waiter{&w.ch.senders, &w.ch.receivers} {
+		for i, other := range *queue {
+			if other == w {
+				*queue = append((*queue)[:i:i], (*queue)[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package interp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChannels(t *testing.T) {
+	source := `
+var c = chan();
+spawn send(c, 1);
+spawn send(c, 2);
+var sum = receive(c) + receive(c);
+print sum;
+spawn receive(c);
+send(c, "hello");
+`
+	if got := run(t, New(), source); got != "3\n" {
+		t.Errorf("got %q, want 3", got)
+	}
+}
+
+func TestChannelDeadlock(t *testing.T) {
+	tests := []string{
+		"send(chan(), 1);",
+		"receive(chan());",
+		// the only other task returns without sending
+		"var c = chan(); spawn clock(); receive(c);",
+		// every task waits on the other
+		"var a = chan(); var b = chan(); spawn send(a, receive(b)); receive(a);",
+	}
+	for _, source := range tests {
+		intr := New()
+		intr.Stdout = &bytes.Buffer{}
+		err := intr.EvalContext(context.Background(), source)
+		var re RuntimeError
+		if !errors.As(err, &re) || re.Message != errDeadlock.Error() {
+			t.Errorf("%s: got %v, want a deadlock", source, err)
+		}
+	}
+}
+
+func TestChannelContext(t *testing.T) {
+	intr := New()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// a task sleeping could still send, so the wait is not a deadlock
+	err := intr.EvalContext(ctx, "var c = chan(); spawn sleep(100000); receive(c);")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestTasksShareJSONObjects(t *testing.T) {
+	// the tasks encode o while the main task assigns its members
+	source := `
+var o = jsonParse("{}");
+spawn jsonStringify(o, false);
+spawn jsonStringify(o, true);
+o.a = 1;
+o.b = 2;
+o.c = 3;
+print o.a + o.b + o.c;
+`
+	if got := run(t, New(), source); got != "6\n" {
+		t.Errorf("got %q, want 6", got)
+	}
+}
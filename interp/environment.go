@@ -0,0 +1,142 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// Environment holds the variables of a program and their values.
+// It is safe for concurrent use by the tasks of spawn.
+//
+// Each name has a slot, which the VM looks up once per chunk: GetSlot and AssignSlot then index a slice
+// rather than hash the name.
+type Environment struct {
+	// mu is only used once the environment is shared by tasks: a lock costs as much as most instructions
+	mu     sync.RWMutex
+	shared bool
+	slots  map[string]int
+	// values are by slot. A slot can be looked up before its variable is defined.
+	values []binding
+}
+
+// binding is the value of a slot, if its variable is defined.
+type binding struct {
+	value   Value
+	defined bool
+}
+
+// NewEnvironment returns an environment without variables.
+func NewEnvironment() *Environment {
+	return &Environment{slots: make(map[string]int)}
+}
+
+// Define creates the variable name with value, or sets it if it exists: a program can declare
+// a variable again, like the REPL often does.
+func (env *Environment) Define(name string, value Value) {
+	env.DefineSlot(env.Slot(name), value)
+}
+
+// Get returns the value of the variable name.
+func (env *Environment) Get(name token.Token) (Value, error) {
+	if env.shared {
+		env.mu.RLock()
+		defer env.mu.RUnlock()
+	}
+	if slot, ok := env.slots[name.Lexeme]; ok && env.values[slot].defined {
+		return env.values[slot].value, nil
+	}
+	return Nil(), undefinedVariable(name)
+}
+
+// Assign sets the variable name, which must exist, to value.
+func (env *Environment) Assign(name token.Token, value Value) error {
+	if env.shared {
+		env.mu.Lock()
+		defer env.mu.Unlock()
+	}
+	slot, ok := env.slots[name.Lexeme]
+	if !ok || !env.values[slot].defined {
+		return undefinedVariable(name)
+	}
+	env.values[slot].value = value
+	return nil
+}
+
+// Slot returns the slot of the variable name, which need not be defined yet.
+func (env *Environment) Slot(name string) int {
+	if env.shared {
+		env.mu.Lock()
+		defer env.mu.Unlock()
+	}
+	if slot, ok := env.slots[name]; ok {
+		return slot
+	}
+	env.slots[name] = len(env.values)
+	env.values = append(env.values, binding{})
+	return len(env.values) - 1
+}
+
+// DefineSlot is Define of the variable of slot.
+func (env *Environment) DefineSlot(slot int, value Value) {
+	if env.shared {
+		env.mu.Lock()
+		defer env.mu.Unlock()
+	}
+	env.values[slot] = binding{value: value, defined: true}
+}
+
+// GetSlot is Get of the variable of slot, whose name is reported if it is not defined.
+func (env *Environment) GetSlot(slot int, name token.Token) (Value, error) {
+	if env.shared {
+		env.mu.RLock()
+		defer env.mu.RUnlock()
+	}
+	if !env.values[slot].defined {
+		return Nil(), undefinedVariable(name)
+	}
+	return env.values[slot].value, nil
+}
+
+// AssignSlot is Assign of the variable of slot.
+func (env *Environment) AssignSlot(slot int, name token.Token, value Value) error {
+	if env.shared {
+		env.mu.Lock()
+		defer env.mu.Unlock()
+	}
+	if !env.values[slot].defined {
+		return undefinedVariable(name)
+	}
+	env.values[slot].value = value
+	return nil
+}
+
+// Names returns the names of the variables, sorted.
+func (env *Environment) Names() []string {
+	if env.shared {
+		env.mu.RLock()
+		defer env.mu.RUnlock()
+	}
+	names := make([]string, 0, len(env.slots))
+	for name, slot := range env.slots {
+		if env.values[slot].defined {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// share makes the environment lock its variables, before a task that uses it starts. shared is only
+// written by the first call, before there is any task, so it needs no lock itself.
+func (env *Environment) share() {
+	if !env.shared {
+		env.shared = true
+	}
+}
+
+func undefinedVariable(name token.Token) error {
+	return RuntimeError{Span: name.Span, Message: fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)}
+}
@@ -0,0 +1,150 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// formatBuiltins are the builtins of formatted output, whose format is that of C's printf, with the verbs
+//
+//	%d  an integer, or a number with no fraction
+//	%f  a number in decimal notation, with 6 decimals unless a precision gives them, like %.2f
+//	%e  a number in scientific notation
+//	%g  a number like %e for large exponents and like %f otherwise, without the zeros of the end
+//	%s  any value, as print writes it; a precision is the number of characters to keep
+//	%v  %s
+//	%%  a percent sign
+//
+// and the flags of Go's fmt: a width pads to that many characters, with spaces on the left, or on the
+// right with -, or zeros with 0, and + writes the sign of positive numbers.
+var formatBuiltins = []*NativeFunction{
+	{
+		Name:     "format",
+		NumArgs:  1,
+		Variadic: true,
+		// format(format, values...) is the string of values formatted by format
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			s, err := formatValues(intr, "format", arguments)
+			if err != nil {
+				return Nil(), err
+			}
+			return String(s), nil
+		},
+	},
+	{
+		Name:     "printf",
+		NumArgs:  1,
+		Variadic: true,
+		// printf(format, values...) prints format(format, values...), without adding a new line
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			s, err := formatValues(intr, "printf", arguments)
+			if err != nil {
+				return Nil(), err
+			}
+			fmt.Fprint(intr.Output(), s)
+			return Nil(), nil
+		},
+	},
+}
+
+// maxFormatWidth is the largest width or precision of a verb, so that a format cannot make a string larger
+// than MaxMemory before it is counted.
+const maxFormatWidth = 1000
+
+// formatValues returns the values that follow the format of arguments formatted by it, for the builtin name.
+func formatValues(intr Interpreter, name string, arguments []Value) (string, error) {
+	if arguments[0].Kind() != StringKind {
+		return "", fmt.Errorf("The first argument of %s must be a format string, got %s.", name, typeName(arguments[0]))
+	}
+	format, values := arguments[0].AsString(), arguments[1:]
+	var b strings.Builder
+	used := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		// the verb is the flags, the width, the precision and its letter
+		start := i
+		for i++; i < len(format) && strings.IndexByte("+- 0#", format[i]) >= 0; i++ {
+		}
+		width, precision := 0, 0
+		number := func(n *int) {
+			for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+				if *n <= maxFormatWidth {
+					*n = *n*10 + int(format[i]-'0')
+				}
+			}
+		}
+		number(&width)
+		if i < len(format) && format[i] == '.' {
+			i++
+			number(&precision)
+		}
+		if width > maxFormatWidth || precision > maxFormatWidth {
+			return "", fmt.Errorf("The width and the precision in the format of %s must be at most %d.", name, maxFormatWidth)
+		}
+		if i == len(format) {
+			return "", fmt.Errorf("The format of %s ends in the middle of a verb.", name)
+		}
+		verb := format[start : i+1]
+		if format[i] == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if strings.IndexByte("dfegsv", format[i]) < 0 {
+			return "", fmt.Errorf("Unknown verb %s in the format of %s, which has %%d, %%f, %%e, %%g, %%s, %%v and %%%%.", verb, name)
+		}
+		if used == len(values) {
+			return "", fmt.Errorf("The format of %s has more verbs than the %s it got.", name, count(len(values), "value"))
+		}
+		s, err := formatValue(verb, values[used])
+		if err != nil {
+			return "", fmt.Errorf("Cannot format %s with %s for %s: %s.", values[used], verb, name, err)
+		}
+		b.WriteString(s)
+		used++
+	}
+	if used < len(values) {
+		return "", fmt.Errorf("The format of %s has %s for the %s it got.", name, count(used, "verb"), count(len(values), "value"))
+	}
+	if err := intr.allocate(b.Len()); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// formatValue returns value formatted by verb, one of the verbs of format, which is also a verb of Go's fmt
+// for its conversion to Go.
+func formatValue(verb string, value Value) (string, error) {
+	switch verb[len(verb)-1] {
+	case 'd':
+		if !value.IsNumber() {
+			return "", fmt.Errorf("it needs a number, got %s", typeName(value))
+		}
+		if value.Kind() == IntKind {
+			return fmt.Sprintf(verb, value.AsInt()), nil
+		}
+		x := value.AsFloat()
+		if x != math.Trunc(x) || x < -(1<<63) || x >= 1<<63 {
+			return "", fmt.Errorf("it needs an integer")
+		}
+		return fmt.Sprintf(verb, int64(x)), nil
+	case 'f', 'e', 'g':
+		if !value.IsNumber() {
+			return "", fmt.Errorf("it needs a number, got %s", typeName(value))
+		}
+		return fmt.Sprintf(verb, value.AsFloat()), nil
+	}
+	return fmt.Sprintf(verb[:len(verb)-1]+"s", stringify(value)), nil
+}
+
+// count returns n things, like "1 value" or "2 values".
+func count(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	return strconv.Itoa(n) + " " + thing + "s"
+}
@@ -0,0 +1,32 @@
+package interp
+
+import "github.com/gadumitrachioaiei/go-lox/token"
+
+// Hooks are functions an interpreter calls as it runs code, to build tracers, debuggers or coverage tools
+// on top of it. Any of them can be nil.
+// The bytecode VM runs no nodes, so it only calls OnCall and OnReturn, through Call.
+type Hooks struct {
+	// OnEnterNode is called before node, an ast.Stmt or an ast.Expr, runs.
+	OnEnterNode func(node interface{}, span token.Span)
+	// OnExitNode is called after node ran, with the value of an expression, nil for a statement,
+	// and the error that stopped it, if any.
+	OnExitNode func(node interface{}, span token.Span, value Value, err error)
+	// OnCall is called before function is called with arguments. span is the closing parenthesis of the call.
+	OnCall func(function string, arguments []Value, span token.Span)
+	// OnReturn is called after function returned value, or failed with err.
+	OnReturn func(function string, value Value, err error, span token.Span)
+}
+
+// enter calls OnEnterNode, if any.
+func (h *Hooks) enter(node interface{}, span token.Span) {
+	if h != nil && h.OnEnterNode != nil {
+		h.OnEnterNode(node, span)
+	}
+}
+
+// exit calls OnExitNode, if any.
+func (h *Hooks) exit(node interface{}, span token.Span, value Value, err error) {
+	if h != nil && h.OnExitNode != nil {
+		h.OnExitNode(node, span, value, err)
+	}
+}
@@ -0,0 +1,908 @@
+// Package interp runs Lox programs by walking their syntax tree.
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// Interpreter evaluates statements and expressions.
+// An interpreter made by New keeps its global variables from one call to the next, so the lines of a REPL
+// can use what the previous ones defined. The zero value runs every call in new globals.
+type Interpreter struct {
+	// IEEEDivision makes a division by zero evaluate to +Inf, -Inf or NaN, as in IEEE 754, instead of failing.
+	IEEEDivision bool
+	// CoerceStrings makes + between a string and a value of another type stringify that value and concatenate,
+	// instead of failing.
+	CoerceStrings bool
+	// MaxDepth limits how deeply expressions can nest when they are evaluated, so that a deep tree is
+	// a runtime error instead of running out of stack. 0 means DefaultMaxDepth.
+	MaxDepth int
+	// MaxSteps limits how many statements and expressions a run can evaluate, so that a script cannot run
+	// for too long: the bytecode VM counts its instructions instead. 0 means no limit.
+	MaxSteps int
+	// MaxMemory limits the approximate number of bytes of the values a run creates, so that a script cannot
+	// use up the memory of its host. Only strings made by concatenation and read from files allocate so far.
+	// 0 means no limit.
+	MaxMemory int
+	// AllowFiles lets the code read and write files, with readFile, writeFile and appendFile, which fail
+	// otherwise: a host that runs code it does not trust leaves it off, as it sets the limits.
+	AllowFiles bool
+	// Args are the arguments of the code, which argCount and arg give, like the words after the script
+	// on the command line of the CLI.
+	Args []string
+	// Stdin is where readLine reads, nil for os.Stdin.
+	Stdin io.Reader
+	// Stdout is where print writes, nil for os.Stdout.
+	Stdout io.Writer
+	// Stderr is where the diagnostics of the code go, nil for os.Stderr. Hosts like the CLI report
+	// the errors of a run there.
+	Stderr io.Writer
+	// Hooks are called as the code runs, nil for none.
+	Hooks *Hooks
+	// Stats, if not nil, counts what the runs do, across runs.
+	Stats *Stats
+	// depth is the current evaluation depth, shared by the copies of the interpreter made during a run
+	depth *int
+	// steps are the statements and expressions evaluated so far in the run, shared like depth
+	steps *int
+	// allocated are the bytes of the values created so far in the run, shared like depth
+	allocated *int
+	// frames are the calls being run, the innermost last, shared like depth
+	frames *[]Frame
+	// globals are the global variables, shared by the copies of the interpreter
+	globals *Environment
+	// input buffers the lines of readLine, shared like globals
+	input *lineReader
+	// random is the generator of random and randomInt, shared like globals
+	random *randomSource
+	// tasks are the tasks of spawn, and those waiting on channels, shared like globals
+	tasks *taskGroup
+	// ctx stops the run when it is done, nil for none
+	ctx context.Context
+}
+
+// Stats are counts of what the runs of an interpreter did. The tasks of spawn are not counted.
+type Stats struct {
+	// Steps are the statements and expressions evaluated, or the instructions run by the bytecode VM.
+	Steps int
+}
+
+// New returns an interpreter whose global environment has only the builtin functions, like clock.
+func New() Interpreter {
+	return Interpreter{globals: newGlobals(), input: &lineReader{}, random: newRandomSource(), tasks: newTaskGroup()}
+}
+
+// Globals returns the global variables of the interpreter, nil for the zero value.
+func (intr Interpreter) Globals() *Environment {
+	return intr.globals
+}
+
+// Output returns the writer of print: Stdout, or os.Stdout.
+func (intr Interpreter) Output() io.Writer {
+	if intr.Stdout == nil {
+		return os.Stdout
+	}
+	return intr.Stdout
+}
+
+// ErrorOutput returns the writer of diagnostics: Stderr, or os.Stderr.
+func (intr Interpreter) ErrorOutput() io.Writer {
+	if intr.Stderr == nil {
+		return os.Stderr
+	}
+	return intr.Stderr
+}
+
+// Input returns the reader of readLine: Stdin, or os.Stdin.
+func (intr Interpreter) Input() io.Reader {
+	if intr.Stdin == nil {
+		return os.Stdin
+	}
+	return intr.Stdin
+}
+
+// newString returns the value of a string the code made with operator, counting its bytes.
+func (intr Interpreter) newString(operator token.Token, s string) (Value, error) {
+	if err := intr.allocate(len(s)); err != nil {
+		return Nil(), RuntimeError{Span: operator.Span, Message: err.Error()}
+	}
+	return String(s), nil
+}
+
+// allocate counts size bytes of values the code made, and fails once they are more than MaxMemory.
+func (intr Interpreter) allocate(size int) error {
+	if intr.allocated != nil {
+		*intr.allocated += size
+		if intr.MaxMemory > 0 && *intr.allocated > intr.MaxMemory {
+			return fmt.Errorf("Memory limit of %d bytes exceeded.", intr.MaxMemory)
+		}
+	}
+	return nil
+}
+
+// DefaultMaxDepth is the evaluation depth an interpreter allows when its MaxDepth is not set.
+const DefaultMaxDepth = 1000
+
+// Interpret executes statements until the first runtime error, which it returns.
+func (intr Interpreter) Interpret(statements []ast.Stmt) error {
+	return intr.InterpretContext(context.Background(), statements)
+}
+
+// InterpretContext is Interpret, but it stops with the error of ctx as soon as ctx is done, which it checks
+// before each statement, so that a host can cancel a script or give it a deadline.
+func (intr Interpreter) InterpretContext(ctx context.Context, statements []ast.Stmt) error {
+	intr.start()
+	intr.ctx = ctx
+	for _, stmt := range statements {
+		if err := intr.execute(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InterpretExpression evaluates expr and returns its value formatted for printing.
+func (intr Interpreter) InterpretExpression(expr ast.Expr) (string, error) {
+	value, err := intr.Evaluate(expr)
+	if err != nil {
+		return "", err
+	}
+	return stringify(value), nil
+}
+
+// Evaluate evaluates expr and returns its value.
+func (intr Interpreter) Evaluate(expr ast.Expr) (Value, error) {
+	intr.start()
+	return intr.evaluate(expr)
+}
+
+// BeginRun returns a copy of the interpreter ready for a run, with new counters for its limits, and with
+// new globals if it has none. Another backend runs code with it, through Unary, Binary and Call.
+func (intr Interpreter) BeginRun() Interpreter {
+	intr.start()
+	return intr
+}
+
+// BeginRunContext is BeginRun for a run that stops when ctx is done, like InterpretContext: the builtins
+// that wait, like sleep and receive, and the tasks of spawn stop with the error of ctx. The backend checks
+// ctx itself between its steps.
+func (intr Interpreter) BeginRunContext(ctx context.Context) Interpreter {
+	intr.start()
+	intr.ctx = ctx
+	return intr
+}
+
+// start prepares the state of a run.
+func (intr *Interpreter) start() {
+	intr.depth = new(int)
+	intr.steps = new(int)
+	intr.allocated = new(int)
+	intr.frames = new([]Frame)
+	if intr.globals == nil {
+		intr.globals = newGlobals()
+	}
+	if intr.input == nil {
+		intr.input = &lineReader{}
+	}
+	if intr.random == nil {
+		intr.random = newRandomSource()
+	}
+	if intr.tasks == nil {
+		intr.tasks = newTaskGroup()
+	}
+}
+
+// result is what evaluating an expression gives: its value, or the runtime error that stopped the evaluation.
+type result struct {
+	value Value
+	err   error
+}
+
+// EvalContext scans, parses and runs source like InterpretContext, with the keywords of the extensions, like spawn.
+// It returns the first scan or syntax error, if any, without running anything.
+func (intr Interpreter) EvalContext(ctx context.Context, source string) error {
+	s := scanner.NewWithConfig(source, scanner.Config{Keywords: scanner.Keywords()})
+	tokens, errs := s.ScanTokens()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	statements, errs := parser.New(tokens).Parse()
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return intr.InterpretContext(ctx, statements)
+}
+
+func (intr Interpreter) execute(stmt ast.Stmt) error {
+	if intr.ctx != nil {
+		if err := intr.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	span := stmt.SourceSpan()
+	if err := intr.step(span); err != nil {
+		return err
+	}
+	intr.Hooks.enter(stmt, span)
+	err := ast.AcceptStmt[error](stmt, intr)
+	intr.Hooks.exit(stmt, span, Nil(), err)
+	return err
+}
+
+// step counts a statement or an expression of the code in span, and fails when the run has used up MaxSteps.
+func (intr Interpreter) step(span token.Span) error {
+	*intr.steps++
+	if intr.Stats != nil {
+		intr.Stats.Steps++
+	}
+	if intr.MaxSteps > 0 && *intr.steps > intr.MaxSteps {
+		return StepBudgetError(intr.MaxSteps, span)
+	}
+	return nil
+}
+
+// StepBudgetError returns the error of a run that went over its budget of steps, at the code in span.
+func StepBudgetError(budget int, span token.Span) RuntimeError {
+	return RuntimeError{Span: span, Message: fmt.Sprintf("Step budget of %d exceeded.", budget)}
+}
+
+func (intr Interpreter) evaluate(expr ast.Expr) (Value, error) {
+	*intr.depth++
+	defer func() { *intr.depth-- }()
+	maxDepth := intr.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if *intr.depth > maxDepth {
+		return Nil(), RuntimeError{Span: expr.SourceSpan(), Message: "Expression nested too deeply."}
+	}
+	span := expr.SourceSpan()
+	if err := intr.step(span); err != nil {
+		return Nil(), err
+	}
+	intr.Hooks.enter(expr, span)
+	r := ast.AcceptExpr[result](expr, intr)
+	intr.Hooks.exit(expr, span, r.value, r.err)
+	return r.value, r.err
+}
+
+func (intr Interpreter) VisitExpressionStmt(stmt ast.ExpressionStmt) error {
+	_, err := intr.evaluate(stmt.Expr)
+	return err
+}
+
+func (intr Interpreter) VisitPrintStmt(stmt ast.PrintStmt) error {
+	value, err := intr.evaluate(stmt.Expr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(intr.Output(), stringify(value))
+	return nil
+}
+
+func (intr Interpreter) VisitSpawnStmt(stmt ast.SpawnStmt) error {
+	call := stmt.Call.(ast.Call)
+	callee, err := intr.evaluate(call.Callee)
+	if err != nil {
+		return err
+	}
+	arguments := make([]Value, len(call.Arguments))
+	for i, argument := range call.Arguments {
+		if arguments[i], err = intr.evaluate(argument); err != nil {
+			return err
+		}
+	}
+	return intr.Spawn(callee, arguments, call.Paren)
+}
+
+func (intr Interpreter) VisitVarStmt(stmt ast.VarStmt) error {
+	var value Value
+	if stmt.Initializer != nil {
+		var err error
+		if value, err = intr.evaluate(stmt.Initializer); err != nil {
+			return err
+		}
+	}
+	intr.globals.Define(stmt.Name.Lexeme, value)
+	return nil
+}
+
+func (intr Interpreter) VisitVariableExpr(expr ast.Variable) result {
+	value, err := intr.globals.Get(expr.Name)
+	return result{value, err}
+}
+
+func (intr Interpreter) VisitAssignExpr(expr ast.Assign) result {
+	value, err := intr.evaluate(expr.Value)
+	if err != nil {
+		return result{err: err}
+	}
+	if err := intr.globals.Assign(expr.Name, value); err != nil {
+		return result{err: err}
+	}
+	return result{value: value}
+}
+
+func (intr Interpreter) VisitLiteralExpr(expr ast.Literal) result {
+	return result{value: FromGo(expr.Value)}
+}
+
+func (intr Interpreter) VisitGroupingExpr(expr ast.Grouping) result {
+	value, err := intr.evaluate(expr.Expr)
+	return result{value, err}
+}
+
+func (intr Interpreter) VisitUnaryExpr(expr ast.Unary) result {
+	operand, err := intr.evaluate(expr.Right)
+	if err != nil {
+		return result{err: err}
+	}
+	value, err := intr.Unary(expr.Operator, operand)
+	return result{value, err}
+}
+
+// Unary applies a unary operator to its operand, the way the interpreter evaluates it.
+// Other backends use it to give the same results and errors.
+func (intr Interpreter) Unary(operator token.Token, operand Value) (Value, error) {
+	switch operator.Type {
+	case token.MINUS:
+		if err := checkNumberOperand(operator, operand); err != nil {
+			return Nil(), err
+		}
+		if n := operand.AsInt(); operand.Kind() == IntKind && n != math.MinInt64 {
+			return Int(-n), nil
+		}
+		return Float(-operand.AsFloat()), nil
+	case token.BANG:
+		return Bool(!operand.Truthy()), nil
+	}
+	panic(fmt.Sprintf("unknown unary operator: %v", operator))
+}
+
+func (intr Interpreter) VisitBinaryExpr(expr ast.Binary) result {
+	left, err := intr.evaluate(expr.Left)
+	if err != nil {
+		return result{err: err}
+	}
+	right, err := intr.evaluate(expr.Right)
+	if err != nil {
+		return result{err: err}
+	}
+	value, err := intr.Binary(expr.Operator, left, right)
+	return result{value, err}
+}
+
+// Binary applies an arithmetic, comparison or equality operator to its operands, like Unary.
+func (intr Interpreter) Binary(operator token.Token, left, right Value) (Value, error) {
+	switch operator.Type {
+	case token.SLASH:
+		if !intr.IEEEDivision {
+			if err := checkNumberOperands(operator, left, right); err != nil {
+				return Nil(), err
+			}
+			if err := checkNonZeroDivisor(operator, right); err != nil {
+				return Nil(), err
+			}
+		}
+		return arithmetic(operator, left, right)
+	case token.MINUS, token.STAR:
+		return arithmetic(operator, left, right)
+	case token.PLUS:
+		if left.IsNumber() && right.IsNumber() {
+			return arithmetic(operator, left, right)
+		}
+		okLeft, okRight := left.Kind() == StringKind, right.Kind() == StringKind
+		if okLeft && okRight {
+			return intr.newString(operator, left.AsString()+right.AsString())
+		}
+		if intr.CoerceStrings && (okLeft || okRight) {
+			return intr.newString(operator, stringify(left)+stringify(right))
+		}
+		return Nil(), operatorError(operator, "Operands must be two numbers or two strings", left, right)
+	case token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL:
+		holds, err := compare(operator, left, right)
+		return Bool(holds), err
+	case token.EQUAL_EQUAL:
+		return Bool(isEqual(left, right)), nil
+	case token.BANG_EQUAL:
+		return Bool(!isEqual(left, right)), nil
+	}
+	panic(fmt.Sprintf("unknown binary operator: %v", operator))
+}
+
+func (intr Interpreter) VisitLogicalExpr(expr ast.Logical) result {
+	left, err := intr.evaluate(expr.Left)
+	if err != nil {
+		return result{err: err}
+	}
+	switch expr.Operator.Type {
+	case token.QUESTION_QUESTION:
+		if !left.IsNil() {
+			return result{value: left}
+		}
+	case token.OR:
+		if left.Truthy() {
+			return result{value: left}
+		}
+	case token.AND:
+		if !left.Truthy() {
+			return result{value: left}
+		}
+	}
+	value, err := intr.evaluate(expr.Right)
+	return result{value, err}
+}
+
+func (intr Interpreter) VisitComparisonExpr(expr ast.Comparison) result {
+	left, err := intr.evaluate(expr.Operands[0])
+	if err != nil {
+		return result{err: err}
+	}
+	for i, operator := range expr.Operators {
+		right, err := intr.evaluate(expr.Operands[i+1])
+		if err != nil {
+			return result{err: err}
+		}
+		holds, err := compare(operator, left, right)
+		if err != nil || !holds {
+			return result{value: Bool(false), err: err}
+		}
+		left = right
+	}
+	return result{value: Bool(true)}
+}
+
+func (intr Interpreter) VisitCallExpr(expr ast.Call) result {
+	callee, err := intr.evaluate(expr.Callee)
+	if err != nil {
+		return result{err: err}
+	}
+	arguments := make([]Value, len(expr.Arguments))
+	for i, argument := range expr.Arguments {
+		if arguments[i], err = intr.evaluate(argument); err != nil {
+			return result{err: err}
+		}
+	}
+	value, err := intr.Call(callee, arguments, expr.Paren)
+	return result{value, err}
+}
+
+func (intr Interpreter) VisitGetExpr(expr ast.Get) result {
+	object, err := intr.evaluate(expr.Object)
+	if err != nil {
+		return result{err: err}
+	}
+	value, err := intr.GetProperty(object, expr.Name)
+	return result{value, err}
+}
+
+func (intr Interpreter) VisitSetExpr(expr ast.Set) result {
+	object, err := intr.evaluate(expr.Object)
+	if err != nil {
+		return result{err: err}
+	}
+	value, err := intr.evaluate(expr.Value)
+	if err != nil {
+		return result{err: err}
+	}
+	if err := intr.SetProperty(object, expr.Name, value); err != nil {
+		return result{err: err}
+	}
+	return result{value: value}
+}
+
+// GetProperty returns the property name of object, which must be an Instance, like Unary.
+func (intr Interpreter) GetProperty(object Value, name token.Token) (Value, error) {
+	return intr.GetPropertyCached(object, name, nil)
+}
+
+// GetPropertyCached is GetProperty with the cache of the site that reads the property, which is nil
+// for none.
+func (intr Interpreter) GetPropertyCached(object Value, name token.Token, cache *PropertyCache) (Value, error) {
+	instance, ok := object.AsObject().(Instance)
+	if !ok {
+		return Nil(), RuntimeError{Span: name.Span, Message: "Only instances have properties."}
+	}
+	var value Value
+	var err error
+	if bound, ok := instance.(*boundObject); ok {
+		value, err = bound.get(name.Lexeme, cache)
+	} else {
+		value, err = instance.Get(name.Lexeme)
+	}
+	if err != nil {
+		return Nil(), propertyError(name, err)
+	}
+	return value, nil
+}
+
+// SetProperty sets the property name of object, which must be an Instance, to value, like Unary.
+func (intr Interpreter) SetProperty(object Value, name token.Token, value Value) error {
+	return intr.SetPropertyCached(object, name, value, nil)
+}
+
+// SetPropertyCached is SetProperty with the cache of the site that writes the property, like GetPropertyCached.
+func (intr Interpreter) SetPropertyCached(object Value, name token.Token, value Value, cache *PropertyCache) error {
+	instance, ok := object.AsObject().(Instance)
+	if !ok {
+		return RuntimeError{Span: name.Span, Message: "Only instances have fields."}
+	}
+	var err error
+	if bound, ok := instance.(*boundObject); ok {
+		err = bound.set(name.Lexeme, value, cache)
+	} else {
+		err = instance.Set(name.Lexeme, value)
+	}
+	if err != nil {
+		return propertyError(name, err)
+	}
+	return nil
+}
+
+// propertyError returns err of the property name as a runtime error at name.
+func propertyError(name token.Token, err error) error {
+	var re RuntimeError
+	if errors.As(err, &re) {
+		return re
+	}
+	return RuntimeError{Span: name.Span, Message: err.Error()}
+}
+
+// Call calls callee with arguments, like Unary. paren is the closing parenthesis of the call,
+// where its errors are reported.
+func (intr Interpreter) Call(callee Value, arguments []Value, paren token.Token) (Value, error) {
+	function, err := callable(callee, arguments, paren)
+	if err != nil {
+		return Nil(), err
+	}
+	if intr.frames == nil {
+		// called from outside a run, by another backend
+		intr.frames = new([]Frame)
+	}
+	name := callableName(function)
+	*intr.frames = append(*intr.frames, Frame{Function: name, Line: paren.Line})
+	defer func() { *intr.frames = (*intr.frames)[:len(*intr.frames)-1] }()
+	if intr.Hooks != nil && intr.Hooks.OnCall != nil {
+		intr.Hooks.OnCall(name, arguments, paren.Span)
+	}
+	value, err := function.Call(intr, arguments)
+	if intr.Hooks != nil && intr.Hooks.OnReturn != nil {
+		intr.Hooks.OnReturn(name, value, err, paren.Span)
+	}
+	if err != nil {
+		var exit ExitError
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &exit) {
+			// a builtin stopped with the run, or ended it, which is not an error of the code, as between statements
+			return Nil(), err
+		}
+		var re RuntimeError
+		if !errors.As(err, &re) {
+			re = RuntimeError{Span: paren.Span, Message: err.Error()}
+		}
+		// the innermost call the error goes through has all the calls around it
+		if re.Trace == nil {
+			for i := len(*intr.frames) - 1; i >= 0; i-- {
+				re.Trace = append(re.Trace, (*intr.frames)[i])
+			}
+		}
+		return Nil(), re
+	}
+	return value, nil
+}
+
+// Spawn calls callee with arguments in a new task, on its own goroutine, like Call but without waiting
+// for it: only the errors of the call itself, like a wrong number of arguments, are returned.
+// The task shares the globals of the run, has its own limits of depth, steps and memory, and no hooks or stats.
+// Nothing waits for a task, so its runtime error is written to ErrorOutput, exit only ends the task, the
+// task stops silently with the context of the run, and the program ends with its main task.
+// The globals, the channels and the instances of jsonParse are locked, but the tasks use the objects of
+// Bind, and the Stdout and Stderr of a host, without locking: a script passes a bound object from task to
+// task through a channel, and a host gives writers that can be written concurrently, like os.Stdout.
+func (intr Interpreter) Spawn(callee Value, arguments []Value, paren token.Token) error {
+	if _, err := callable(callee, arguments, paren); err != nil {
+		return err
+	}
+	intr.globals.share()
+	task := intr
+	task.Hooks, task.Stats = nil, nil
+	task.start()
+	done := task.tasks.spawn()
+	go func() {
+		defer done()
+		_, err := task.Call(callee, arguments, paren)
+		var exit ExitError
+		if err == nil || errors.As(err, &exit) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			// the task ended, or stopped with the run
+			return
+		}
+		fmt.Fprintln(task.ErrorOutput(), err)
+	}()
+	return nil
+}
+
+// callable returns the function of callee, if it can be called with arguments.
+func callable(callee Value, arguments []Value, paren token.Token) (Callable, error) {
+	function, ok := callee.AsObject().(Callable)
+	if !ok {
+		return nil, RuntimeError{Span: paren.Span, Message: "Can only call functions and classes."}
+	}
+	if native, ok := function.(*NativeFunction); ok && native.Variadic {
+		if len(arguments) < native.NumArgs {
+			return nil, RuntimeError{Span: paren.Span, Message: fmt.Sprintf("Expected at least %d arguments but got %d.", native.NumArgs, len(arguments))}
+		}
+		return function, nil
+	}
+	if len(arguments) != function.Arity() {
+		return nil, RuntimeError{Span: paren.Span, Message: fmt.Sprintf("Expected %d arguments but got %d.", function.Arity(), len(arguments))}
+	}
+	return function, nil
+}
+
+// compare applies a comparison operator to two numbers, or to two strings in lexicographic order.
+func compare(operator token.Token, left, right Value) (bool, error) {
+	if left.Kind() == StringKind && right.Kind() == StringKind {
+		leftString, rightString := left.AsString(), right.AsString()
+		switch operator.Type {
+		case token.GREATER:
+			return leftString > rightString, nil
+		case token.GREATER_EQUAL:
+			return leftString >= rightString, nil
+		case token.LESS:
+			return leftString < rightString, nil
+		case token.LESS_EQUAL:
+			return leftString <= rightString, nil
+		}
+	}
+	if !(left.IsNumber() && right.IsNumber()) {
+		return false, operatorError(operator, "Operands must be two numbers or two strings", left, right)
+	}
+	if left.Kind() == IntKind && right.Kind() == IntKind {
+		l, r := left.AsInt(), right.AsInt()
+		switch operator.Type {
+		case token.GREATER:
+			return l > r, nil
+		case token.GREATER_EQUAL:
+			return l >= r, nil
+		case token.LESS:
+			return l < r, nil
+		case token.LESS_EQUAL:
+			return l <= r, nil
+		}
+	}
+	a, b := left.AsFloat(), right.AsFloat()
+	switch operator.Type {
+	case token.GREATER:
+		return a > b, nil
+	case token.GREATER_EQUAL:
+		return a >= b, nil
+	case token.LESS:
+		return a < b, nil
+	case token.LESS_EQUAL:
+		return a <= b, nil
+	}
+	panic(fmt.Sprintf("unknown comparison operator: %v", operator))
+}
+
+// arithmetic applies a numeric binary operator.
+// Two integers give an integer, except for a division that is not exact, which gives a float like any mixed operands.
+func arithmetic(operator token.Token, left, right Value) (Value, error) {
+	if err := checkNumberOperands(operator, left, right); err != nil {
+		return Nil(), err
+	}
+	if left.Kind() == IntKind && right.Kind() == IntKind {
+		// an integer result that overflows is a float, like a quotient with a fraction
+		var n int64
+		ok := false
+		l, r := left.AsInt(), right.AsInt()
+		switch operator.Type {
+		case token.PLUS:
+			n, ok = AddInt(l, r)
+		case token.MINUS:
+			n, ok = SubInt(l, r)
+		case token.STAR:
+			n, ok = MulInt(l, r)
+		case token.SLASH:
+			n, ok = DivInt(l, r)
+		}
+		if ok {
+			return Int(n), nil
+		}
+	}
+	a, b := left.AsFloat(), right.AsFloat()
+	switch operator.Type {
+	case token.PLUS:
+		return Float(a + b), nil
+	case token.MINUS:
+		return Float(a - b), nil
+	case token.STAR:
+		return Float(a * b), nil
+	case token.SLASH:
+		return Float(a / b), nil
+	}
+	panic(fmt.Sprintf("unknown arithmetic operator: %v", operator))
+}
+
+func checkNumberOperands(tok token.Token, left, right Value) error {
+	if !(left.IsNumber() && right.IsNumber()) {
+		return operatorError(tok, "Operands must be numbers", left, right)
+	}
+	return nil
+}
+
+func checkNumberOperand(tok token.Token, operand Value) error {
+	if !operand.IsNumber() {
+		return operatorError(tok, "Operand must be a number", operand)
+	}
+	return nil
+}
+
+func checkNonZeroDivisor(tok token.Token, divisor Value) error {
+	if divisor.AsFloat() == 0 {
+		return operatorError(tok, "Division by zero")
+	}
+	return nil
+}
+
+// stringify formats a value the way the reference Lox implementation prints it.
+func stringify(v Value) string {
+	switch v.Kind() {
+	case NilKind:
+		return "nil"
+	case BoolKind:
+		return strconv.FormatBool(v.AsBool())
+	case IntKind:
+		return strconv.FormatInt(v.AsInt(), 10)
+	case StringKind:
+		return v.AsString()
+	case FloatKind:
+		obj := v.AsFloat()
+		switch {
+		case math.IsNaN(obj):
+			return "NaN"
+		case math.IsInf(obj, 1):
+			return "Infinity"
+		case math.IsInf(obj, -1):
+			return "-Infinity"
+		case math.Abs(obj) >= 1e21:
+			// plain notation would print every digit of huge numbers
+			return strconv.FormatFloat(obj, 'g', -1, 64)
+		}
+		return strconv.FormatFloat(obj, 'f', -1, 64)
+	}
+	return fmt.Sprint(v.AsObject())
+}
+
+func isEqual(a, b Value) bool {
+	// integers and floats are both numbers, so 1 == 1.0
+	if a.IsNumber() && b.IsNumber() {
+		if a.Kind() == IntKind && b.Kind() == IntKind {
+			return a.AsInt() == b.AsInt()
+		}
+		return a.AsFloat() == b.AsFloat()
+	}
+	if a.Kind() != b.Kind() {
+		return false
+	}
+	switch a.Kind() {
+	case NilKind:
+		return true
+	case BoolKind:
+		return a.AsBool() == b.AsBool()
+	case StringKind:
+		return a.AsString() == b.AsString()
+	}
+	return objectsEqual(a.AsObject(), b.AsObject())
+}
+
+// objectsEqual reports whether the objects x and y are the same Go value. An object can hold a value that
+// Go cannot compare, like a slice a registered function returned, which is equal to nothing.
+func objectsEqual(x, y interface{}) (equal bool) {
+	defer func() {
+		if recover() != nil {
+			equal = false
+		}
+	}()
+	return x == y
+}
+
+// RuntimeError is an error of the code being run. Its span is the code that failed, usually an operator.
+type RuntimeError struct {
+	token.Span
+	// Message says what failed, like "Operands must be numbers".
+	Message string
+	// Operator is the lexeme of the operator that failed, empty for the errors of anything else.
+	Operator string
+	// Operands are the types of the operands the operator got, as typeName names them.
+	Operands []string
+	// Trace are the calls that were running when the error happened, the innermost first.
+	Trace []Frame
+}
+
+// ExitError is the error of a run that the code ended by calling exit. It is not a runtime error: it goes
+// through every call and statement unchanged, and a host ends with Code as its status, like the CLI,
+// instead of reporting it.
+type ExitError struct {
+	Code int
+}
+
+func (e ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Frame is a call being run.
+type Frame struct {
+	// Function is the name of the function called.
+	Function string
+	// Line is the line of the call.
+	Line int
+}
+
+// StackTrace returns a line for each call of the trace, the innermost first:
+//
+//	at clock() (line 3)
+func (re RuntimeError) StackTrace() string {
+	var builder strings.Builder
+	for _, frame := range re.Trace {
+		fmt.Fprintf(&builder, "    at %s() (line %d)\n", frame.Function, frame.Line)
+	}
+	return builder.String()
+}
+
+// Error returns the message with its line and context: line 12: Operands must be numbers, got string and nil for '+'
+func (re RuntimeError) Error() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "line %d: %s", re.Line, re.Message)
+	if len(re.Operands) > 0 {
+		builder.WriteString(", got " + strings.Join(re.Operands, " and "))
+	}
+	if re.Operator != "" {
+		fmt.Fprintf(&builder, " for '%s'", re.Operator)
+	}
+	return builder.String()
+}
+
+// operatorError returns the error of operator applied to operands.
+func operatorError(operator token.Token, message string, operands ...Value) RuntimeError {
+	re := RuntimeError{Span: operator.Span, Message: message, Operator: operator.Lexeme}
+	for _, operand := range operands {
+		re.Operands = append(re.Operands, typeName(operand))
+	}
+	return re
+}
+
+// typeName returns the name of the Lox type of a value.
+func typeName(v Value) string {
+	switch v.Kind() {
+	case NilKind:
+		return "nil"
+	case BoolKind:
+		return "bool"
+	case IntKind, FloatKind:
+		return "number"
+	case StringKind:
+		return "string"
+	}
+	switch v.AsObject().(type) {
+	case Callable:
+		return "function"
+	case *Channel:
+		return "channel"
+	case *jsonArray:
+		return "array"
+	case Instance:
+		return "instance"
+	}
+	return fmt.Sprintf("%T", v.AsObject())
+}
@@ -0,0 +1,144 @@
+package interp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ioBuiltins are the builtins of input and output: readLine and eprint, on the input and the error output
+// of the interpreter, and the builtins of files, which need AllowFiles.
+var ioBuiltins = []*NativeFunction{
+	{
+		Name: "readLine",
+		// readLine() is the next line of the input, without its end, or nil at the end of the input
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			line, err := intr.input.readLine(intr.Input())
+			if err == io.EOF {
+				return Nil(), nil
+			}
+			if err != nil {
+				return Nil(), fmt.Errorf("Cannot read a line: %v.", err)
+			}
+			if err := intr.allocate(len(line)); err != nil {
+				return Nil(), err
+			}
+			return String(line), nil
+		},
+	},
+	{
+		Name:    "eprint",
+		NumArgs: 1,
+		// eprint(value) is print to the error output
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			fmt.Fprintln(intr.ErrorOutput(), arguments[0])
+			return Nil(), nil
+		},
+	},
+	{
+		Name:    "readFile",
+		NumArgs: 1,
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			path, err := fileArgument(intr, "readFile", arguments)
+			if err != nil {
+				return Nil(), err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return Nil(), fileError("read", path, err)
+			}
+			if err := intr.allocate(len(data)); err != nil {
+				return Nil(), err
+			}
+			return String(string(data)), nil
+		},
+	},
+	{
+		Name:    "writeFile",
+		NumArgs: 2,
+		// writeFile(path, value) replaces the file by the string form of value, as print writes it
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			return writeFile(intr, "writeFile", arguments, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+		},
+	},
+	{
+		Name:    "appendFile",
+		NumArgs: 2,
+		// appendFile(path, value) is writeFile at the end of the file
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			return writeFile(intr, "appendFile", arguments, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+		},
+	},
+}
+
+// writeFile writes the value of arguments to the file of their path, opened with flag, for the builtin name.
+func writeFile(intr Interpreter, name string, arguments []Value, flag int) (Value, error) {
+	path, err := fileArgument(intr, name, arguments)
+	if err != nil {
+		return Nil(), err
+	}
+	f, err := os.OpenFile(path, flag, 0o644)
+	if err != nil {
+		return Nil(), fileError("write", path, err)
+	}
+	_, err = io.WriteString(f, stringify(arguments[1]))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Nil(), fileError("write", path, err)
+	}
+	return Nil(), nil
+}
+
+// fileArgument returns the path that the builtin of files name got as its first argument, if the code may
+// use files.
+func fileArgument(intr Interpreter, name string, arguments []Value) (string, error) {
+	if !intr.AllowFiles {
+		return "", fmt.Errorf("%s needs access to files, which is not allowed.", name)
+	}
+	if arguments[0].Kind() != StringKind {
+		return "", fmt.Errorf("%s of %s must be a path, got %s.", argumentName(0, len(arguments)), name, typeName(arguments[0]))
+	}
+	return arguments[0].AsString(), nil
+}
+
+// fileError returns the error of a failure to do verb on the file path, without the operation and the path
+// that the errors of os repeat.
+func fileError(verb, path string, err error) error {
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	return fmt.Errorf("Cannot %s '%s': %v.", verb, path, err)
+}
+
+// lineReader reads the lines of readLine through a buffer, which it keeps while the input it reads stays
+// the same. The tasks of spawn share it, so it is locked.
+type lineReader struct {
+	mu     sync.Mutex
+	source io.Reader
+	reader *bufio.Reader
+}
+
+// readLine returns the next line of source, without its end, or io.EOF at its end.
+func (r *lineReader) readLine(source io.Reader) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.reader == nil || r.source != source {
+		r.source, r.reader = source, bufio.NewReader(source)
+	}
+	line, err := r.reader.ReadString('\n')
+	if err == io.EOF && line != "" {
+		// the last line has no end
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
@@ -0,0 +1,289 @@
+package interp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// jsonBuiltins are the builtins of JSON. A JSON object is an instance whose members are its properties,
+// like config.server.port, and a JSON array is an array, whose elements get reads, like
+// get(config.servers, 0), and len counts. Arrays cannot change, until Lox has lists.
+var jsonBuiltins = []*NativeFunction{
+	{
+		Name:    "jsonParse",
+		NumArgs: 1,
+		// jsonParse(s) is the value of the JSON text s: nil, a boolean, a number, an integer if it has no
+		// fraction or exponent, a string, an instance or an array
+		Func: func(arguments []Value) (Value, error) {
+			if err := stringArguments("jsonParse", arguments, 1); err != nil {
+				return Nil(), err
+			}
+			d := json.NewDecoder(strings.NewReader(arguments[0].AsString()))
+			d.UseNumber()
+			value, err := decodeJSON(d)
+			if err == nil {
+				if _, end := d.Token(); end != io.EOF {
+					err = errors.New("there is more after the value")
+				}
+			}
+			if err != nil {
+				return Nil(), fmt.Errorf("Cannot parse JSON: %v.", err)
+			}
+			return value, nil
+		},
+	},
+	{
+		Name:    "jsonStringify",
+		NumArgs: 2,
+		// jsonStringify(value, pretty) is the JSON text of value, indented if pretty is true
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			if arguments[1].Kind() != BoolKind {
+				return Nil(), fmt.Errorf("The second argument of jsonStringify must be a boolean, got %s.", typeName(arguments[1]))
+			}
+			var b bytes.Buffer
+			if err := encodeJSON(&b, arguments[0], nil); err != nil {
+				return Nil(), fmt.Errorf("Cannot write JSON: %v.", err)
+			}
+			if arguments[1].AsBool() {
+				var indented bytes.Buffer
+				json.Indent(&indented, b.Bytes(), "", "  ")
+				b = indented
+			}
+			if err := intr.allocate(b.Len()); err != nil {
+				return Nil(), err
+			}
+			return String(b.String()), nil
+		},
+	},
+	{
+		Name:    "get",
+		NumArgs: 2,
+		// get(array, i) is the element i of array, from 0, and get(object, name) the member name of a JSON
+		// object, which need not be a Lox name, like "content-type"
+		Func: func(arguments []Value) (Value, error) {
+			switch container := arguments[0].AsObject().(type) {
+			case *jsonArray:
+				if len(container.elements) == 0 {
+					return Nil(), fmt.Errorf("The first argument of get must not be empty.")
+				}
+				i, err := indexArgument("get", arguments, 1, len(container.elements)-1)
+				if err != nil {
+					return Nil(), err
+				}
+				return container.elements[i], nil
+			case *jsonObject:
+				if arguments[1].Kind() != StringKind {
+					return Nil(), fmt.Errorf("The second argument of get must be a name, got %s.", typeName(arguments[1]))
+				}
+				return container.Get(arguments[1].AsString())
+			}
+			return Nil(), fmt.Errorf("The first argument of get must be an array or a JSON object, got %s.", typeName(arguments[0]))
+		},
+	},
+}
+
+// jsonObject is an instance made by jsonParse. Its members are its properties, which assigning creates,
+// and it keeps them in order, so that jsonStringify writes them as they were read. It is locked, as the
+// tasks of spawn can share it.
+type jsonObject struct {
+	mu      sync.Mutex
+	names   []string
+	members map[string]Value
+}
+
+func newJSONObject() *jsonObject {
+	return &jsonObject{members: make(map[string]Value)}
+}
+
+func (o *jsonObject) Get(name string) (Value, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	value, ok := o.members[name]
+	if !ok {
+		return Nil(), undefinedProperty(name)
+	}
+	return value, nil
+}
+
+func (o *jsonObject) Set(name string, value Value) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.members[name]; !ok {
+		o.names = append(o.names, name)
+	}
+	o.members[name] = value
+	return nil
+}
+
+// jsonArray is an array made by jsonParse. Its elements do not change, so the tasks of spawn can share it.
+type jsonArray struct {
+	elements []Value
+}
+
+// String returns the JSON text of the array, as print writes it.
+func (a *jsonArray) String() string {
+	var b bytes.Buffer
+	if err := encodeJSON(&b, Object(a), nil); err != nil {
+		return "<json array>"
+	}
+	return b.String()
+}
+
+// snapshot returns the names of the members of o, in order, and their values, which do not change as
+// another task assigns the members.
+func (o *jsonObject) snapshot() ([]string, []Value) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	members := make([]Value, len(o.names))
+	for i, name := range o.names {
+		members[i] = o.members[name]
+	}
+	return o.names[:len(o.names):len(o.names)], members
+}
+
+// String returns the JSON text of the object, as print writes it.
+func (o *jsonObject) String() string {
+	var b bytes.Buffer
+	if err := encodeJSON(&b, Object(o), nil); err != nil {
+		return "<json object>"
+	}
+	return b.String()
+}
+
+// decodeJSON returns the value of the next JSON value of d, which uses numbers.
+func decodeJSON(d *json.Decoder) (Value, error) {
+	tok, err := d.Token()
+	if err != nil {
+		if err == io.EOF {
+			err = errors.New("unexpected end of JSON input")
+		}
+		return Nil(), err
+	}
+	switch tok := tok.(type) {
+	case nil:
+		return Nil(), nil
+	case bool:
+		return Bool(tok), nil
+	case string:
+		return String(tok), nil
+	case json.Number:
+		if n, err := strconv.ParseInt(string(tok), 10, 64); err == nil {
+			return Int(n), nil
+		}
+		x, err := strconv.ParseFloat(string(tok), 64)
+		if err != nil {
+			return Nil(), fmt.Errorf("the number %s is out of range", tok)
+		}
+		return Float(x), nil
+	case json.Delim:
+		if tok == '[' {
+			array := &jsonArray{}
+			for d.More() {
+				value, err := decodeJSON(d)
+				if err != nil {
+					return Nil(), err
+				}
+				array.elements = append(array.elements, value)
+			}
+			// the closing bracket
+			if _, err := d.Token(); err != nil {
+				return Nil(), err
+			}
+			return Object(array), nil
+		}
+		object := newJSONObject()
+		for d.More() {
+			name, err := d.Token()
+			if err != nil {
+				return Nil(), err
+			}
+			value, err := decodeJSON(d)
+			if err != nil {
+				return Nil(), err
+			}
+			object.Set(name.(string), value)
+		}
+		// the closing brace
+		if _, err := d.Token(); err != nil {
+			return Nil(), err
+		}
+		return Object(object), nil
+	}
+	return Nil(), fmt.Errorf("unexpected %v", tok)
+}
+
+// encodeJSON writes the JSON text of value to b. outer are the objects value is in, which it cannot be.
+func encodeJSON(b *bytes.Buffer, value Value, outer []*jsonObject) error {
+	switch value.Kind() {
+	case NilKind:
+		b.WriteString("null")
+	case BoolKind:
+		b.WriteString(strconv.FormatBool(value.AsBool()))
+	case IntKind:
+		b.WriteString(strconv.FormatInt(value.AsInt(), 10))
+	case FloatKind:
+		x := value.AsFloat()
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fmt.Errorf("JSON has no %s", value)
+		}
+		data, _ := json.Marshal(x)
+		b.Write(data)
+	case StringKind:
+		encodeJSONString(b, value.AsString())
+	default:
+		if array, ok := value.AsObject().(*jsonArray); ok {
+			// an array cannot be in itself, as it cannot change, but an object in it can be in an outer one
+			b.WriteByte('[')
+			for i, element := range array.elements {
+				if i > 0 {
+					b.WriteByte(',')
+				}
+				if err := encodeJSON(b, element, outer); err != nil {
+					return err
+				}
+			}
+			b.WriteByte(']')
+			return nil
+		}
+		object, ok := value.AsObject().(*jsonObject)
+		if !ok {
+			return fmt.Errorf("a %s has no JSON text, only nil, booleans, numbers, strings and the instances and arrays of jsonParse", typeName(value))
+		}
+		for _, o := range outer {
+			if o == object {
+				return errors.New("an object is in itself")
+			}
+		}
+		names, members := object.snapshot()
+		b.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			encodeJSONString(b, name)
+			b.WriteByte(':')
+			if err := encodeJSON(b, members[i], append(outer, object)); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	}
+	return nil
+}
+
+// encodeJSONString writes s as a JSON string, without escaping the characters of HTML, as a script does not
+// write HTML.
+func encodeJSONString(b *bytes.Buffer, s string) {
+	e := json.NewEncoder(b)
+	e.SetEscapeHTML(false)
+	e.Encode(s)
+	// Encode ends the value with a new line
+	b.Truncate(b.Len() - 1)
+}
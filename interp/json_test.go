@@ -0,0 +1,89 @@
+package interp
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// runJSON runs source with the global text set to the JSON text, which Lox strings cannot write, as they
+// have no escapes.
+func runJSON(t *testing.T, text, source string) string {
+	t.Helper()
+	intr := New()
+	intr.Globals().Define("text", String(text))
+	return run(t, intr, source)
+}
+
+func TestJSONParse(t *testing.T) {
+	text := `{"servers": [{"port": 80}, {"port": 443}], "content-type": "json", "empty": [], "nested": [[1, 2], [3]]}`
+	tests := []struct {
+		source, want string
+	}{
+		{"var v = jsonParse(text); print len(v.servers); print get(v.servers, 1).port;", "2\n443\n"},
+		{`print get(jsonParse(text), "content-type");`, "json\n"},
+		{"var v = jsonParse(text); print len(v.empty); print get(get(v.nested, 0), 1);", "0\n2\n"},
+		{"print type(jsonParse(text).nested);", "array\n"},
+		{"var v = jsonParse(text); get(v.servers, 0).port = 8080; print jsonStringify(v.servers, false);", `[{"port":8080},{"port":443}]` + "\n"},
+		{"print jsonParse(text);", `{"servers":[{"port":80},{"port":443}],"content-type":"json","empty":[],"nested":[[1,2],[3]]}` + "\n"},
+	}
+	for _, test := range tests {
+		if got := runJSON(t, text, test.source); got != test.want {
+			t.Errorf("%s printed %q, want %q", test.source, got, test.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	for _, text := range []string{
+		`null`, `true`, `12`, `2.5`, `"a<b>"`, `[]`, `{}`,
+		`[1,2.5,"x",null,true,[[]],{"a":[{}]}]`,
+		`{"b":1,"a":{"c":[1,{"d":null}]}}`,
+	} {
+		if got := runJSON(t, text, "print jsonStringify(jsonParse(text), false);"); got != text+"\n" {
+			t.Errorf("%s came back as %q", text, got)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	for _, text := range []string{`[1,`, `[1 2]`, `{"a":1} 2`, `[1]]`} {
+		intr := New()
+		intr.Globals().Define("text", String(text))
+		if err := intr.EvalContext(context.Background(), "jsonParse(text);"); err == nil {
+			t.Errorf("%s parsed, want an error", text)
+		}
+	}
+	intr := New()
+	intr.Globals().Define("text", String(`{"a":{}}`))
+	err := intr.EvalContext(context.Background(), "var v = jsonParse(text); v.a.b = v; jsonStringify(v, false);")
+	if err == nil || !strings.Contains(err.Error(), "an object is in itself") {
+		t.Errorf("writing an object in itself failed with %v", err)
+	}
+}
+
+func TestUnmarshalJSONArray(t *testing.T) {
+	intr := New()
+	intr.Globals().Define("text", String(`{"Ports":[80,443],"Names":[["a"],[]]}`))
+	run(t, intr, "var v = jsonParse(text);")
+	v, err := intr.Globals().Get(token.Token{Type: token.IDENTIFIER, Lexeme: "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		Ports []int
+		Names [][]string
+	}
+	if err := Unmarshal(v, &config); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{80, 443}; !reflect.DeepEqual(config.Ports, want) {
+		t.Errorf("got the ports %v, want %v", config.Ports, want)
+	}
+	if want := [][]string{{"a"}, {}}; !reflect.DeepEqual(config.Names, want) {
+		t.Errorf("got the names %v, want %v", config.Names, want)
+	}
+}
@@ -0,0 +1,237 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+)
+
+// mathBuiltins are the builtins of numbers. Like the operators, they keep integers integers where the
+// result is one, as in floor(2.5) or pow(2, 10), and like division by zero, they report an argument out of
+// their domain, like sqrt(-1), as an error rather than returning NaN or Infinity.
+var mathBuiltins = []*NativeFunction{
+	{
+		Name:    "abs",
+		NumArgs: 1,
+		Func: func(arguments []Value) (Value, error) {
+			if err := numberArguments("abs", arguments); err != nil {
+				return Nil(), err
+			}
+			if n := arguments[0].AsInt(); arguments[0].Kind() == IntKind && n != math.MinInt64 {
+				if n < 0 {
+					n = -n
+				}
+				return Int(n), nil
+			}
+			return Float(math.Abs(arguments[0].AsFloat())), nil
+		},
+	},
+	roundingBuiltin("floor", math.Floor),
+	roundingBuiltin("ceil", math.Ceil),
+	// round rounds half away from zero, so round(2.5) is 3 and round(-2.5) is -3
+	roundingBuiltin("round", math.Round),
+	{
+		Name:    "sqrt",
+		NumArgs: 1,
+		Func: func(arguments []Value) (Value, error) {
+			if err := numberArguments("sqrt", arguments); err != nil {
+				return Nil(), err
+			}
+			x := arguments[0].AsFloat()
+			if x < 0 {
+				return Nil(), fmt.Errorf("The argument of sqrt must not be negative, got %s.", arguments[0])
+			}
+			return Float(math.Sqrt(x)), nil
+		},
+	},
+	{
+		Name:    "pow",
+		NumArgs: 2,
+		Func: func(arguments []Value) (Value, error) {
+			if err := numberArguments("pow", arguments); err != nil {
+				return Nil(), err
+			}
+			base, exponent := arguments[0], arguments[1]
+			if base.Kind() == IntKind && exponent.Kind() == IntKind && exponent.AsInt() >= 0 {
+				if n, ok := powInt(base.AsInt(), exponent.AsInt()); ok {
+					return Int(n), nil
+				}
+			}
+			x, y := base.AsFloat(), exponent.AsFloat()
+			switch {
+			case x == 0 && y < 0:
+				return Nil(), fmt.Errorf("The base of pow must not be 0 with a negative exponent, a division by zero.")
+			case x < 0 && y != math.Trunc(y):
+				return Nil(), fmt.Errorf("The base of pow must not be negative with a fractional exponent.")
+			}
+			return Float(math.Pow(x, y)), nil
+		},
+	},
+	{
+		Name:    "min",
+		NumArgs: 2,
+		Func: func(arguments []Value) (Value, error) {
+			if err := numberArguments("min", arguments); err != nil {
+				return Nil(), err
+			}
+			if less(arguments[1], arguments[0]) {
+				return arguments[1], nil
+			}
+			return arguments[0], nil
+		},
+	},
+	{
+		Name:    "max",
+		NumArgs: 2,
+		Func: func(arguments []Value) (Value, error) {
+			if err := numberArguments("max", arguments); err != nil {
+				return Nil(), err
+			}
+			if less(arguments[0], arguments[1]) {
+				return arguments[1], nil
+			}
+			return arguments[0], nil
+		},
+	},
+	floatBuiltin("sin", math.Sin),
+	floatBuiltin("cos", math.Cos),
+	{
+		Name:    "log",
+		NumArgs: 1,
+		// log is the natural logarithm
+		Func: func(arguments []Value) (Value, error) {
+			if err := numberArguments("log", arguments); err != nil {
+				return Nil(), err
+			}
+			x := arguments[0].AsFloat()
+			if x <= 0 {
+				return Nil(), fmt.Errorf("The argument of log must be positive, got %s.", arguments[0])
+			}
+			return Float(math.Log(x)), nil
+		},
+	},
+	{
+		Name: "pi",
+		Func: func(arguments []Value) (Value, error) {
+			return Float(math.Pi), nil
+		},
+	},
+}
+
+// roundingBuiltin returns the builtin name, which rounds its argument to a whole number with round:
+// an integer, unless it is too large for one.
+func roundingBuiltin(name string, round func(float64) float64) *NativeFunction {
+	return &NativeFunction{
+		Name:    name,
+		NumArgs: 1,
+		Func: func(arguments []Value) (Value, error) {
+			if err := numberArguments(name, arguments); err != nil {
+				return Nil(), err
+			}
+			if arguments[0].Kind() == IntKind {
+				return arguments[0], nil
+			}
+			x := round(arguments[0].AsFloat())
+			if x >= -(1<<63) && x < 1<<63 {
+				return Int(int64(x)), nil
+			}
+			return Float(x), nil
+		},
+	}
+}
+
+// floatBuiltin returns the builtin name, which applies fn to its argument.
+func floatBuiltin(name string, fn func(float64) float64) *NativeFunction {
+	return &NativeFunction{
+		Name:    name,
+		NumArgs: 1,
+		Func: func(arguments []Value) (Value, error) {
+			if err := numberArguments(name, arguments); err != nil {
+				return Nil(), err
+			}
+			x := arguments[0].AsFloat()
+			if math.IsInf(x, 0) {
+				return Nil(), fmt.Errorf("The argument of %s must be finite, got %s.", name, arguments[0])
+			}
+			return Float(fn(x)), nil
+		},
+	}
+}
+
+// numberArguments checks that the arguments of the builtin name are numbers.
+func numberArguments(name string, arguments []Value) error {
+	for i, argument := range arguments {
+		if !argument.IsNumber() {
+			return fmt.Errorf("%s of %s must be a number, got %s.", argumentName(i, len(arguments)), name, typeName(argument))
+		}
+	}
+	return nil
+}
+
+// less reports whether the number a is less than the number b, comparing two integers exactly.
+func less(a, b Value) bool {
+	if a.Kind() == IntKind && b.Kind() == IntKind {
+		return a.AsInt() < b.AsInt()
+	}
+	return a.AsFloat() < b.AsFloat()
+}
+
+// powInt returns base to the power exponent, which is not negative, and false if it overflows an integer.
+func powInt(base, exponent int64) (int64, bool) {
+	result := int64(1)
+	for ; exponent > 0; exponent >>= 1 {
+		if exponent&1 == 1 {
+			r, ok := MulInt(result, base)
+			if !ok {
+				return 0, false
+			}
+			result = r
+		}
+		if exponent > 1 {
+			b, ok := MulInt(base, base)
+			if !ok {
+				return 0, false
+			}
+			base = b
+		}
+	}
+	return result, true
+}
+
+// AddInt returns a plus b, and false if it overflows an integer, when the operators and the VM give a float.
+func AddInt(a, b int64) (int64, bool) {
+	c := a + b
+	if (c > a) != (b > 0) {
+		return 0, false
+	}
+	return c, true
+}
+
+// SubInt returns a minus b, and false if it overflows an integer, like AddInt.
+func SubInt(a, b int64) (int64, bool) {
+	c := a - b
+	if (c < a) != (b > 0) {
+		return 0, false
+	}
+	return c, true
+}
+
+// DivInt returns a divided by b, and false if the quotient is not an integer, or b is 0, or it overflows
+// one, like AddInt.
+func DivInt(a, b int64) (int64, bool) {
+	if b == 0 || a%b != 0 || (a == math.MinInt64 && b == -1) {
+		return 0, false
+	}
+	return a / b, true
+}
+
+// MulInt returns a times b, and false if it overflows an integer, like AddInt.
+func MulInt(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return c, true
+}
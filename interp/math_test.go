@@ -0,0 +1,57 @@
+package interp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckedIntegers(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(a, b int64) (int64, bool)
+		a, b int64
+		want int64
+		ok   bool
+	}{
+		{"AddInt", AddInt, 1, 2, 3, true},
+		{"AddInt", AddInt, math.MaxInt64, 1, 0, false},
+		{"AddInt", AddInt, math.MinInt64, -1, 0, false},
+		{"AddInt", AddInt, math.MinInt64, math.MaxInt64, -1, true},
+		{"SubInt", SubInt, 1, 2, -1, true},
+		{"SubInt", SubInt, math.MinInt64, 1, 0, false},
+		{"SubInt", SubInt, math.MaxInt64, -1, 0, false},
+		{"SubInt", SubInt, -1, math.MaxInt64, math.MinInt64, true},
+		{"MulInt", MulInt, -3, 4, -12, true},
+		{"MulInt", MulInt, 1 << 62, 2, 0, false},
+		{"MulInt", MulInt, math.MinInt64, -1, 0, false},
+		{"MulInt", MulInt, -1 << 62, 2, math.MinInt64, true},
+		{"DivInt", DivInt, 6, 3, 2, true},
+		{"DivInt", DivInt, 7, 2, 0, false},
+		{"DivInt", DivInt, 1, 0, 0, false},
+		{"DivInt", DivInt, math.MinInt64, -1, 0, false},
+	}
+	for _, test := range tests {
+		got, ok := test.op(test.a, test.b)
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("%s(%d, %d) = %d, %v, want %d, %v", test.name, test.a, test.b, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{"print 9223372036854775807 + 1;", "9223372036854776000\n"},
+		{"print -9223372036854775807 - 2;", "-9223372036854776000\n"},
+		{"print 4611686018427387904 * 2;", "9223372036854776000\n"},
+		{"print -(-9223372036854775807 - 1);", "9223372036854776000\n"},
+		{"print (-9223372036854775807 - 1) / -1;", "9223372036854776000\n"},
+		{"print 9223372036854775807 + 0;", "9223372036854775807\n"},
+	}
+	for _, test := range tests {
+		if got := run(t, New(), test.source); got != test.want {
+			t.Errorf("%s printed %q, want %q", test.source, got, test.want)
+		}
+	}
+}
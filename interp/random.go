@@ -0,0 +1,92 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// randomBuiltins are the builtins of random numbers. Each interpreter has its generator, so that the
+// interpreters of a host draw their numbers apart, and seed makes what a script draws the same from one
+// run to the next.
+var randomBuiltins = []*NativeFunction{
+	{
+		Name: "random",
+		// random() is a float from 0, included, to 1, not included
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			return Float(intr.random.float()), nil
+		},
+	},
+	{
+		Name:    "randomInt",
+		NumArgs: 2,
+		// randomInt(lo, hi) is an integer from lo to hi, both included
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			lo, err := integerArgument("randomInt", arguments, 0)
+			if err != nil {
+				return Nil(), err
+			}
+			hi, err := integerArgument("randomInt", arguments, 1)
+			if err != nil {
+				return Nil(), err
+			}
+			if lo > hi {
+				return Nil(), fmt.Errorf("The bounds of randomInt must be in order, got %d and %d.", lo, hi)
+			}
+			return Int(intr.random.between(lo, hi)), nil
+		},
+	},
+	{
+		Name:    "seed",
+		NumArgs: 1,
+		// seed(n) starts the generator again from the integer n
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			n, err := integerArgument("seed", arguments, 0)
+			if err != nil {
+				return Nil(), err
+			}
+			intr.random.seed(n)
+			return Nil(), nil
+		},
+	},
+}
+
+// randomSource is the generator of an interpreter, seeded from the time until seed is called. The tasks of
+// spawn share it, so it is locked.
+type randomSource struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func newRandomSource() *randomSource {
+	return &randomSource{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (r *randomSource) seed(n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rand.Seed(n)
+}
+
+func (r *randomSource) float() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rand.Float64()
+}
+
+// between returns an integer from lo to hi, both included, which are in order.
+func (r *randomSource) between(lo, hi int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if span := uint64(hi) - uint64(lo); span < math.MaxInt64 {
+		return lo + r.rand.Int63n(int64(span)+1)
+	}
+	// more than half of the integers: draw from all of them until one is in range
+	for {
+		if n := int64(r.rand.Uint64()); n >= lo && n <= hi {
+			return n
+		}
+	}
+}
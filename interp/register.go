@@ -0,0 +1,180 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var (
+	valueType = reflect.TypeOf(Value{})
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Register defines name in the globals as a native function that calls fn, a Go function, converting
+// its arguments from Lox values and its result back:
+//   - the integer and float types take numbers, an integer type only those without a fraction
+//     that it can hold, and give numbers
+//   - string and bool take and give strings and booleans
+//   - Value takes and gives any value unchanged, and interface{} takes any value as Value.Go gives it
+//   - a pointer to a struct and a map with string keys give an instance, as Bind makes them, and take
+//     an instance holding a value assignable to them
+//   - any other type, like a slice, takes an object holding a value assignable to it, and gives an object;
+//     Lox has no lists yet
+//   - pointers, slices, maps and the like give nil for a nil value, and take it
+//
+// fn can return nothing, one value, an error, or one value and an error, which becomes a runtime error.
+// Its arity is its number of parameters; variadic functions cannot be registered.
+func (intr Interpreter) Register(name string, fn interface{}) error {
+	if intr.globals == nil {
+		return errors.New("Register needs an interpreter made by New")
+	}
+	native, err := nativeFunction(name, fn)
+	if err != nil {
+		return err
+	}
+	intr.globals.Define(name, Object(native))
+	return nil
+}
+
+// nativeFunction adapts fn for Register.
+func nativeFunction(name string, fn interface{}) (*NativeFunction, error) {
+	f := reflect.ValueOf(fn)
+	t := f.Type()
+	if t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("cannot register %s: %T is not a function", name, fn)
+	}
+	if t.IsVariadic() {
+		return nil, fmt.Errorf("cannot register %s: variadic functions are not supported", name)
+	}
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	results := t.NumOut()
+	if returnsError {
+		results--
+	}
+	if results > 1 {
+		return nil, fmt.Errorf("cannot register %s: it returns more than a value and an error", name)
+	}
+	return &NativeFunction{
+		Name:    name,
+		NumArgs: t.NumIn(),
+		Func: func(arguments []Value) (Value, error) {
+			in := make([]reflect.Value, len(arguments))
+			for i, argument := range arguments {
+				var err error
+				if in[i], err = toGo(argument, t.In(i)); err != nil {
+					return Nil(), fmt.Errorf("Argument %d of %s %s.", i+1, name, err)
+				}
+			}
+			out := f.Call(in)
+			if returnsError {
+				if err, _ := out[len(out)-1].Interface().(error); err != nil {
+					return Nil(), err
+				}
+			}
+			if results == 0 {
+				return Nil(), nil
+			}
+			return fromReflect(out[0]), nil
+		},
+	}, nil
+}
+
+// toGo converts a Lox value to a Go value of type t. Its error completes the sentence "Argument 1 of f".
+func toGo(v Value, t reflect.Type) (reflect.Value, error) {
+	if t == valueType {
+		return reflect.ValueOf(v), nil
+	}
+	mismatch := func(want string) error {
+		return fmt.Errorf("must be %s, got %s", want, typeName(v))
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if !v.IsNumber() {
+			return reflect.Value{}, mismatch("a number")
+		}
+		x := reflect.New(t).Elem()
+		n := v.AsInt()
+		if v.Kind() == FloatKind {
+			f := v.AsFloat()
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return reflect.Value{}, mismatch("an integer")
+			}
+			n = int64(f)
+		}
+		if x.CanInt() {
+			if x.OverflowInt(n) {
+				return reflect.Value{}, fmt.Errorf("is out of the range of %s", t)
+			}
+			x.SetInt(n)
+		} else {
+			if n < 0 || x.OverflowUint(uint64(n)) {
+				return reflect.Value{}, fmt.Errorf("is out of the range of %s", t)
+			}
+			x.SetUint(uint64(n))
+		}
+		return x, nil
+	case reflect.Float32, reflect.Float64:
+		if !v.IsNumber() {
+			return reflect.Value{}, mismatch("a number")
+		}
+		return reflect.ValueOf(v.AsFloat()).Convert(t), nil
+	case reflect.String:
+		if v.Kind() != StringKind {
+			return reflect.Value{}, mismatch("a string")
+		}
+		return reflect.ValueOf(v.AsString()).Convert(t), nil
+	case reflect.Bool:
+		if v.Kind() != BoolKind {
+			return reflect.Value{}, mismatch("a boolean")
+		}
+		return reflect.ValueOf(v.AsBool()).Convert(t), nil
+	}
+	x := v.Go()
+	if x == nil {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, mismatch(t.String())
+	}
+	if reflect.TypeOf(x).AssignableTo(t) {
+		return reflect.ValueOf(x), nil
+	}
+	return reflect.Value{}, mismatch(t.String())
+}
+
+// fromReflect converts a result of a Go function to a Lox value.
+func fromReflect(x reflect.Value) Value {
+	switch x.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Int(x.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := x.Uint(); u <= math.MaxInt64 {
+			return Int(int64(u))
+		}
+		return Float(float64(x.Uint()))
+	case reflect.Float32, reflect.Float64:
+		return Float(x.Float())
+	case reflect.String:
+		return String(x.String())
+	case reflect.Bool:
+		return Bool(x.Bool())
+	case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+		if x.IsNil() {
+			return Nil()
+		}
+	}
+	if x.Type() == valueType {
+		return x.Interface().(Value)
+	}
+	if x.Kind() == reflect.Interface {
+		return fromReflect(x.Elem())
+	}
+	if bound, err := Bind(x.Interface()); err == nil {
+		return bound
+	}
+	return Object(x.Interface())
+}
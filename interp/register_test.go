@@ -0,0 +1,41 @@
+package interp
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+// run runs source in intr and returns what it printed.
+func run(t *testing.T, intr Interpreter, source string) string {
+	t.Helper()
+	var out bytes.Buffer
+	intr.Stdout = &out
+	if err := intr.EvalContext(context.Background(), source); err != nil {
+		t.Fatalf("running %q: %v", source, err)
+	}
+	return out.String()
+}
+
+func TestEqualUncomparableObjects(t *testing.T) {
+	type withSlice struct{ S []int }
+	intr := New()
+	if err := intr.Register("slice", func() []int { return []int{1} }); err != nil {
+		t.Fatal(err)
+	}
+	if err := intr.Register("object", func() interface{} { return Object(withSlice{}) }); err != nil {
+		t.Fatal(err)
+	}
+	got := run(t, intr, "var s = slice(); print s == s; print s != s; var o = object(); print o == o;")
+	if want := "false\ntrue\nfalse\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEqualComparableObjects(t *testing.T) {
+	intr := New()
+	got := run(t, intr, "print clock == clock; print clock == bench; var c = chan(); print c == c;")
+	if want := "true\nfalse\ntrue\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// stringBuiltins are the builtins of strings. They count characters, the Unicode code points of the UTF-8
+// of a string, rather than bytes, so that len("né") is 2, and their indexes are those of characters.
+// The strings they make count toward MaxMemory, like those of concatenation.
+var stringBuiltins = []*NativeFunction{
+	{
+		Name:    "len",
+		NumArgs: 1,
+		// len(s) is the number of characters of s, and len(array) the number of elements of array
+		Func: func(arguments []Value) (Value, error) {
+			if array, ok := arguments[0].AsObject().(*jsonArray); ok {
+				return Int(int64(len(array.elements))), nil
+			}
+			if arguments[0].Kind() != StringKind {
+				return Nil(), fmt.Errorf("The argument of len must be a string or an array, got %s.", typeName(arguments[0]))
+			}
+			return Int(int64(utf8.RuneCountInString(arguments[0].AsString()))), nil
+		},
+	},
+	{
+		Name:    "substr",
+		NumArgs: 3,
+		// substr(s, start, end) is the characters of s from start up to end, which is not included
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			if err := stringArguments("substr", arguments, 1); err != nil {
+				return Nil(), err
+			}
+			characters := []rune(arguments[0].AsString())
+			start, err := indexArgument("substr", arguments, 1, len(characters))
+			if err != nil {
+				return Nil(), err
+			}
+			end, err := indexArgument("substr", arguments, 2, len(characters))
+			if err != nil {
+				return Nil(), err
+			}
+			if end < start {
+				return Nil(), fmt.Errorf("The end of substr must not be before its start, got %d and %d.", start, end)
+			}
+			return newBuiltinString(intr, string(characters[start:end]))
+		},
+	},
+	{
+		Name:    "indexOf",
+		NumArgs: 2,
+		// indexOf(s, sub) is the index of the first sub in s, or -1
+		Func: func(arguments []Value) (Value, error) {
+			if err := stringArguments("indexOf", arguments, 2); err != nil {
+				return Nil(), err
+			}
+			s := arguments[0].AsString()
+			i := strings.Index(s, arguments[1].AsString())
+			if i < 0 {
+				return Int(-1), nil
+			}
+			return Int(int64(utf8.RuneCountInString(s[:i]))), nil
+		},
+	},
+	{
+		Name:    "replace",
+		NumArgs: 3,
+		// replace(s, old, new) replaces every old in s by new
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			if err := stringArguments("replace", arguments, 3); err != nil {
+				return Nil(), err
+			}
+			s, old, replacement := arguments[0].AsString(), arguments[1].AsString(), arguments[2].AsString()
+			if old == "" {
+				return Nil(), fmt.Errorf("The second argument of replace must not be empty.")
+			}
+			// the result is counted before it is made, as it can be much larger than s
+			if err := intr.allocate(len(s) + strings.Count(s, old)*(len(replacement)-len(old))); err != nil {
+				return Nil(), err
+			}
+			return String(strings.ReplaceAll(s, old, replacement)), nil
+		},
+	},
+	stringBuiltin("trim", strings.TrimSpace),
+	stringBuiltin("upper", strings.ToUpper),
+	stringBuiltin("lower", strings.ToLower),
+	{
+		Name:    "charCode",
+		NumArgs: 2,
+		// charCode(s, i) is the code point of the character i of s
+		Func: func(arguments []Value) (Value, error) {
+			if err := stringArguments("charCode", arguments, 1); err != nil {
+				return Nil(), err
+			}
+			characters := []rune(arguments[0].AsString())
+			if len(characters) == 0 {
+				return Nil(), fmt.Errorf("The first argument of charCode must not be empty.")
+			}
+			i, err := indexArgument("charCode", arguments, 1, len(characters)-1)
+			if err != nil {
+				return Nil(), err
+			}
+			return Int(int64(characters[i])), nil
+		},
+	},
+	{
+		Name:    "num",
+		NumArgs: 1,
+		// num(s) is the number that s writes as a Lox literal, like "42", "-2.5e3" or "0xFF", with spaces
+		// around it, or nil if s is not a number, so that a script can check the input it reads
+		Func: func(arguments []Value) (Value, error) {
+			if err := stringArguments("num", arguments, 1); err != nil {
+				return Nil(), err
+			}
+			return parseNumber(arguments[0].AsString()), nil
+		},
+	},
+	{
+		Name:    "str",
+		NumArgs: 1,
+		// str(value) is the string of value, as print writes it
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			if arguments[0].Kind() == StringKind {
+				return arguments[0], nil
+			}
+			return newBuiltinString(intr, stringify(arguments[0]))
+		},
+	},
+}
+
+// stringBuiltin returns the builtin name, which applies fn to its argument.
+func stringBuiltin(name string, fn func(string) string) *NativeFunction {
+	return &NativeFunction{
+		Name:    name,
+		NumArgs: 1,
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			if err := stringArguments(name, arguments, 1); err != nil {
+				return Nil(), err
+			}
+			return newBuiltinString(intr, fn(arguments[0].AsString()))
+		},
+	}
+}
+
+// newBuiltinString returns the value of s, a string a builtin made, counting its bytes.
+func newBuiltinString(intr Interpreter, s string) (Value, error) {
+	if err := intr.allocate(len(s)); err != nil {
+		return Nil(), err
+	}
+	return String(s), nil
+}
+
+// parseNumber returns the number of the literal s, which may have a minus sign and spaces around it, or nil.
+func parseNumber(s string) Value {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	if negative {
+		s = s[1:]
+	}
+	sc := scanner.New(s)
+	tokens, errs := sc.ScanTokens()
+	// the literal must be all of s, without the comments or the spaces the scanner skips
+	if len(errs) > 0 || len(tokens) != 2 || tokens[0].Type != token.NUMBER || tokens[0].Lexeme != s {
+		return Nil()
+	}
+	n := FromGo(tokens[0].Literal)
+	if negative {
+		if n.Kind() == IntKind {
+			return Int(-n.AsInt())
+		}
+		return Float(-n.AsFloat())
+	}
+	return n
+}
+
+// stringArguments checks that the first count arguments of the builtin name are strings.
+func stringArguments(name string, arguments []Value, count int) error {
+	for i, argument := range arguments[:count] {
+		if argument.Kind() != StringKind {
+			return fmt.Errorf("%s of %s must be a string, got %s.", argumentName(i, len(arguments)), name, typeName(argument))
+		}
+	}
+	return nil
+}
+
+// indexArgument returns the argument i of the builtin name, which must be an integer from 0 to last.
+func indexArgument(name string, arguments []Value, i, last int) (int, error) {
+	index, err := integerArgument(name, arguments, i)
+	if err != nil {
+		return 0, err
+	}
+	if index < 0 || index > int64(last) {
+		return 0, fmt.Errorf("%s of %s must be from 0 to %d, got %d.", argumentName(i, len(arguments)), name, last, index)
+	}
+	return int(index), nil
+}
@@ -0,0 +1,43 @@
+package interp
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStringBuiltinsAllocate(t *testing.T) {
+	tests := []string{
+		`var s = "ab"; s = replace(s, "a", s); s = replace(s, "a", s); s = replace(s, "a", s);`,
+		`var s = "abcdefgh"; s = upper(s); s = lower(s); s = trim(s);`,
+		`var s = substr("abcdefgh", 0, 8) + "";`,
+		`var s = str(123456789);`,
+		`var s = formatTime(0, "2006-01-02 15:04:05");`,
+	}
+	for _, source := range tests {
+		intr := New()
+		intr.MaxMemory = 8
+		intr.Stdout = &bytes.Buffer{}
+		err := intr.EvalContext(context.Background(), source)
+		if err == nil || !strings.Contains(err.Error(), "Memory limit of 8 bytes exceeded.") {
+			t.Errorf("%s: got %v, want the memory limit", source, err)
+		}
+	}
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{`print replace("a-b-c", "-", "+");`, "a+b+c\n"},
+		{`print replace("aaa", "a", "");`, "\n"},
+		{`print substr("héllo", 1, 3);`, "él\n"},
+		{`print upper("né") + lower("NÉ") + trim("  x  ");`, "NÉnéx\n"},
+	}
+	for _, test := range tests {
+		if got := run(t, New(), test.source); got != test.want {
+			t.Errorf("%s printed %q, want %q", test.source, got, test.want)
+		}
+	}
+}
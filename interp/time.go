@@ -0,0 +1,66 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// timeBuiltins are the builtins of time, besides clock. They give times as milliseconds since the epoch.
+var timeBuiltins = []*NativeFunction{
+	{
+		Name: "now",
+		// now() is the integer milliseconds since the epoch
+		Func: func(arguments []Value) (Value, error) {
+			return Int(time.Now().UnixNano() / int64(time.Millisecond)), nil
+		},
+	},
+	{
+		Name:    "sleep",
+		NumArgs: 1,
+		// sleep(ms) waits for ms milliseconds, or until the context of the run is done, when the run stops
+		// with its error
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			if err := numberArguments("sleep", arguments); err != nil {
+				return Nil(), err
+			}
+			ms := arguments[0].AsFloat()
+			if ms < 0 {
+				return Nil(), fmt.Errorf("The argument of sleep must not be negative, got %s.", arguments[0])
+			}
+			duration := time.Duration(math.MaxInt64)
+			if ms < float64(duration/time.Millisecond) {
+				duration = time.Duration(ms * float64(time.Millisecond))
+			}
+			timer := time.NewTimer(duration)
+			defer timer.Stop()
+			if intr.ctx == nil {
+				<-timer.C
+				return Nil(), nil
+			}
+			select {
+			case <-timer.C:
+				return Nil(), nil
+			case <-intr.ctx.Done():
+				return Nil(), intr.ctx.Err()
+			}
+		},
+	},
+	{
+		Name:    "formatTime",
+		NumArgs: 2,
+		// formatTime(ms, layout) is the time ms milliseconds after the epoch, in UTC, written in the layout of
+		// Go's time package, which is how the time 2006-01-02 15:04:05 would be written, like "Jan 2 15:04"
+		call: func(intr Interpreter, arguments []Value) (Value, error) {
+			ms, err := integerArgument("formatTime", arguments, 0)
+			if err != nil {
+				return Nil(), err
+			}
+			if arguments[1].Kind() != StringKind {
+				return Nil(), fmt.Errorf("The second argument of formatTime must be a layout string, got %s.", typeName(arguments[1]))
+			}
+			t := time.Unix(ms/1000, ms%1000*int64(time.Millisecond)).UTC()
+			return newBuiltinString(intr, t.Format(arguments[1].AsString()))
+		},
+	},
+}
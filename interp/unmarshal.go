@@ -0,0 +1,68 @@
+package interp
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ValueOf returns the Lox value of a Go value, converted like the results of Register: numbers, strings
+// and booleans become Lox ones, pointers to structs and maps with string keys instances, and nil nil.
+func ValueOf(x interface{}) Value {
+	if x == nil {
+		return Nil()
+	}
+	return fromReflect(reflect.ValueOf(x))
+}
+
+// Unmarshal stores v in the Go value target points to, converted like the arguments of Register,
+// so that a host can get the results of a script without switching on their kinds.
+// An instance is also stored in a struct of another type than its own, field by field: each field gets
+// the property of its name, as the lox tag of Bind gives it, and keeps its value if the instance does not
+// have it, and an array of jsonParse is stored in a slice, element by element.
+func Unmarshal(v Value, target interface{}) error {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Ptr || t.IsNil() {
+		return fmt.Errorf("cannot unmarshal into %T: it is not a non-nil pointer", target)
+	}
+	return unmarshal(v, t.Elem(), t.Elem().Type().String())
+}
+
+// unmarshal stores v in dst, which path names in errors.
+func unmarshal(v Value, dst reflect.Value, path string) error {
+	if instance, ok := v.AsObject().(Instance); ok && dst.Kind() == reflect.Struct {
+		if bound, ok := instance.(*boundObject); ok && bound.v.Kind() == reflect.Ptr && bound.v.Type().Elem() == dst.Type() {
+			dst.Set(bound.v.Elem())
+			return nil
+		}
+		for name, field := range fields(dst.Type()) {
+			value, err := instance.Get(name)
+			if err != nil {
+				continue
+			}
+			target, err := dst.FieldByIndexErr(field.index)
+			if err != nil {
+				continue
+			}
+			if err := unmarshal(value, target, path+"."+name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if array, ok := v.AsObject().(*jsonArray); ok && dst.Kind() == reflect.Slice {
+		elements := reflect.MakeSlice(dst.Type(), len(array.elements), len(array.elements))
+		for i, element := range array.elements {
+			if err := unmarshal(element, elements.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(elements)
+		return nil
+	}
+	x, err := toGo(v, dst.Type())
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal into %s: the value %v", path, err)
+	}
+	dst.Set(x)
+	return nil
+}
@@ -0,0 +1,40 @@
+package interp
+
+import "testing"
+
+type point struct {
+	X, Y int
+}
+
+func TestUnmarshalBoundStruct(t *testing.T) {
+	v, err := Bind(&point{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p point
+	if err := Unmarshal(v, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p != (point{1, 2}) {
+		t.Errorf("got %+v, want {X:1 Y:2}", p)
+	}
+}
+
+func TestUnmarshalBoundMapIntoStruct(t *testing.T) {
+	// a map whose values have the type of the target is stored field by field, by its keys
+	v, err := Bind(map[string]point{"X": {3, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p struct{ X point }
+	if err := Unmarshal(v, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.X != (point{3, 4}) {
+		t.Errorf("got %+v, want {X:{X:3 Y:4}}", p)
+	}
+	var q point
+	if err := Unmarshal(v, &q); err == nil {
+		t.Errorf("unmarshalled %v into %+v, want an error", v, q)
+	}
+}
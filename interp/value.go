@@ -0,0 +1,237 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+)
+
+// Kind is the type of a Value.
+type Kind uint8
+
+const (
+	NilKind Kind = iota
+	BoolKind
+	IntKind
+	FloatKind
+	StringKind
+	// ObjectKind is any other value, like a function.
+	ObjectKind
+)
+
+// Value is a Lox value. Numbers and booleans are kept in the value itself rather than boxed in an interface,
+// so that arithmetic does not allocate. The zero Value is nil.
+//
+// A Value is 16 bytes, two words, as the VM copies one for most instructions. ptr is nil for nil, the tag
+// of the kind of a boolean or a number, whose payload is in bits, the bytes of a string, whose length is
+// in bits, or a box holding an object, with objectBits in bits. A string is not copied, and the garbage
+// collector keeps its bytes alive through ptr. Only an object allocates, once, for its box.
+type Value struct {
+	ptr unsafe.Pointer
+	// bits is a boolean as 0 or 1, an int64, the bits of a float64, the length of a string, or objectBits
+	bits uint64
+}
+
+// kindTags are the ptr of the kinds whose payload is in bits, and of the empty string, which has no bytes.
+// ptr is compared with their addresses, which no string or box can have.
+var kindTags [ObjectKind]byte
+
+var (
+	boolTag        = unsafe.Pointer(&kindTags[BoolKind])
+	intTag         = unsafe.Pointer(&kindTags[IntKind])
+	floatTag       = unsafe.Pointer(&kindTags[FloatKind])
+	emptyStringTag = unsafe.Pointer(&kindTags[StringKind])
+)
+
+// objectBits are the bits of an object, which no string is long enough to have.
+const objectBits = math.MaxUint64
+
+// stringHeader is the layout of a Go string.
+type stringHeader struct {
+	data unsafe.Pointer
+	len  int
+}
+
+// Nil returns the nil value.
+func Nil() Value {
+	return Value{}
+}
+
+// Bool returns a boolean value.
+func Bool(b bool) Value {
+	v := Value{ptr: boolTag}
+	if b {
+		v.bits = 1
+	}
+	return v
+}
+
+// Int returns an integer number.
+func Int(n int64) Value {
+	return Value{ptr: intTag, bits: uint64(n)}
+}
+
+// Float returns a floating point number. Its bits are kept as they are, so NaN payloads and -0 survive.
+func Float(f float64) Value {
+	return Value{ptr: floatTag, bits: math.Float64bits(f)}
+}
+
+// String returns a string value.
+func String(s string) Value {
+	if len(s) == 0 {
+		return Value{ptr: emptyStringTag}
+	}
+	return Value{ptr: (*stringHeader)(unsafe.Pointer(&s)).data, bits: uint64(len(s))}
+}
+
+// Object returns a value for obj, like a Callable. obj should not be a Go nil or string, which are the
+// Lox nil and string values: FromGo converts those.
+func Object(obj interface{}) Value {
+	switch obj := obj.(type) {
+	case nil:
+		return Nil()
+	case string:
+		return String(obj)
+	}
+	box := new(interface{})
+	*box = obj
+	return Value{ptr: unsafe.Pointer(box), bits: objectBits}
+}
+
+// FromGo returns the value of a Go nil, bool, int64, float64 or string, as the scanner gives literals,
+// or an object for anything else.
+func FromGo(x interface{}) Value {
+	switch x := x.(type) {
+	case nil:
+		return Nil()
+	case bool:
+		return Bool(x)
+	case int64:
+		return Int(x)
+	case float64:
+		return Float(x)
+	case string:
+		return String(x)
+	case Value:
+		return x
+	}
+	return Object(x)
+}
+
+func (v Value) Kind() Kind {
+	switch v.ptr {
+	case nil:
+		return NilKind
+	case boolTag:
+		return BoolKind
+	case intTag:
+		return IntKind
+	case floatTag:
+		return FloatKind
+	}
+	if v.bits == objectBits {
+		return ObjectKind
+	}
+	return StringKind
+}
+
+func (v Value) IsNil() bool {
+	return v.ptr == nil
+}
+
+// IsNumber reports whether v is an integer or a float.
+func (v Value) IsNumber() bool {
+	return v.ptr == intTag || v.ptr == floatTag
+}
+
+// Truthy reports whether v counts as true in a condition: anything but nil and false.
+func (v Value) Truthy() bool {
+	return v.ptr != nil && !(v.ptr == boolTag && v.bits == 0)
+}
+
+// AsBool returns the boolean of v, false if v is not one.
+func (v Value) AsBool() bool {
+	return v.ptr == boolTag && v.bits == 1
+}
+
+// AsInt returns the integer of v, 0 if v is not one.
+func (v Value) AsInt() int64 {
+	if v.ptr != intTag {
+		return 0
+	}
+	return int64(v.bits)
+}
+
+// AsFloat returns v converted to a float64 if it is a number, 0 otherwise.
+func (v Value) AsFloat() float64 {
+	switch v.ptr {
+	case intTag:
+		return float64(int64(v.bits))
+	case floatTag:
+		return math.Float64frombits(v.bits)
+	}
+	return 0
+}
+
+// AsString returns the string of v, "" if v is not one.
+func (v Value) AsString() string {
+	if v.Kind() != StringKind || v.ptr == emptyStringTag {
+		return ""
+	}
+	var s string
+	*(*stringHeader)(unsafe.Pointer(&s)) = stringHeader{data: v.ptr, len: int(v.bits)}
+	return s
+}
+
+// AsObject returns the object of v, nil if v is not one.
+func (v Value) AsObject() interface{} {
+	if v.Kind() != ObjectKind {
+		return nil
+	}
+	return *(*interface{})(v.ptr)
+}
+
+// Go returns v as a Go nil, bool, int64, float64, string or object, which is the Go value itself for
+// an instance made by Bind.
+func (v Value) Go() interface{} {
+	switch v.Kind() {
+	case BoolKind:
+		return v.AsBool()
+	case IntKind:
+		return v.AsInt()
+	case FloatKind:
+		return v.AsFloat()
+	case StringKind:
+		return v.AsString()
+	case ObjectKind:
+		obj := v.AsObject()
+		if bound, ok := obj.(*boundObject); ok {
+			return bound.v.Interface()
+		}
+		return obj
+	}
+	return nil
+}
+
+// String formats v the way the reference Lox implementation prints it.
+func (v Value) String() string {
+	return stringify(v)
+}
+
+func (k Kind) String() string {
+	switch k {
+	case NilKind:
+		return "nil"
+	case BoolKind:
+		return "bool"
+	case IntKind:
+		return "int"
+	case FloatKind:
+		return "float"
+	case StringKind:
+		return "string"
+	case ObjectKind:
+		return "object"
+	}
+	return fmt.Sprintf("Kind(%d)", k)
+}
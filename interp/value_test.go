@@ -0,0 +1,91 @@
+package interp
+
+import (
+	"math"
+	"runtime"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestValueSize(t *testing.T) {
+	if size := unsafe.Sizeof(Value{}); size > 16 {
+		t.Errorf("a Value takes %d bytes, want at most 16", size)
+	}
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	type pair struct{ a, b int }
+	bits := []uint64{
+		math.Float64bits(math.NaN()), 0x7ff8000000000001, 0xfff0000000000001,
+		math.Float64bits(math.Inf(1)), math.Float64bits(math.Inf(-1)),
+		math.Float64bits(math.Copysign(0, -1)), 1, math.Float64bits(math.MaxFloat64),
+	}
+	for _, b := range bits {
+		v := FromGo(math.Float64frombits(b))
+		if v.Kind() != FloatKind || math.Float64bits(v.AsFloat()) != b || math.Float64bits(v.Go().(float64)) != b {
+			t.Errorf("the float of bits %#x came back as %v, %#x", b, v.Kind(), math.Float64bits(v.AsFloat()))
+		}
+	}
+	for _, n := range []int64{0, -1, math.MaxInt64, math.MinInt64} {
+		if v := Int(n); v.Kind() != IntKind || v.AsInt() != n || v.Go() != n || v.AsString() != "" || v.AsObject() != nil {
+			t.Errorf("the integer %d came back as %v, %d", n, v.Kind(), v.AsInt())
+		}
+	}
+	for _, s := range []string{"", "a", strings.Repeat("lox", 100)[7:31]} {
+		if v := String(s); v.Kind() != StringKind || v.AsString() != s || v.Go() != s || v.Truthy() != true {
+			t.Errorf("the string %q came back as %v, %q", s, v.Kind(), v.AsString())
+		}
+	}
+	if v := Object(pair{1, 2}); v.Kind() != ObjectKind || v.AsObject() != (pair{1, 2}) || v.AsString() != "" || v.AsInt() != 0 {
+		t.Errorf("the object came back as %v, %v", v.Kind(), v.AsObject())
+	}
+	if v := Object("s"); v.Kind() != StringKind || v.AsString() != "s" {
+		t.Errorf("the object of a string is %v, %v", v.Kind(), v.AsObject())
+	}
+	if v := (Value{}); v.Kind() != NilKind || !v.IsNil() || v.Truthy() || Object(nil).Kind() != NilKind {
+		t.Errorf("the zero Value is %v", v.Kind())
+	}
+	if Bool(false).Truthy() || !Bool(true).AsBool() || Int(0).AsBool() || !Int(0).Truthy() {
+		t.Errorf("the booleans are wrong")
+	}
+}
+
+// TestValueKeepsString checks that the garbage collector keeps the bytes of a string held only by a Value.
+func TestValueKeepsString(t *testing.T) {
+	values := make([]Value, 100)
+	for i := range values {
+		values[i] = String(strings.Repeat(string(rune('a'+i%26)), 1000))
+	}
+	for i := 0; i < 3; i++ {
+		runtime.GC()
+		_ = strings.Repeat("x", 1<<20)
+	}
+	for i, v := range values {
+		if want := strings.Repeat(string(rune('a'+i%26)), 1000); v.AsString() != want {
+			t.Fatalf("string %d changed after a collection", i)
+		}
+	}
+}
+
+func TestFloatEdges(t *testing.T) {
+	intr := New()
+	intr.Globals().Define("nan", Float(math.NaN()))
+	intr.Globals().Define("inf", Float(math.Inf(1)))
+	intr.Globals().Define("negativeZero", Float(math.Copysign(0, -1)))
+	tests := []struct {
+		source, want string
+	}{
+		{"print -0.0 == 0; print negativeZero == 0; print negativeZero == 0.0;", "true\ntrue\ntrue\n"},
+		{"print nan != nan; print nan == nan; var n = nan; print n == n;", "true\nfalse\nfalse\n"},
+		{"print nan < 1; print nan >= 1;", "false\nfalse\n"},
+		{"print nan; print inf; print -inf; print inf + 1; print inf - inf;", "NaN\nInfinity\n-Infinity\nInfinity\nNaN\n"},
+		{"print inf == inf; print -inf < 9223372036854775807;", "true\ntrue\n"},
+		{"print 2.5 * 2; print 1.5;", "5\n1.5\n"},
+	}
+	for _, test := range tests {
+		if got := run(t, intr, test.source); got != test.want {
+			t.Errorf("%s printed %q, want %q", test.source, got, test.want)
+		}
+	}
+}
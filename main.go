@@ -2,52 +2,356 @@ package main
 
 import (
 	"bufio"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/compiled"
+	"github.com/gadumitrachioaiei/go-lox/diag"
+	"github.com/gadumitrachioaiei/go-lox/intern"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
 	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+	"github.com/gadumitrachioaiei/go-lox/vm"
 )
 
+var (
+	coerceStrings  = flag.Bool("coerce-strings", false, "make + between a string and another value concatenate their string forms")
+	astFormat      = flag.String("ast", "", "print the syntax tree in this format instead of running the code: json")
+	checkOnly      = flag.Bool("check", false, "only scan and parse the file, or stdin without a file, and report all the errors")
+	maxSteps       = flag.Int("max-steps", 0, "stop the code with an error after this many evaluation steps, or VM instructions; 0 for no limit")
+	maxMemory      = flag.Int("max-memory", 0, "stop the code with an error once the values it creates take about this many bytes; 0 for no limit")
+	backendName    = flag.String("backend", "tree", "how to run the code: tree, walking the syntax tree, or vm, compiling it to bytecode")
+	coverageFormat = flag.String("coverage", "", "report on stderr at exit which lines of the file ran, in this format: text, an annotated listing, or lcov; only with the tree backend")
+	countOps       = flag.Bool("count-ops", false, "report on stderr at exit how many nodes the tree backend evaluated, or instructions the vm ran")
+	profile        = flag.Bool("profile", false, "report on stderr at exit the calls and the time of each function, and of each line with the tree backend")
+	allowFiles     = flag.Bool("allow-files", false, "let the code read and write files, with readFile, writeFile and appendFile")
+	trace          = flag.Bool("trace", false, "print on stderr each instruction the vm runs, with its frame and the stack")
+	buildOutput    = flag.String("o", "", "with build, the executable to write, or Go source if it ends with .go, and with compile the .loxc file; named after the script by default")
+)
+
+// backend runs code: the tree-walking interpreter or the bytecode VM.
+type backend interface {
+	Interpret(statements []ast.Stmt) error
+	Evaluate(expr ast.Expr) (interp.Value, error)
+}
+
+// stdout gets the output of the code and the values of the REPL, stderr the diagnostics.
+// They are variables so that the CLI can run with other writers.
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// exitSyntaxError is the exit status of a script, or of -check, when the code has scan or syntax errors,
+// as in the reference implementation.
+const exitSyntaxError = 65
+
+// exitRuntimeError is the exit status of a script stopped by a runtime error, as in the reference implementation.
+const exitRuntimeError = 70
+
+// exit is the exit of the code, which ends the CLI with its status, nil until the code calls exit.
+var exit *interp.ExitError
+
 func main() {
-	if args := os.Args; len(args) > 2 {
-		log.Fatal("We need at most one argument, that must be a file path")
-	} else if len(args) == 2 {
-		runFile(args[1])
+	// registered first, so that it runs after the reports of the other deferred calls
+	defer func() {
+		if exit != nil {
+			os.Exit(exit.Code)
+		}
+	}()
+	flag.Parse()
+	if flag.Arg(0) == "run" {
+		// compiled chunks only run on the VM
+		*backendName = "vm"
+	}
+	if *astFormat != "" && *astFormat != "json" {
+		log.Fatalf("unknown syntax tree format %q", *astFormat)
+	}
+	intr := interp.New()
+	intr.CoerceStrings = *coerceStrings
+	intr.MaxSteps = *maxSteps
+	intr.MaxMemory = *maxMemory
+	intr.AllowFiles = *allowFiles
+	if args := flag.Args(); len(args) > 1 {
+		switch args[0] {
+		case "debug", "dap", "build", "compile", "disasm", "run":
+			// the commands take a file, and reject the words after it
+		default:
+			// the words after a script are its arguments
+			intr.Args = args[1:]
+		}
+	}
+	intr.Stdout, intr.Stderr = stdout, stderr
+	if *coverageFormat != "" {
+		switch {
+		case *coverageFormat != "text" && *coverageFormat != "lcov":
+			log.Fatalf("unknown coverage format %q", *coverageFormat)
+		case *backendName != "tree":
+			log.Fatal("-coverage only works with the tree backend")
+		case *profile:
+			log.Fatal("-coverage and -profile cannot be used together")
+		case flag.NArg() != 1 || *checkOnly:
+			log.Fatal("-coverage needs a file to run")
+		}
+		fileCoverage = newCoverage(flag.Arg(0))
+		intr.Hooks = fileCoverage.hooks()
+	}
+	if *countOps {
+		stats := &interp.Stats{}
+		intr.Stats = stats
+		unit := "nodes evaluated"
+		if *backendName == "vm" {
+			unit = "instructions run"
+		}
+		defer func() { fmt.Fprintln(stderr, stats.Steps, unit) }()
+	}
+	if *profile {
+		p := newProfiler()
+		intr.Hooks = p.hooks()
+		defer p.report(stderr)
+	}
+	if *trace && *backendName != "vm" {
+		log.Fatal("-trace only works with the vm backend")
+	}
+	var runner backend
+	switch *backendName {
+	case "tree":
+		runner = intr
+	case "vm":
+		machine := vm.New(intr)
+		if *trace {
+			machine.SetTrace(stderr)
+		}
+		runner = machine
+	default:
+		log.Fatalf("unknown backend %q", *backendName)
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "debug" {
+		if len(args) != 2 || *backendName != "tree" || intr.Hooks != nil {
+			log.Fatal("debug needs one file, which it runs with the tree backend, without -coverage or -profile")
+		}
+		debugFile(intr, args[1])
+	} else if len(args) > 0 && args[0] == "dap" {
+		if len(args) != 1 || *backendName != "tree" || intr.Hooks != nil {
+			log.Fatal("dap takes no file, the editor launches it, and runs it with the tree backend, without -coverage or -profile")
+		}
+		serveDAP(intr)
+	} else if len(args) > 0 && args[0] == "build" {
+		if len(args) != 2 || intr.Hooks != nil || intr.Stats != nil || *maxSteps != 0 || *maxMemory != 0 {
+			log.Fatal("build needs one file, and takes no other option than -coerce-strings, -allow-files and -o")
+		}
+		buildFile(args[1], *buildOutput, compiled.Options{CoerceStrings: *coerceStrings, AllowFiles: *allowFiles})
+	} else if len(args) > 0 && args[0] == "compile" {
+		if len(args) != 2 {
+			log.Fatal("compile needs one file")
+		}
+		compileFile(args[1], *buildOutput)
+	} else if len(args) > 0 && args[0] == "disasm" {
+		if len(args) != 2 {
+			log.Fatal("disasm needs one file, a script or a .loxc file")
+		}
+		disassembleFile(args[1])
+	} else if len(args) > 0 && args[0] == "run" {
+		if len(args) != 2 {
+			log.Fatal("run needs one .loxc file")
+		}
+		runCompiledFile(runner.(*vm.VM), args[1])
+	} else if *checkOnly {
+		if len(args) > 1 {
+			log.Fatal("-check needs at most one file")
+		}
+		checkFile(args)
+	} else if len(args) > 0 {
+		runFile(runner, args[0])
 	} else {
-		runPrompt()
+		runPrompt(runner, intr.Globals())
+	}
+}
+
+func runFile(runner backend, path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatalf("reading file: %v", err)
+	}
+	status := run(runner, string(data))
+	reportCoverage(string(data))
+	setExitStatus(status)
+}
+
+// setExitStatus makes the CLI end with status once it has made its reports, unless the code called exit.
+func setExitStatus(status int) {
+	if status != 0 && exit == nil {
+		exit = &interp.ExitError{Code: status}
+	}
+}
+
+// debugFile runs the file at path in the debugger, which reads its commands from stdin.
+func debugFile(intr interp.Interpreter, path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Fatalf("reading file: %v", err)
 	}
+	d := newDebugger(intr, path, string(data), os.Stdin, stdout)
+	intr.Hooks = d.hooks()
+	setExitStatus(run(intr, string(data)))
 }
 
-func runFile(path string) {
+// parseFile reads and parses the script at path, for a command that translates it rather than running it.
+// It prints every error and exits with exitSyntaxError if there were any.
+func parseFile(path string) (string, []ast.Stmt) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		log.Fatalf("reading file: %v", err)
 	}
-	run(string(data))
+	source := string(data)
+	s := scanner.NewWithConfig(source, scanner.Config{Keywords: keywords})
+	tokens, errs := s.ScanTokens()
+	statements, parseErrs := parser.New(tokens).Parse()
+	errs = append(errs, parseErrs...)
+	for _, err := range errs {
+		fmt.Fprintln(stderr, diag.Format(source, err))
+	}
+	if len(errs) > 0 {
+		os.Exit(exitSyntaxError)
+	}
+	return source, statements
+}
+
+// checkFile scans and parses the file in args, or stdin if there is none, without running it.
+// It prints every error and exits with exitSyntaxError if there were any.
+func checkFile(args []string) {
+	var data []byte
+	var err error
+	if len(args) == 1 {
+		data, err = ioutil.ReadFile(args[0])
+	} else {
+		data, err = ioutil.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		log.Fatalf("reading file: %v", err)
+	}
+	s := scanner.NewWithConfig(string(data), scanner.Config{Keywords: keywords})
+	tokens, errs := s.ScanTokens()
+	_, parseErrs := parser.New(tokens).Parse()
+	errs = append(errs, parseErrs...)
+	for _, err := range errs {
+		fmt.Fprintln(stderr, diag.Format(string(data), err))
+	}
+	if len(errs) > 0 {
+		os.Exit(exitSyntaxError)
+	}
 }
 
-func runPrompt() {
+func runPrompt(runner backend, globals *interp.Environment) {
 	ioScanner := bufio.NewScanner(os.Stdin)
+	results := 0
 	for ioScanner.Scan() {
-		run(ioScanner.Text())
+		value, ok := runLine(runner, ioScanner.Text())
+		if exit != nil {
+			return
+		}
+		if ok {
+			// the results are kept like in Python: _ is the last one, _1, _2... are all of them in order
+			results++
+			globals.Define("_", value)
+			globals.Define(fmt.Sprintf("_%d", results), value)
+		}
 	}
 	if err := ioScanner.Err(); err != nil {
 		log.Fatalf("scanning stdin: %v", err)
 	}
 }
 
-func run(text string) {
-	scanner := scanner.New(text)
-	tokens, errors := scanner.ScanTokens()
+// run runs text, and returns the exit status of a script that ends like it: 0, exitSyntaxError or
+// exitRuntimeError. The code may have called exit instead, which sets exit.
+func run(runner backend, text string) int {
+	tokens, ok := scan(text)
+	if !ok {
+		return exitSyntaxError
+	}
+	statements, errors := parser.New(tokens).Parse()
 	if len(errors) > 0 {
 		for _, err := range errors {
-			fmt.Println(err)
+			fmt.Fprintln(stderr, diag.Format(text, err))
 		}
-		return
+		return exitSyntaxError
+	}
+	if fileCoverage != nil {
+		fileCoverage.add(statements)
 	}
-	for _, token := range tokens {
-		fmt.Println(token)
+	if *astFormat == "json" {
+		data, err := ast.Marshal(statements)
+		if err != nil {
+			log.Fatalf("encoding syntax tree: %v", err)
+		}
+		fmt.Fprintln(stdout, string(data))
+		return 0
+	}
+	if err := runner.Interpret(statements); err != nil && !reportRuntimeError(text, err) {
+		return exitRuntimeError
+	}
+	return 0
+}
+
+// runLine runs a line typed in the REPL: an expression is evaluated and its value printed and returned,
+// anything else is run as statements.
+func runLine(runner backend, line string) (interp.Value, bool) {
+	if *astFormat != "" {
+		run(runner, line)
+		return interp.Nil(), false
+	}
+	tokens, ok := scan(line)
+	if !ok {
+		return interp.Nil(), false
+	}
+	expr, err := parser.New(tokens).ParseExpression()
+	if err != nil {
+		run(runner, line)
+		return interp.Nil(), false
+	}
+	value, err := runner.Evaluate(expr)
+	if err != nil {
+		reportRuntimeError(line, err)
+		return interp.Nil(), false
+	}
+	fmt.Fprintln(stdout, value)
+	return value, true
+}
+
+// reportRuntimeError prints err with its source and the calls that were running, unless the code ended
+// with exit, which it keeps instead. It reports whether the code ended with exit.
+func reportRuntimeError(text string, err error) bool {
+	var exitErr interp.ExitError
+	if errors.As(err, &exitErr) {
+		exit = &exitErr
+		return true
+	}
+	fmt.Fprintln(stderr, diag.Format(text, err))
+	var re interp.RuntimeError
+	if errors.As(err, &re) {
+		fmt.Fprint(stderr, re.StackTrace())
+	}
+	return false
+}
+
+// keywords are the reserved words of the code the CLI runs, with those of the extensions, like spawn.
+var keywords = scanner.Keywords()
+
+// interner is shared by the lines of the REPL, so that a name keeps one copy however many lines use it.
+var interner intern.Table
+
+func scan(text string) ([]token.Token, bool) {
+	s := scanner.NewWithConfig(text, scanner.Config{Keywords: keywords, Interner: &interner})
+	tokens, errors := s.ScanTokens()
+	for _, err := range errors {
+		fmt.Fprintln(stderr, diag.Format(text, err))
 	}
+	return tokens, len(errors) == 0
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/vm"
+)
+
+func TestRunExitStatus(t *testing.T) {
+	oldStdout, oldStderr := stdout, stderr
+	defer func() { stdout, stderr, exit = oldStdout, oldStderr, nil }()
+	tests := []struct {
+		source string
+		status int
+		exit   int
+	}{
+		{"print 1;", 0, 0},
+		{"print @;", exitSyntaxError, 0},
+		{"print 1 +;", exitSyntaxError, 0},
+		{`print "a" - 1;`, exitRuntimeError, 0},
+		{"print undefined;", exitRuntimeError, 0},
+		{"exit(3);", 0, 3},
+	}
+	for _, test := range tests {
+		for _, name := range []string{"tree", "vm"} {
+			exit = nil
+			var out bytes.Buffer
+			stdout, stderr = &out, &out
+			intr := interp.New()
+			intr.Stdout, intr.Stderr = &out, &out
+			var runner backend = intr
+			if name == "vm" {
+				runner = vm.New(intr)
+			}
+			status := run(runner, test.source)
+			exitCode := 0
+			if exit != nil {
+				exitCode = exit.Code
+			}
+			if status != test.status || exitCode != test.exit {
+				t.Errorf("%s on the %s: status %d and exit %d, want %d and %d; it printed %q", test.source, name, status, exitCode, test.status, test.exit, out.String())
+			}
+		}
+	}
+}
+
+// TestASTJSON checks that -ast json prints the syntax tree of the code instead of running it.
+func TestASTJSON(t *testing.T) {
+	oldStdout, oldStderr, oldFormat := stdout, stderr, *astFormat
+	defer func() { stdout, stderr, exit, *astFormat = oldStdout, oldStderr, nil, oldFormat }()
+	*astFormat, exit = "json", nil
+	const source = "var a = 1;\nprint a + 2.5;\nexit(3);"
+	var out, errOut bytes.Buffer
+	stdout, stderr = &out, &errOut
+	intr := interp.New()
+	intr.Stdout, intr.Stderr = &out, &errOut
+	if status := run(intr, source); status != 0 || errOut.Len() > 0 || exit != nil {
+		t.Fatalf("the syntax tree ended with the status %d and exit %v and printed %q", status, exit, errOut.String())
+	}
+	statements, err := ast.Unmarshal(out.Bytes())
+	if err != nil {
+		t.Fatalf("the syntax tree\n%s\ndoes not decode: %v", out.String(), err)
+	}
+	tokens, _ := scan(source)
+	want, _ := parser.New(tokens).Parse()
+	if !reflect.DeepEqual(statements, want) {
+		t.Errorf("the syntax tree decodes to\n%s", ast.DiffStmts(want, statements))
+	}
+
+	out.Reset()
+	if status := run(intr, "print 1 +;"); status != exitSyntaxError || out.Len() > 0 {
+		t.Errorf("the syntax tree of code that does not parse ended with the status %d and printed %q", status, out.String())
+	}
+}
@@ -0,0 +1,278 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// Binding powers of the operators, from the loosest to the tightest.
+const (
+	precedenceAssignment = iota + 1
+	precedenceCoalesce
+	precedenceOr
+	precedenceAnd
+	precedenceEquality
+	precedenceComparison
+	precedenceTerm
+	precedenceFactor
+	precedenceUnary
+	precedenceCall
+)
+
+// prefixParselet parses an expression that starts with tok, which has been consumed.
+type prefixParselet func(p *Parser, tok token.Token) (ast.Expr, error)
+
+// infixParselet parses the rest of an expression whose operator tok follows left, and has been consumed.
+type infixParselet func(p *Parser, left ast.Expr, tok token.Token) (ast.Expr, error)
+
+type infixRule struct {
+	precedence int
+	parse      infixParselet
+}
+
+var (
+	// prefixRules are the parselets of the token types an expression can start with.
+	prefixRules map[token.TokenType]prefixParselet
+	// infixRules are the parselets of the operators that follow an operand.
+	infixRules map[token.TokenType]infixRule
+	// expressionStart are the token types an expression can start with, in order.
+	expressionStart []token.TokenType
+	// infixOperators are the token types that can follow an operand, in order.
+	infixOperators []token.TokenType
+)
+
+// The tables are filled in init, as the parselets refer back to them through parsePrecedence.
+func init() {
+	prefixRules = map[token.TokenType]prefixParselet{
+		token.NUMBER:     literal,
+		token.STRING:     literal,
+		token.TRUE:       literal,
+		token.FALSE:      literal,
+		token.NIL:        literal,
+		token.IDENTIFIER: variable,
+		token.LEFT_PAREN: grouping,
+		token.MINUS:      unary,
+		token.BANG:       unary,
+	}
+	infixRules = map[token.TokenType]infixRule{
+		token.EQUAL:             {precedenceAssignment, assignment},
+		token.QUESTION_QUESTION: {precedenceCoalesce, logical},
+		token.OR:                {precedenceOr, logical},
+		token.AND:               {precedenceAnd, logical},
+		token.EQUAL_EQUAL:       {precedenceEquality, binary},
+		token.BANG_EQUAL:        {precedenceEquality, binary},
+		token.GREATER:           {precedenceComparison, comparison},
+		token.GREATER_EQUAL:     {precedenceComparison, comparison},
+		token.LESS:              {precedenceComparison, comparison},
+		token.LESS_EQUAL:        {precedenceComparison, comparison},
+		token.PLUS:              {precedenceTerm, binary},
+		token.MINUS:             {precedenceTerm, binary},
+		token.STAR:              {precedenceFactor, binary},
+		token.SLASH:             {precedenceFactor, binary},
+		token.LEFT_PAREN:        {precedenceCall, call},
+		token.DOT:               {precedenceCall, property},
+	}
+	for typ := range prefixRules {
+		expressionStart = append(expressionStart, typ)
+	}
+	sort.Slice(expressionStart, func(i, j int) bool { return expressionStart[i] < expressionStart[j] })
+	for typ := range infixRules {
+		infixOperators = append(infixOperators, typ)
+	}
+	sort.Slice(infixOperators, func(i, j int) bool { return infixOperators[i] < infixOperators[j] })
+}
+
+func (p *Parser) expression() (ast.Expr, error) {
+	return p.parsePrecedence(precedenceAssignment)
+}
+
+// parsePrecedence parses an expression whose operators bind at least as tightly as precedence.
+func (p *Parser) parsePrecedence(precedence int) (ast.Expr, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	maxDepth := p.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if p.depth > maxDepth {
+		return nil, p.error(p.peek(), TooDeep, nil, "Expression nested too deeply.")
+	}
+	prefix, ok := prefixRules[p.peek().Type]
+	if !ok {
+		return nil, p.error(p.peek(), MissingExpression, expressionStart, "Expect expression.")
+	}
+	expr, err := prefix(p, p.advance())
+	if err != nil {
+		return nil, err
+	}
+	for {
+		rule, ok := infixRules[p.peek().Type]
+		if !ok || rule.precedence < precedence {
+			return expr, nil
+		}
+		if expr, err = rule.parse(p, expr, p.advance()); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func literal(p *Parser, tok token.Token) (ast.Expr, error) {
+	switch tok.Type {
+	case token.TRUE:
+		return ast.Literal{Value: true, Span: tok.Span}, nil
+	case token.FALSE:
+		return ast.Literal{Value: false, Span: tok.Span}, nil
+	case token.NIL:
+		return ast.Literal{Value: nil, Span: tok.Span}, nil
+	}
+	return ast.Literal{Value: tok.Literal, Span: tok.Span}, nil
+}
+
+func variable(p *Parser, name token.Token) (ast.Expr, error) {
+	return ast.Variable{Name: name, Span: name.Span}, nil
+}
+
+func grouping(p *Parser, left token.Token) (ast.Expr, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	right, err := p.consumeAfterExpression(token.RIGHT_PAREN, "expression")
+	if err != nil {
+		return nil, err
+	}
+	return ast.Grouping{Expr: expr, Span: left.Span.Cover(right.Span)}, nil
+}
+
+func unary(p *Parser, operator token.Token) (ast.Expr, error) {
+	right, err := p.parsePrecedence(precedenceUnary)
+	if err != nil {
+		return nil, err
+	}
+	return ast.Unary{
+		Operator: operator,
+		Right:    right,
+		Span:     operator.Span.Cover(right.SourceSpan()),
+	}, nil
+}
+
+// binary parses a left associative binary operator: its right operand binds one level tighter.
+func binary(p *Parser, left ast.Expr, operator token.Token) (ast.Expr, error) {
+	right, err := p.parsePrecedence(infixRules[operator.Type].precedence + 1)
+	if err != nil {
+		return nil, err
+	}
+	return ast.Binary{
+		Operator: operator,
+		Left:     left,
+		Right:    right,
+		Span:     left.SourceSpan().Cover(right.SourceSpan()),
+	}, nil
+}
+
+func logical(p *Parser, left ast.Expr, operator token.Token) (ast.Expr, error) {
+	right, err := p.parsePrecedence(infixRules[operator.Type].precedence + 1)
+	if err != nil {
+		return nil, err
+	}
+	return ast.Logical{
+		Operator: operator,
+		Left:     left,
+		Right:    right,
+		Span:     left.SourceSpan().Cover(right.SourceSpan()),
+	}, nil
+}
+
+// comparison parses all the comparisons of a chain: a single one is a Binary, more make a Comparison.
+func comparison(p *Parser, left ast.Expr, operator token.Token) (ast.Expr, error) {
+	operators := []token.Token{operator}
+	operands := []ast.Expr{left}
+	for {
+		right, err := p.parsePrecedence(precedenceTerm)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, right)
+		if !p.match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL) {
+			break
+		}
+		operators = append(operators, p.previous())
+	}
+	if len(operators) == 1 {
+		return ast.Binary{
+			Operator: operators[0],
+			Left:     operands[0],
+			Right:    operands[1],
+			Span:     operands[0].SourceSpan().Cover(operands[1].SourceSpan()),
+		}, nil
+	}
+	return ast.Comparison{
+		Operators: operators,
+		Operands:  operands,
+		Span:      operands[0].SourceSpan().Cover(operands[len(operands)-1].SourceSpan()),
+	}, nil
+}
+
+// assignment parses the value assigned to the variable or the property on its left. It is right associative,
+// so its value is parsed at its own precedence: a = b = c assigns c to b, then to a.
+func assignment(p *Parser, left ast.Expr, equals token.Token) (ast.Expr, error) {
+	switch left.(type) {
+	case ast.Variable, ast.Get:
+	default:
+		return nil, p.error(equals, InvalidAssignment, nil, "Invalid assignment target.")
+	}
+	value, err := p.parsePrecedence(precedenceAssignment)
+	if err != nil {
+		return nil, err
+	}
+	span := left.SourceSpan().Cover(value.SourceSpan())
+	if target, ok := left.(ast.Get); ok {
+		return ast.Set{Object: target.Object, Name: target.Name, Value: value, Span: span}, nil
+	}
+	return ast.Assign{Name: left.(ast.Variable).Name, Value: value, Span: span}, nil
+}
+
+// property parses the name of a property of the expression on its left.
+func property(p *Parser, object ast.Expr, dot token.Token) (ast.Expr, error) {
+	name, err := p.consume(token.IDENTIFIER, "Expect property name after '.'.")
+	if err != nil {
+		return nil, err
+	}
+	return ast.Get{Object: object, Name: name, Span: object.SourceSpan().Cover(name.Span)}, nil
+}
+
+// maxArguments is the most arguments a call can have, as in the reference implementation.
+const maxArguments = 255
+
+// call parses the arguments of a call of the expression on its left, up to the closing parenthesis.
+func call(p *Parser, callee ast.Expr, paren token.Token) (ast.Expr, error) {
+	var arguments []ast.Expr
+	if !p.checkTokenType(token.RIGHT_PAREN) {
+		for {
+			if len(arguments) == maxArguments {
+				return nil, p.error(p.peek(), TooManyArguments, nil, fmt.Sprintf("Can't have more than %d arguments.", maxArguments))
+			}
+			argument, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			arguments = append(arguments, argument)
+			if !p.match(token.COMMA) {
+				break
+			}
+		}
+	}
+	closing, err := p.consumeAfterExpression(token.RIGHT_PAREN, "arguments")
+	if err != nil {
+		return nil, err
+	}
+	return ast.Call{
+		Callee:    callee,
+		Paren:     closing,
+		Arguments: arguments,
+		Span:      callee.SourceSpan().Cover(closing.Span),
+	}, nil
+}
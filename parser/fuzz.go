@@ -0,0 +1,55 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+)
+
+// FuzzSeeds are inputs that exercise every kind of token, expression and statement. They seed FuzzScan and
+// FuzzParse, with the corpus in the testdata/fuzz directories:
+//
+//	go test ./parser -fuzz FuzzParse
+var FuzzSeeds = []string{
+	``,
+	`print 1; 2;`,
+	`print ; 1 + ; print (; 3;`,
+	`1 + 2 * 3 - 4 / 5`,
+	`-(1.5e3 + 0x1F) * 0b101 - 1_000`,
+	`!true == false != nil`,
+	`1 < 2 <= 3 > 2 >= 1`,
+	`nil ?? "default"`,
+	`var a; var b = 1; a = b = b + 1; print a;`,
+	`var = 1; var a 1; (a) = 1; a + 1 = 2;`,
+	`print clock() - f(1, a = 2)(3); -g(); f(; f(1,);`,
+	`"multi
+line" + "string"`,
+	`(((1)))`,
+	`// line comment
+/* block
+comment */ 1`,
+	`and class else fun for if or print return super this var while`,
+	`"unterminated`,
+	`/* unterminated`,
+	`1__2 0x 1e+ @#$ ?`,
+	`(1 + )`,
+}
+
+// ParseSource scans and parses source and returns the statements and all the scan and parse errors, for fuzzing.
+// The tokens are parsed even if there are scan errors, to reach as much of the parser as possible.
+// It never panics: a panic in the scanner or the parser is returned as an error.
+func ParseSource(source []byte) (statements []ast.Stmt, errs []error) {
+	tokens, errs := scanner.ScanSource(source)
+	if tokens == nil {
+		return nil, errs
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			statements = nil
+			errs = append(errs, fmt.Errorf("parser panic: %v", r))
+		}
+	}()
+	statements, parseErrs := New(tokens).Parse()
+	return statements, append(errs, parseErrs...)
+}
@@ -0,0 +1,193 @@
+package parser
+
+import (
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// Tree is the result of parsing tokens, kept so that they can be parsed again incrementally after an edit.
+type Tree struct {
+	Tokens     []token.Token
+	Statements []ast.Stmt
+	Errors     []error
+	// code are the tokens without the comments, which the parser saw
+	code []token.Token
+}
+
+// NewTree parses tokens.
+func NewTree(tokens []token.Token) *Tree {
+	p := New(tokens)
+	statements, errs := p.Parse()
+	return &Tree{Tokens: tokens, Statements: statements, Errors: errs, code: p.tokens}
+}
+
+// Reparse returns the tree of tokens, which are the tokens of the tree after an edit, as scanner.Rescan returns them.
+// It gives the same result as NewTree, but only parses again the statements that overlap the tokens changed
+// by the edit. The statements before them are reused and so are the statements after them,
+// with their positions shifted.
+func (t *Tree) Reparse(tokens []token.Token) *Tree {
+	p := New(tokens)
+	old, cur := t.code, p.tokens
+	delta := cur[len(cur)-1].StartOffset - old[len(old)-1].StartOffset
+
+	// The tokens before the edit are the same, the tokens after it are the same but for their positions.
+	prefix := 0
+	for prefix < len(old)-1 && prefix < len(cur)-1 && old[prefix] == cur[prefix] {
+		prefix++
+	}
+	// The lines after the edit move by the same number of lines, and only the columns on the line where it ends
+	// move, as in scanner.Rescan. The shifter checks this for each token, so that it moves every one of them
+	// where it is now.
+	sh := shifter{
+		delta:     delta,
+		line:      old[len(old)-1].Line,
+		lineDelta: cur[len(cur)-1].Line - old[len(old)-1].Line,
+		colDelta:  cur[len(cur)-1].Col - old[len(old)-1].Col,
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(cur)-prefix {
+		o, c := old[len(old)-1-suffix], cur[len(cur)-1-suffix]
+		if o.Line != sh.line && sh.colDelta == 0 {
+			sh.line = o.Line
+			sh.colDelta = c.Col - o.Col
+		}
+		if o.Type != c.Type || o.Lexeme != c.Lexeme || o.Literal != c.Literal || sh.token(o) != c {
+			break
+		}
+		suffix++
+	}
+
+	// Statements made only of tokens before the edit parse the same.
+	kept := 0
+	restart := 0
+	for kept < len(t.Statements) && prefix > 0 && t.Statements[kept].SourceSpan().EndOffset <= old[prefix-1].EndOffset {
+		restart = t.Statements[kept].SourceSpan().EndOffset
+		kept++
+	}
+	tree := &Tree{Tokens: tokens, Statements: append([]ast.Stmt(nil), t.Statements[:kept]...), code: cur}
+	for _, err := range t.Errors {
+		if err.(ParseError).Token.StartOffset < restart {
+			tree.Errors = append(tree.Errors, err)
+		}
+	}
+	for p.current < len(cur)-1 && cur[p.current].StartOffset < restart {
+		p.current++
+	}
+
+	// Parse again until the parser is at the start of a statement made only of tokens after the edit:
+	// that statement and the ones after it parse the same.
+	next := kept
+	for next < len(t.Statements) && t.Statements[next].SourceSpan().StartOffset < old[len(old)-suffix].StartOffset {
+		next++
+	}
+	for !p.isAtEnd() {
+		for next < len(t.Statements) && t.Statements[next].SourceSpan().StartOffset+delta < p.peek().StartOffset {
+			next++
+		}
+		if next < len(t.Statements) && t.Statements[next].SourceSpan().StartOffset+delta == p.peek().StartOffset {
+			break
+		}
+		stmt, err := p.declaration()
+		if err != nil {
+			tree.Errors = append(tree.Errors, err)
+			p.synchronize()
+			continue
+		}
+		tree.Statements = append(tree.Statements, stmt)
+	}
+	if p.isAtEnd() {
+		return tree
+	}
+
+	resume := t.Statements[next].SourceSpan().StartOffset
+	for _, stmt := range t.Statements[next:] {
+		tree.Statements = append(tree.Statements, sh.stmt(stmt))
+	}
+	for _, err := range t.Errors {
+		if pe := err.(ParseError); pe.Token.StartOffset >= resume {
+			pe.Token = sh.token(pe.Token)
+			tree.Errors = append(tree.Errors, pe)
+		}
+	}
+	return tree
+}
+
+// shifter moves nodes parsed from the tokens after an edit to the positions of these tokens after the edit,
+// the way scanner.Rescan moves the tokens: only the columns on the line where they start change.
+type shifter struct {
+	delta               int
+	line                int
+	lineDelta, colDelta int
+}
+
+func (sh shifter) token(tok token.Token) token.Token {
+	tok.Span = sh.span(tok.Span)
+	return tok
+}
+
+func (sh shifter) span(span token.Span) token.Span {
+	if span.Line == sh.line {
+		span.Col += sh.colDelta
+	}
+	span.Line += sh.lineDelta
+	span.StartOffset += sh.delta
+	span.EndOffset += sh.delta
+	return span
+}
+
+func (sh shifter) stmt(stmt ast.Stmt) ast.Stmt {
+	switch stmt := stmt.(type) {
+	case ast.ExpressionStmt:
+		return ast.ExpressionStmt{Expr: sh.expr(stmt.Expr), Span: sh.span(stmt.Span)}
+	case ast.PrintStmt:
+		return ast.PrintStmt{Expr: sh.expr(stmt.Expr), Span: sh.span(stmt.Span)}
+	case ast.SpawnStmt:
+		return ast.SpawnStmt{Call: sh.expr(stmt.Call), Span: sh.span(stmt.Span)}
+	case ast.VarStmt:
+		shifted := ast.VarStmt{Name: sh.token(stmt.Name), Span: sh.span(stmt.Span)}
+		if stmt.Initializer != nil {
+			shifted.Initializer = sh.expr(stmt.Initializer)
+		}
+		return shifted
+	}
+	panic("unknown statement type")
+}
+
+func (sh shifter) expr(expr ast.Expr) ast.Expr {
+	switch expr := expr.(type) {
+	case ast.Binary:
+		return ast.Binary{Operator: sh.token(expr.Operator), Left: sh.expr(expr.Left), Right: sh.expr(expr.Right), Span: sh.span(expr.Span)}
+	case ast.Logical:
+		return ast.Logical{Operator: sh.token(expr.Operator), Left: sh.expr(expr.Left), Right: sh.expr(expr.Right), Span: sh.span(expr.Span)}
+	case ast.Comparison:
+		shifted := ast.Comparison{Span: sh.span(expr.Span)}
+		for _, operator := range expr.Operators {
+			shifted.Operators = append(shifted.Operators, sh.token(operator))
+		}
+		for _, operand := range expr.Operands {
+			shifted.Operands = append(shifted.Operands, sh.expr(operand))
+		}
+		return shifted
+	case ast.Grouping:
+		return ast.Grouping{Expr: sh.expr(expr.Expr), Span: sh.span(expr.Span)}
+	case ast.Literal:
+		return ast.Literal{Value: expr.Value, Span: sh.span(expr.Span)}
+	case ast.Unary:
+		return ast.Unary{Operator: sh.token(expr.Operator), Right: sh.expr(expr.Right), Span: sh.span(expr.Span)}
+	case ast.Variable:
+		return ast.Variable{Name: sh.token(expr.Name), Span: sh.span(expr.Span)}
+	case ast.Assign:
+		return ast.Assign{Name: sh.token(expr.Name), Value: sh.expr(expr.Value), Span: sh.span(expr.Span)}
+	case ast.Get:
+		return ast.Get{Object: sh.expr(expr.Object), Name: sh.token(expr.Name), Span: sh.span(expr.Span)}
+	case ast.Set:
+		return ast.Set{Object: sh.expr(expr.Object), Name: sh.token(expr.Name), Value: sh.expr(expr.Value), Span: sh.span(expr.Span)}
+	case ast.Call:
+		shifted := ast.Call{Callee: sh.expr(expr.Callee), Paren: sh.token(expr.Paren), Span: sh.span(expr.Span)}
+		for _, argument := range expr.Arguments {
+			shifted.Arguments = append(shifted.Arguments, sh.expr(argument))
+		}
+		return shifted
+	}
+	panic("unknown expression type")
+}
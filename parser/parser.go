@@ -0,0 +1,393 @@
+// Package parser turns the tokens of a Lox program into its syntax tree.
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+/*
+Our grammar for statements:
+program        → declaration* EOF
+declaration    → varDecl | statement
+varDecl        → "var" IDENTIFIER ( "=" expression )? ";"
+statement      → exprStmt | printStmt
+exprStmt       → expression ";"
+printStmt      → "print" expression ";"
+
+Our grammar for expressions:
+expression -> literal | unary | binary | grouping | variable | assignment | call
+call -> expression "(" (expression ("," expression)*)? ")"
+variable -> IDENTIFIER
+assignment -> IDENTIFIER "=" expression
+literal -> NUMBER | STRING | "true" | "false" | "nil"
+grouping -> "(" expression ")"
+unary -> ("-" | "!") expression
+binary -> expression operator expression
+operator -> "==" | "!=" | "<" | ">" | "<=" | ">=" | "+" | "-" | "*" | "/" | or | and
+
+Operator precedence and associativity turn it into this grammar, which the Pratt parser in expression.go implements:
+each level is an entry of its tables rather than a method.
+expression     → assignment
+assignment     → IDENTIFIER "=" assignment | coalesce
+coalesce       → or ("??" or)*
+or             → and ("or" and)*
+and            → equality ("and" equality)*
+equality       → comparison (("==" | "!=") comparison)*
+comparison     → term (("<" | ">" | "<=" | ">=") term) *
+term           → factor (("+" | "-") factor)*
+factor         → unary (("*" | "/") unary)*
+unary          → ("-" | "!") unary | call
+call           → primary ( "(" arguments? ")" )*
+arguments      → expression ( "," expression )*
+primary        → NUMBER | STRING | "true" | "false" | "nil" | "(" expression ")" | IDENTIFIER
+
+A chain of comparisons like a < b < c means a < b and b < c, with b evaluated only once,
+and it stops at the first comparison that is false.
+*/
+
+// DefaultMaxDepth is the nesting depth of expressions a parser accepts when its MaxDepth is not set.
+const DefaultMaxDepth = 1000
+
+type Parser struct {
+	// MaxDepth limits how deeply expressions can nest, so that deeply nested input is a syntax error
+	// instead of running out of stack. 0 means DefaultMaxDepth.
+	MaxDepth int
+	tokens   []token.Token
+	current  int
+	depth    int
+}
+
+// New returns a parser for tokens, ignoring any comment tokens.
+// The tokens should end with an EOF token, as the scanner returns them; one is added if they do not.
+func New(tokens []token.Token) *Parser {
+	code := tokens
+	for i, tok := range tokens {
+		if tok.Type == token.COMMENT {
+			code = append(make([]token.Token, 0, len(tokens)), tokens[:i]...)
+			for _, tok := range tokens[i+1:] {
+				if tok.Type != token.COMMENT {
+					code = append(code, tok)
+				}
+			}
+			break
+		}
+	}
+	if len(code) == 0 || code[len(code)-1].Type != token.EOF {
+		eof := token.Token{Type: token.EOF}
+		if len(code) > 0 {
+			last := code[len(code)-1]
+			eof.Span = token.Span{StartOffset: last.EndOffset, EndOffset: last.EndOffset, Line: last.Line, Col: last.Col + len(last.Lexeme)}
+		}
+		code = append(code[:len(code):len(code)], eof)
+	}
+	return &Parser{tokens: code}
+}
+
+// Parse parses a program.
+// After a syntax error it skips to the next statement and goes on, so it returns all the statements
+// it could parse together with all the errors.
+func (p *Parser) Parse() ([]ast.Stmt, []error) {
+	var statements []ast.Stmt
+	var errs []error
+	for !p.isAtEnd() {
+		stmt, err := p.declaration()
+		if err != nil {
+			errs = append(errs, err)
+			p.synchronize()
+			continue
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, errs
+}
+
+// ParseExpression parses tokens that make up a single expression.
+func (p *Parser) ParseExpression() (ast.Expr, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isAtEnd() {
+		return nil, p.errorAfterExpression(token.EOF, "expression")
+	}
+	return expr, nil
+}
+
+func (p *Parser) declaration() (ast.Stmt, error) {
+	if p.match(token.VAR) {
+		return p.varDeclaration()
+	}
+	return p.statement()
+}
+
+func (p *Parser) varDeclaration() (ast.Stmt, error) {
+	keyword := p.previous()
+	name, err := p.consume(token.IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+	stmt := ast.VarStmt{Name: name}
+	var semicolon token.Token
+	if p.match(token.EQUAL) {
+		if stmt.Initializer, err = p.expression(); err != nil {
+			return nil, err
+		}
+		if semicolon, err = p.consumeAfterExpression(token.SEMICOLON, "variable declaration"); err != nil {
+			return nil, err
+		}
+	} else {
+		if !p.checkTokenType(token.SEMICOLON) {
+			expected := []token.TokenType{token.SEMICOLON, token.EQUAL}
+			return nil, p.error(p.peek(), UnexpectedToken, expected, fmt.Sprintf("Expect %s after variable name.", describeTypes(expected)))
+		}
+		semicolon = p.advance()
+	}
+	stmt.Span = keyword.Span.Cover(semicolon.Span)
+	return stmt, nil
+}
+
+func (p *Parser) statement() (ast.Stmt, error) {
+	if p.match(token.PRINT) {
+		return p.printStatement()
+	}
+	if p.match(token.SPAWN) {
+		return p.spawnStatement()
+	}
+	return p.expressionStatement()
+}
+
+func (p *Parser) printStatement() (ast.Stmt, error) {
+	keyword := p.previous()
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	semicolon, err := p.consumeAfterExpression(token.SEMICOLON, "value")
+	if err != nil {
+		return nil, err
+	}
+	return ast.PrintStmt{Expr: expr, Span: keyword.Span.Cover(semicolon.Span)}, nil
+}
+
+func (p *Parser) spawnStatement() (ast.Stmt, error) {
+	keyword := p.previous()
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := expr.(ast.Call); !ok {
+		return nil, p.error(keyword, SpawnWithoutCall, nil, "Expect a call after 'spawn'.")
+	}
+	semicolon, err := p.consumeAfterExpression(token.SEMICOLON, "call")
+	if err != nil {
+		return nil, err
+	}
+	return ast.SpawnStmt{Call: expr, Span: keyword.Span.Cover(semicolon.Span)}, nil
+}
+
+func (p *Parser) expressionStatement() (ast.Stmt, error) {
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	semicolon, err := p.consumeAfterExpression(token.SEMICOLON, "expression")
+	if err != nil {
+		return nil, err
+	}
+	return ast.ExpressionStmt{Expr: expr, Span: expr.SourceSpan().Cover(semicolon.Span)}, nil
+}
+
+// synchronize discards tokens until it is probably at the start of a statement:
+// after a semicolon or at a keyword that starts one.
+func (p *Parser) synchronize() {
+	p.advance()
+	for !p.isAtEnd() {
+		if p.previous().Type == token.SEMICOLON {
+			return
+		}
+		switch p.peek().Type {
+		case token.CLASS, token.FUN, token.VAR, token.FOR, token.IF, token.WHILE, token.PRINT, token.SPAWN, token.RETURN:
+			return
+		}
+		p.advance()
+	}
+}
+
+func (p *Parser) consume(tokenType token.TokenType, message string) (token.Token, error) {
+	if p.checkTokenType(tokenType) {
+		return p.advance(), nil
+	}
+	return token.Token{}, p.error(p.peek(), UnexpectedToken, []token.TokenType{tokenType}, message)
+}
+
+// consumeAfterExpression consumes a token of type tokenType that follows an expression, which what describes.
+func (p *Parser) consumeAfterExpression(tokenType token.TokenType, what string) (token.Token, error) {
+	if p.checkTokenType(tokenType) {
+		return p.advance(), nil
+	}
+	return token.Token{}, p.errorAfterExpression(tokenType, what)
+}
+
+// errorAfterExpression reports that the token after an expression is not of type tokenType.
+// Any infix operator could have continued the expression instead, so it is expected too.
+func (p *Parser) errorAfterExpression(tokenType token.TokenType, what string) ParseError {
+	expected := append([]token.TokenType{tokenType}, infixOperators...)
+	return p.error(p.peek(), UnexpectedToken, expected, fmt.Sprintf("Expect %s after %s.", describeTypes(expected), what))
+}
+
+func (p *Parser) isAtEnd() bool {
+	return p.tokens[p.current].Type == token.EOF
+}
+
+func (p *Parser) advance() token.Token {
+	if !p.isAtEnd() {
+		p.current++
+	}
+	return p.previous()
+}
+
+func (p *Parser) peek() token.Token {
+	return p.tokens[p.current]
+}
+
+func (p *Parser) previous() token.Token {
+	return p.tokens[p.current-1]
+}
+
+func (p *Parser) checkTokenType(tokenType token.TokenType) bool {
+	if p.isAtEnd() {
+		return false
+	}
+	return p.tokens[p.current].Type == tokenType
+}
+
+func (p *Parser) match(tokenTypes ...token.TokenType) bool {
+	for _, typ := range tokenTypes {
+		if p.checkTokenType(typ) {
+			p.advance()
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) error(tok token.Token, code ParseErrorCode, expected []token.TokenType, message string) ParseError {
+	return ParseError{Token: tok, Code: code, Expected: expected, Message: message}
+}
+
+// symbols are how the token types with a fixed lexeme are written.
+var symbols = map[token.TokenType]string{
+	token.LEFT_PAREN:        "(",
+	token.RIGHT_PAREN:       ")",
+	token.LEFT_BRACE:        "{",
+	token.RIGHT_BRACE:       "}",
+	token.COMMA:             ",",
+	token.DOT:               ".",
+	token.MINUS:             "-",
+	token.PLUS:              "+",
+	token.SEMICOLON:         ";",
+	token.SLASH:             "/",
+	token.STAR:              "*",
+	token.BANG:              "!",
+	token.BANG_EQUAL:        "!=",
+	token.EQUAL:             "=",
+	token.EQUAL_EQUAL:       "==",
+	token.GREATER:           ">",
+	token.GREATER_EQUAL:     ">=",
+	token.LESS:              "<",
+	token.LESS_EQUAL:        "<=",
+	token.QUESTION_QUESTION: "??",
+}
+
+// describeType returns how an error message names a token type.
+func describeType(typ token.TokenType) string {
+	switch typ {
+	case token.IDENTIFIER:
+		return "an identifier"
+	case token.STRING:
+		return "a string"
+	case token.NUMBER:
+		return "a number"
+	case token.COMMENT:
+		return "a comment"
+	case token.EOF:
+		return "end of input"
+	}
+	if symbol, ok := symbols[typ]; ok {
+		return "'" + symbol + "'"
+	}
+	// the keywords
+	return "'" + strings.ToLower(typ.String()) + "'"
+}
+
+// describeTypes returns how an error message names a set of token types: "';'", or "one of ';', '+', '-'".
+func describeTypes(types []token.TokenType) string {
+	if len(types) == 1 {
+		return describeType(types[0])
+	}
+	names := make([]string, len(types))
+	for i, typ := range types {
+		names[i] = describeType(typ)
+	}
+	return "one of " + strings.Join(names, ", ")
+}
+
+// ErrSyntax is the error every ParseError wraps, so errors.Is(err, ErrSyntax) tells syntax errors apart.
+var ErrSyntax = errors.New("syntax error")
+
+// ParseErrorCode is a short machine-readable name for the kind of a syntax error.
+type ParseErrorCode string
+
+const (
+	// MissingExpression means an expression was expected but the token cannot start one.
+	MissingExpression ParseErrorCode = "missing-expression"
+	// UnexpectedToken means the token is not one of the expected types.
+	UnexpectedToken ParseErrorCode = "unexpected-token"
+	// TooDeep means expressions are nested deeper than the MaxDepth of the parser.
+	TooDeep ParseErrorCode = "too-deep"
+	// InvalidAssignment means the left side of an assignment is not a variable.
+	InvalidAssignment ParseErrorCode = "invalid-assignment"
+	// TooManyArguments means a call has more arguments than a function can take.
+	TooManyArguments ParseErrorCode = "too-many-arguments"
+	// SpawnWithoutCall means spawn is followed by something else than a call.
+	SpawnWithoutCall ParseErrorCode = "spawn-without-call"
+)
+
+// ParseError is a syntax error.
+type ParseError struct {
+	// Token is the offending token; its span gives the position of the error.
+	Token token.Token
+	Code  ParseErrorCode
+	// Expected are the token types that would have been valid instead of Token.
+	Expected []token.TokenType
+	Message  string
+}
+
+func (pe ParseError) Error() string {
+	at := fmt.Sprintf("'%s'", pe.Token.Lexeme)
+	if pe.Token.Type == token.EOF {
+		at = "end"
+	}
+	return fmt.Sprintf("Line: %d, Column: %d, at %s: %s", pe.Token.Line, pe.Token.Col, at, pe.Message)
+}
+
+// SourceSpan returns the span of the offending token.
+func (pe ParseError) SourceSpan() token.Span {
+	return pe.Token.Span
+}
+
+func (pe ParseError) Unwrap() error {
+	return ErrSyntax
+}
+
+// Is reports whether target is a ParseError with the same code,
+// so errors.Is(err, ParseError{Code: MissingExpression}) matches any missing expression.
+func (pe ParseError) Is(target error) bool {
+	t, ok := target.(ParseError)
+	return ok && t.Code == pe.Code
+}
@@ -0,0 +1,250 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// parseExpression parses source as one expression, with the keywords of the extensions.
+func parseExpression(t *testing.T, source string) ast.Expr {
+	t.Helper()
+	s := scanner.NewWithConfig(source, scanner.Config{Keywords: scanner.Keywords()})
+	tokens, errs := s.ScanTokens()
+	if len(errs) > 0 {
+		t.Fatalf("scanning %q: %v", source, errs[0])
+	}
+	expr, err := New(tokens).ParseExpression()
+	if err != nil {
+		t.Fatalf("parsing %q: %v", source, err)
+	}
+	return expr
+}
+
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		source, tree string
+	}{
+		// the logical operators bind more loosely than equality, and and more tightly than or
+		{"a == b or c == d", "(or (== a b) (== c d))"},
+		{"a != b and c != d", "(and (!= a b) (!= c d))"},
+		{"a or b and c", "(or a (and b c))"},
+		{"a and b or c", "(or (and a b) c)"},
+		{"a or b or c", "(or (or a b) c)"},
+		{"a and b and c", "(and (and a b) c)"},
+		{"a < b or c + 1 > d and e", "(or (< a b) (and (> (+ c 1) d) e))"},
+		{"!a or b", "(or (! a) b)"},
+		{"!a and !b", "(and (! a) (! b))"},
+		// ?? binds more loosely than or, and more tightly than assignment
+		{"a ?? b or c", "(?? a (or b c))"},
+		{"a or b ?? c", "(?? (or a b) c)"},
+		{"a ?? b ?? c", "(?? (?? a b) c)"},
+		{"a = b ?? c", "(= a (?? b c))"},
+		{"a = b or c", "(= a (or b c))"},
+		{"a = b = c", "(= a (= b c))"},
+		// arithmetic, comparison and equality
+		{"1 + 2 * 3", "(+ 1 (* 2 3))"},
+		{"1 * 2 + 3", "(+ (* 1 2) 3)"},
+		{"1 - 2 - 3", "(- (- 1 2) 3)"},
+		{"1 / 2 / 3", "(/ (/ 1 2) 3)"},
+		{"(1 + 2) * 3", "(* (group (+ 1 2)) 3)"},
+		{"1 + 2 < 3 * 4", "(< (+ 1 2) (* 3 4))"},
+		{"1 < 2 <= 3", "(chain 1 < 2 <= 3)"},
+		{"1 < 2 == 3 > 4", "(== (< 1 2) (> 3 4))"},
+		{"a == b == c", "(== (== a b) c)"},
+		// unary binds more tightly than any binary operator, and more loosely than calls
+		{"-1 + 2", "(+ (- 1) 2)"},
+		{"!a == b", "(== (! a) b)"},
+		{"- -a", "(- (- a))"},
+		{"-a.b(1)", "(- (call (.b a) 1))"},
+		{"!f() and g()", "(and (! (call f)) (call g))"},
+		{"f(a or b, c = d)", "(call f (or a b) (= c d))"},
+		{"a.b.c = d or e", "(= .c (.b a) (or d e))"},
+	}
+	for _, test := range tests {
+		if tree := (ast.Printer{}).Print(parseExpression(t, test.source)); tree != test.tree {
+			t.Errorf("%s parsed as %s, want %s", test.source, tree, test.tree)
+		}
+	}
+}
+
+// FuzzParse checks that the scanner and the parser do not panic, whatever the source, that the
+// statements they return without an error cover the source in order, and that ast.SourcePrinter prints
+// them as source that parses to the same statements.
+func FuzzParse(f *testing.F) {
+	for _, seed := range FuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, source []byte) {
+		statements, errs := ParseSource(source)
+		for _, err := range errs {
+			if strings.Contains(err.Error(), "panic") {
+				t.Fatal(err)
+			}
+		}
+		if len(errs) > 0 {
+			return
+		}
+		end := 0
+		for _, stmt := range statements {
+			span := stmt.SourceSpan()
+			if span.StartOffset < end || span.EndOffset > len(source) || span.StartOffset > span.EndOffset {
+				t.Fatalf("the span %+v of %T is out of order or out of the source", span, stmt)
+			}
+			end = span.EndOffset
+		}
+		printed := ast.SourcePrinter{}.Print(statements)
+		again, errs := ParseSource([]byte(printed))
+		if len(errs) > 0 {
+			t.Fatalf("the printed source\n%s\ndoes not parse: %v", printed, errs[0])
+		}
+		if !ast.EqualStmts(statements, again) {
+			t.Fatalf("the printed source\n%s\nparses to\n%s\ninstead of\n%s", printed, ast.DumpStmts(again), ast.DumpStmts(statements))
+		}
+	})
+}
+
+// TestSourcePrinterRoundTrip checks that ast.SourcePrinter prints parsed statements as source that
+// parses to the same statements.
+func TestSourcePrinterRoundTrip(t *testing.T) {
+	sources := []string{
+		"print 1000000000000000000000.0; print 2.5e-3; print 0xFF;",
+		"print 0.5; print 2.0; print -0.0;",
+		"print -(1); print - -1; print !!true; print -(-1); print -a.b;",
+		"var a; var b; a = b = 1; print a = b ?? 2;",
+		`print "" + "s" == "s";`,
+		"print (1 < 2) < 3; print 1 < (2 < 3); print 1 < 2 < 3; print (1 < 2) == true;",
+		"print 1 - (2 - 3); print (1 - 2) - 3; print 1 / (2 * 3); print -(1 + 2);",
+		"print a ?? (b or c); print (a ?? b) or c; print a and (b or c);",
+		"o.p = o.q.r = f(1, (2))(); print (a = 1).b; spawn f(g, h());",
+	}
+	for _, source := range sources {
+		statements, errs := ParseSource([]byte(source))
+		if len(errs) > 0 {
+			t.Fatalf("%s: %v", source, errs[0])
+		}
+		printed := ast.SourcePrinter{}.Print(statements)
+		again, errs := ParseSource([]byte(printed))
+		if len(errs) > 0 {
+			t.Errorf("%s printed as %q, which does not parse: %v", source, printed, errs[0])
+		} else if !ast.EqualStmts(statements, again) {
+			t.Errorf("%s printed as %q, which parses to\n%s", source, printed, ast.DumpStmts(again))
+		}
+	}
+}
+
+// TestSourcePrinterParentheses checks that ast.SourcePrinter adds the parentheses that trees built by
+// hand, without groupings, need to parse back the same.
+func TestSourcePrinterParentheses(t *testing.T) {
+	number := func(n int64) ast.Expr { return ast.Literal{Value: n} }
+	binary := func(operator token.TokenType, lexeme string, left, right ast.Expr) ast.Expr {
+		return ast.Binary{Operator: token.Token{Type: operator, Lexeme: lexeme}, Left: left, Right: right}
+	}
+	plus := func(left, right ast.Expr) ast.Expr { return binary(token.PLUS, "+", left, right) }
+	less := func(left, right ast.Expr) ast.Expr { return binary(token.LESS, "<", left, right) }
+	logical := func(operator token.TokenType, lexeme string, left, right ast.Expr) ast.Expr {
+		return ast.Logical{Operator: token.Token{Type: operator, Lexeme: lexeme}, Left: left, Right: right}
+	}
+	or := logical(token.OR, "or", ast.Literal{Value: true}, ast.Literal{Value: nil})
+	coalesce := logical(token.QUESTION_QUESTION, "??", ast.Literal{Value: true}, ast.Literal{Value: nil})
+	name := token.Token{Type: token.IDENTIFIER, Lexeme: "a"}
+	tests := []struct {
+		expr ast.Expr
+		want string
+	}{
+		{binary(token.STAR, "*", plus(number(1), number(2)), number(3)), "(1 + 2) * 3"},
+		{plus(number(1), plus(number(2), number(3))), "1 + (2 + 3)"},
+		{plus(plus(number(1), number(2)), number(3)), "1 + 2 + 3"},
+		{less(less(number(1), number(2)), number(3)), "(1 < 2) < 3"},
+		{ast.Unary{Operator: token.Token{Type: token.MINUS, Lexeme: "-"}, Right: plus(number(1), number(2))}, "-(1 + 2)"},
+		{logical(token.QUESTION_QUESTION, "??", or, number(1)), "true or nil ?? 1"},
+		{logical(token.OR, "or", coalesce, number(1)), "(true ?? nil) or 1"},
+		{ast.Get{Object: ast.Assign{Name: name, Value: number(1)}, Name: name}, "(a = 1).a"},
+		{plus(ast.Assign{Name: name, Value: number(1)}, number(2)), "(a = 1) + 2"},
+		{ast.Call{Callee: plus(ast.Variable{Name: name}, number(1))}, "(a + 1)()"},
+	}
+	for _, test := range tests {
+		printed := ast.SourcePrinter{}.PrintExpr(test.expr)
+		if printed != test.want {
+			t.Errorf("%s printed as %q, want %q", ast.Dump(test.expr), printed, test.want)
+			continue
+		}
+		if again := removeGroupings(parseExpression(t, printed)); !ast.Equal(again, test.expr) {
+			t.Errorf("%q parses to\n%s\ninstead of\n%s", printed, ast.Dump(again), ast.Dump(test.expr))
+		}
+	}
+}
+
+// removeGroupings returns expr without its groupings, as a tree built by hand has none.
+func removeGroupings(expr ast.Expr) ast.Expr {
+	return ast.Rewrite(expr, func(expr ast.Expr) ast.Expr {
+		if grouping, ok := expr.(ast.Grouping); ok {
+			return grouping.Expr
+		}
+		return expr
+	})
+}
+
+// TestReparse checks that reparsing after an edit gives the statements of a full parse, positions included,
+// and its errors.
+func TestReparse(t *testing.T) {
+	// insert returns the edit inserting text after the first occurrence of after in source.
+	insert := func(source, after, text string) scanner.Edit {
+		return scanner.Edit{Offset: strings.Index(source, after) + len(after), Inserted: text}
+	}
+	// remove returns the edit deleting the first occurrence of text in source.
+	remove := func(source, text string) scanner.Edit {
+		return scanner.Edit{Offset: strings.Index(source, text), Deleted: len(text)}
+	}
+	const statements = "var a = 1;\nprint a - 2;\nvar b = -3;\nprint a + b;\n"
+	const strs = "print \"one\";\nprint \"two\" + \"three\";\nprint 4;\n"
+	const comments = "print 1; // one\n/* two\n */ print 2;\nprint 3; // three\n"
+	tests := []struct {
+		name, source string
+		edit         scanner.Edit
+	}{
+		{"change a number", statements, insert(statements, "print a - 2", "00")},
+		{"add a statement", statements, insert(statements, "var b = -3;", " print b;")},
+		{"add lines", statements, insert(statements, "var a = 1;", "\n\n")},
+		{"split a statement", statements, insert(statements, "print a ", ";\nprint ")},
+		{"merge two statements", statements, remove(statements, ";\nvar b =")},
+		{"merge by deleting a semicolon", statements, remove(statements, ";\nprint a -")},
+		{"break a statement", statements, remove(statements, "1;")},
+		{"fix a statement", "var a = ;\nprint a;\nprint 2;\n", insert("var a = ;\nprint a;\nprint 2;\n", "= ", "1")},
+		{"move an error", "print 1;\nprint 2;\nvar = 3;\nprint 4;\n", insert("print 1;\nprint 2;\nvar = 3;\nprint 4;\n", "1", "00;\n")},
+		{"delete everything", statements, remove(statements, statements)},
+		{"edit the first statement", statements, insert(statements, "var ", "x")},
+		{"edit the last statement", statements, insert(statements, "a + b", " + 1")},
+		{"edit inside a string", strs, insert(strs, `"tw`, "o, t")},
+		{"add a line inside a string", strs, insert(strs, `"tw`, "\n\n")},
+		{"open a string", strs, remove(strs, `o"`)},
+		{"close a string", "print \"a;\nprint 2;\nprint 3;\n", insert("print \"a;\nprint 2;\nprint 3;\n", "a", `"`)},
+		{"edit inside a comment", comments, insert(comments, "// one", " and more")},
+		{"add a line inside a block comment", comments, insert(comments, "/* two", "\n")},
+		{"comment out a statement", comments, insert(comments, "\n/* two\n */ ", "//")},
+		{"end a block comment early", comments, insert(comments, "two", "*/ print 0;")},
+	}
+	config := scanner.Config{Keywords: scanner.Keywords(), EmitComments: true}
+	for _, test := range tests {
+		s := scanner.NewWithConfig(test.source, config)
+		tokens, _ := s.ScanTokens()
+		tree := NewTree(tokens)
+		source, tokens, _ := scanner.Rescan(test.source, tokens, test.edit, config)
+		got := tree.Reparse(tokens)
+		s = scanner.NewWithConfig(source, config)
+		full, _ := s.ScanTokens()
+		want := NewTree(full)
+		if !ast.EqualStmts(got.Statements, want.Statements) {
+			t.Errorf("%s: %q reparsed to\n%s", test.name, source, ast.DiffStmts(want.Statements, got.Statements))
+		} else if !reflect.DeepEqual(got.Statements, want.Statements) {
+			t.Errorf("%s: %q reparsed to statements with other positions:\n%#v\nwant\n%#v", test.name, source, got.Statements, want.Statements)
+		}
+		if !reflect.DeepEqual(got.Errors, want.Errors) {
+			t.Errorf("%s: %q reparsed with the errors %v, want %v", test.name, source, got.Errors, want.Errors)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package parser
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// referenceParser is the recursive descent parser of expressions that the Pratt parser replaced, a method
+// for each level of precedence, kept to check that the Pratt parser parses its grammar the same way.
+// It knows the literals, grouping, the unary, arithmetic, comparison and equality operators, and the
+// logical operators: the grammar before calls, variables and assignment.
+type referenceParser struct {
+	tokens  []token.Token
+	current int
+}
+
+// errReference is the panic of the reference parser on an error, as it only tells whether there is one.
+type errReference struct{}
+
+// parse returns the expression of all the tokens, or false for an error.
+func (p *referenceParser) parse() (expr ast.Expr, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, isError := r.(errReference); !isError {
+				panic(r)
+			}
+			expr, ok = nil, false
+		}
+	}()
+	expr = p.coalesce()
+	return expr, p.peek().Type == token.EOF
+}
+
+// coalesce       → or ("??" or)*
+func (p *referenceParser) coalesce() ast.Expr {
+	return p.logical(p.or, token.QUESTION_QUESTION)
+}
+
+// or             → and ("or" and)*
+func (p *referenceParser) or() ast.Expr {
+	return p.logical(p.and, token.OR)
+}
+
+// and            → equality ("and" equality)*
+func (p *referenceParser) and() ast.Expr {
+	return p.logical(p.equality, token.AND)
+}
+
+func (p *referenceParser) logical(operand func() ast.Expr, operator token.TokenType) ast.Expr {
+	expr := operand()
+	for p.match(operator) {
+		operator := p.previous()
+		right := operand()
+		expr = ast.Logical{Operator: operator, Left: expr, Right: right, Span: expr.SourceSpan().Cover(right.SourceSpan())}
+	}
+	return expr
+}
+
+// equality       → comparison (("==" | "!=") comparison)*
+func (p *referenceParser) equality() ast.Expr {
+	return p.binary(p.comparison, token.EQUAL_EQUAL, token.BANG_EQUAL)
+}
+
+// comparison     → term ((">" | ">=" | "<" | "<=") term) *
+func (p *referenceParser) comparison() ast.Expr {
+	expr := p.term()
+	var operators []token.Token
+	operands := []ast.Expr{expr}
+	for p.match(token.GREATER, token.GREATER_EQUAL, token.LESS, token.LESS_EQUAL) {
+		operators = append(operators, p.previous())
+		operands = append(operands, p.term())
+	}
+	switch len(operators) {
+	case 0:
+		return expr
+	case 1:
+		return ast.Binary{Operator: operators[0], Left: operands[0], Right: operands[1], Span: operands[0].SourceSpan().Cover(operands[1].SourceSpan())}
+	}
+	return ast.Comparison{Operators: operators, Operands: operands, Span: operands[0].SourceSpan().Cover(operands[len(operands)-1].SourceSpan())}
+}
+
+// term           → factor (("+" | "-") factor)*
+func (p *referenceParser) term() ast.Expr {
+	return p.binary(p.factor, token.PLUS, token.MINUS)
+}
+
+// factor         → unary (("*" | "/") unary)*
+func (p *referenceParser) factor() ast.Expr {
+	return p.binary(p.unary, token.STAR, token.SLASH)
+}
+
+func (p *referenceParser) binary(operand func() ast.Expr, operators ...token.TokenType) ast.Expr {
+	expr := operand()
+	for p.match(operators...) {
+		operator := p.previous()
+		right := operand()
+		expr = ast.Binary{Operator: operator, Left: expr, Right: right, Span: expr.SourceSpan().Cover(right.SourceSpan())}
+	}
+	return expr
+}
+
+// unary          → ("-" | "!") unary | primary
+func (p *referenceParser) unary() ast.Expr {
+	if !p.match(token.MINUS, token.BANG) {
+		return p.primary()
+	}
+	operator := p.previous()
+	right := p.unary()
+	return ast.Unary{Operator: operator, Right: right, Span: operator.Span.Cover(right.SourceSpan())}
+}
+
+// primary        → NUMBER | STRING | "true" | "false" | "nil" | "(" expression ")"
+func (p *referenceParser) primary() ast.Expr {
+	switch {
+	case p.match(token.NUMBER, token.STRING):
+		return ast.Literal{Value: p.previous().Literal, Span: p.previous().Span}
+	case p.match(token.TRUE):
+		return ast.Literal{Value: true, Span: p.previous().Span}
+	case p.match(token.FALSE):
+		return ast.Literal{Value: false, Span: p.previous().Span}
+	case p.match(token.NIL):
+		return ast.Literal{Value: nil, Span: p.previous().Span}
+	case p.match(token.LEFT_PAREN):
+		left := p.previous()
+		expr := p.coalesce()
+		if !p.match(token.RIGHT_PAREN) {
+			panic(errReference{})
+		}
+		return ast.Grouping{Expr: expr, Span: left.Span.Cover(p.previous().Span)}
+	}
+	panic(errReference{})
+}
+
+func (p *referenceParser) match(types ...token.TokenType) bool {
+	for _, typ := range types {
+		if p.peek().Type == typ {
+			p.current++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *referenceParser) peek() token.Token {
+	return p.tokens[p.current]
+}
+
+func (p *referenceParser) previous() token.Token {
+	return p.tokens[p.current-1]
+}
+
+// referenceOperands, referenceUnary and referenceBinary are the tokens of the grammar of the reference parser.
+var (
+	referenceOperands = []string{"1", "2.5", `"s"`, "true", "false", "nil"}
+	referenceUnary    = []string{"-", "!"}
+	referenceBinary   = []string{"-", "+", "*", "/", "==", "!=", "<", "<=", ">", ">=", "and", "or", "??"}
+)
+
+// referenceSource returns a random sequence of about n of the tokens of the reference grammar. Most are
+// valid expressions, and the rest have a token out of place. A "(" never follows an operand or a ")", which
+// would be a call, outside the reference grammar.
+func referenceSource(random *rand.Rand, n int) string {
+	var words []string
+	pick := func(from []string) {
+		words = append(words, from[random.Intn(len(from))])
+	}
+	afterOperand, open := false, 0
+	for len(words) < n {
+		switch r := random.Intn(20); {
+		case r == 0:
+			// a token out of place
+			pick([]string{")", "+", "<", "and"})
+			afterOperand = false
+		case afterOperand && r < 4 && open > 0:
+			words = append(words, ")")
+			open--
+		case afterOperand:
+			pick(referenceBinary)
+			afterOperand = false
+		case r < 4:
+			words = append(words, "(")
+			open++
+		case r < 7:
+			pick(referenceUnary)
+		default:
+			pick(referenceOperands)
+			afterOperand = true
+		}
+	}
+	if afterOperand {
+		for ; open > 0; open-- {
+			words = append(words, ")")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// TestPrattMatchesRecursiveDescent parses random sequences of the tokens of the reference grammar with
+// both parsers: they must give the same trees, spans included, and fail on the same sequences.
+func TestPrattMatchesRecursiveDescent(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	valid := 0
+	const sequences = 50000
+	for i := 0; i < sequences; i++ {
+		source := referenceSource(random, 1+random.Intn(15))
+		s := scanner.New(source)
+		tokens, errs := s.ScanTokens()
+		if len(errs) > 0 {
+			t.Fatalf("scanning %q: %v", source, errs)
+		}
+		want, wantOK := (&referenceParser{tokens: tokens}).parse()
+		got, err := New(tokens).ParseExpression()
+		switch {
+		case wantOK != (err == nil):
+			t.Errorf("%s: the recursive descent parser reported %v for an error, the Pratt parser %v", source, !wantOK, err)
+		case wantOK && !reflect.DeepEqual(got, want):
+			t.Errorf("%s: the Pratt parser gave %s, the recursive descent parser %s", source, ast.Printer{}.Print(got), ast.Printer{}.Print(want))
+		case wantOK:
+			valid++
+		}
+	}
+	// the sequences must reach beyond the errors
+	if valid < sequences/4 {
+		t.Errorf("only %d of the %d sequences are valid expressions", valid, sequences)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// profiler records where a run spends its time, through the hooks of the interpreter:
+// the calls of each function, and the nodes run on each line, which only the tree backend reports.
+type profiler struct {
+	functions map[string]*profileEntry
+	lines     map[int]*profileEntry
+	// running are the lines of the nodes being run, the innermost last
+	running []int
+	// calls are the start times of the calls being run, the innermost last
+	calls []time.Time
+	// last is when the time was last counted to the innermost line
+	last time.Time
+}
+
+// profileEntry is what a function or a line took: how many times it ran and for how long.
+// The time of a line is only that of its own nodes, not of the nodes of other lines they run.
+type profileEntry struct {
+	count int
+	time  time.Duration
+}
+
+func newProfiler() *profiler {
+	return &profiler{functions: make(map[string]*profileEntry), lines: make(map[int]*profileEntry)}
+}
+
+func (p *profiler) hooks() *interp.Hooks {
+	return &interp.Hooks{
+		OnEnterNode: func(node interface{}, span token.Span) {
+			p.tick()
+			p.running = append(p.running, span.Line)
+			entry(p.lines, span.Line).count++
+		},
+		OnExitNode: func(node interface{}, span token.Span, value interp.Value, err error) {
+			p.tick()
+			p.running = p.running[:len(p.running)-1]
+		},
+		OnCall: func(function string, arguments []interp.Value, span token.Span) {
+			p.calls = append(p.calls, time.Now())
+			entry(p.functions, function).count++
+		},
+		OnReturn: func(function string, value interp.Value, err error, span token.Span) {
+			start := p.calls[len(p.calls)-1]
+			p.calls = p.calls[:len(p.calls)-1]
+			entry(p.functions, function).time += time.Since(start)
+		},
+	}
+}
+
+// tick counts the time since the last tick to the innermost line being run.
+func (p *profiler) tick() {
+	now := time.Now()
+	if len(p.running) > 0 {
+		p.lines[p.running[len(p.running)-1]].time += now.Sub(p.last)
+	}
+	p.last = now
+}
+
+func entry[K comparable](entries map[K]*profileEntry, key K) *profileEntry {
+	e, ok := entries[key]
+	if !ok {
+		e = &profileEntry{}
+		entries[key] = e
+	}
+	return e
+}
+
+// report writes the functions and then the lines, each sorted from the one that took the most time.
+func (p *profiler) report(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "function\tcalls\ttime\t")
+	for _, name := range sortedKeys(p.functions) {
+		e := p.functions[name]
+		fmt.Fprintf(tw, "%s\t%d\t%v\t\n", name, e.count, e.time)
+	}
+	if len(p.lines) > 0 {
+		fmt.Fprintln(tw, "\t\t\t")
+		fmt.Fprintln(tw, "line\tnodes\ttime\t")
+		for _, line := range sortedKeys(p.lines) {
+			e := p.lines[line]
+			fmt.Fprintf(tw, "%d\t%d\t%v\t\n", line, e.count, e.time)
+		}
+	}
+	tw.Flush()
+}
+
+// sortedKeys returns the keys of entries from the one that took the most time.
+func sortedKeys[K comparable](entries map[K]*profileEntry) []K {
+	keys := make([]K, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return entries[keys[i]].time > entries[keys[j]].time
+	})
+	return keys
+}
@@ -1,308 +0,0 @@
-package main
-
-import (
-	"fmt"
-	"strconv"
-)
-
-//go:generate stringer -type TokenType
-type TokenType int
-
-const (
-	// Single-character tokens.
-	LEFT_PAREN TokenType = iota
-	RIGHT_PAREN
-	LEFT_BRACE
-	RIGHT_BRACE
-	COMMA
-	DOT
-	MINUS
-	PLUS
-	SEMICOLON
-	SLASH
-	STAR
-
-	// One or two character tokens.
-	BANG
-	BANG_EQUAL
-	EQUAL
-	EQUAL_EQUAL
-	GREATER
-	GREATER_EQUAL
-	LESS
-	LESS_EQUAL
-
-	// Literals
-	IDENTIFIER
-	STRING
-	NUMBER
-
-	// Keywords
-	AND
-	CLASS
-	ELSE
-	FALSE
-	FUN
-	FOR
-	IF
-	NIL
-	OR
-	PRINT
-	RETURN
-	SUPER
-	THIS
-	TRUE
-	VAR
-	WHILE
-
-	// signals when we parsed all tokens
-	EOF
-)
-
-var keywords = map[string]TokenType{
-	"and":    AND,
-	"class":  CLASS,
-	"else":   ELSE,
-	"false":  FALSE,
-	"fun":    FUN,
-	"for":    FOR,
-	"if":     IF,
-	"nil":    NIL,
-	"or":     OR,
-	"print":  PRINT,
-	"return": RETURN,
-	"super":  SUPER,
-	"this":   THIS,
-	"true":   TRUE,
-	"var":    VAR,
-	"while":  WHILE,
-}
-
-type Token struct {
-	Type    TokenType
-	Lexeme  string
-	Literal interface{}
-	Line    int
-}
-
-func NewToken(typ TokenType, lexeme string, literal interface{}, line int) Token {
-	return Token{
-		Type:    typ,
-		Lexeme:  lexeme,
-		Literal: literal,
-		Line:    line,
-	}
-}
-
-func (t Token) String() string {
-	return fmt.Sprintf("%s %s %v", t.Type, t.Lexeme, t.Literal)
-}
-
-type Scanner struct {
-	source  string
-	start   int
-	current int // points at the character currently being considered
-	line    int
-	tokens  []Token
-	errors  []error
-}
-
-func NewScanner(source string) Scanner {
-	return Scanner{source: source}
-}
-
-func (s *Scanner) error(message string) {
-	s.errors = append(s.errors, fmt.Errorf("Line: %d, %s", s.line, message))
-}
-
-func (s *Scanner) ScanTokens() ([]Token, []error) {
-	for !s.isAtEnd() {
-		s.start = s.current
-		s.scanToken()
-	}
-	s.tokens = append(s.tokens, Token{
-		Type: EOF,
-		Line: s.line,
-	})
-	return s.tokens, s.errors
-}
-
-func (s *Scanner) scanToken() {
-	c := s.advance()
-	switch c {
-	// lexems of length 1
-	case '(':
-		s.addToken(LEFT_PAREN)
-	case ')':
-		s.addToken(RIGHT_PAREN)
-	case '{':
-		s.addToken(LEFT_BRACE)
-	case '}':
-		s.addToken(RIGHT_BRACE)
-	case ',':
-		s.addToken(COMMA)
-	case '.':
-		s.addToken(DOT)
-	case '-':
-		s.addToken(MINUS)
-	case '+':
-		s.addToken(PLUS)
-	case ';':
-		s.addToken(SEMICOLON)
-	case '*':
-		s.addToken(STAR)
-	// lexems of length 1 or 2
-	case '!':
-		if s.match('=') {
-			s.addToken(BANG_EQUAL)
-		} else {
-			s.addToken(BANG)
-		}
-	case '=':
-		if s.match('=') {
-			s.addToken(EQUAL_EQUAL)
-		} else {
-			s.addToken(EQUAL)
-		}
-	case '<':
-		if s.match('=') {
-			s.addToken(LESS_EQUAL)
-		} else {
-			s.addToken(LESS)
-		}
-	case '>':
-		if s.match('=') {
-			s.addToken(GREATER_EQUAL)
-		} else {
-			s.addToken(GREATER)
-		}
-	// handle comment or division:
-	case '/':
-		if s.match('/') {
-			for s.peek() != '\n' && !s.isAtEnd() {
-				s.advance()
-			}
-		} else {
-			s.addToken(SLASH)
-		}
-	// ignore white space
-	case ' ', '\t', '\r':
-	case '\n':
-		s.line++
-	// handle string literals
-	case '"':
-		s.string()
-	default:
-		if isDigit(c) {
-			s.number()
-		} else if isAlpha(c) {
-			s.identifier()
-		} else {
-			s.error("Unexpected character.")
-		}
-	}
-}
-
-func (s *Scanner) identifier() {
-	for isAlphaNumeric(s.peek()) {
-		s.advance()
-	}
-	if typ, ok := keywords[s.source[s.start:s.current]]; ok {
-		s.addToken(typ)
-	} else {
-		s.addToken(IDENTIFIER)
-	}
-}
-
-func (s *Scanner) string() {
-	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
-		}
-		s.advance()
-	}
-	if s.isAtEnd() {
-		s.error("Unterminated string.")
-		return
-	}
-	s.advance() // we consume the second quote
-	s.addTokenLiteral(STRING, s.source[s.start+1:s.current-1])
-}
-
-func (s *Scanner) number() {
-	for isDigit(s.peek()) {
-		s.advance()
-	}
-	if s.peek() == '.' && isDigit(s.peekNext()) {
-		s.advance()
-		for isDigit(s.peek()) {
-			s.advance()
-		}
-	}
-	n, _ := strconv.ParseFloat(s.source[s.start:s.current], 64)
-	s.addTokenLiteral(NUMBER, n)
-}
-
-func (s *Scanner) isAtEnd() bool {
-	return s.current >= len(s.source)
-}
-
-func (s *Scanner) advance() byte {
-	c := s.source[s.current]
-	s.current++
-	return c
-}
-
-func (s *Scanner) peek() byte {
-	if s.isAtEnd() {
-		return '\x00'
-	}
-	return s.source[s.current]
-}
-
-func (s *Scanner) peekNext() byte {
-	if s.current+1 >= len(s.source) {
-		return '\x00'
-	}
-	return s.source[s.current+1]
-}
-
-func (s *Scanner) match(expected byte) bool {
-	if s.isAtEnd() {
-		return false
-	}
-	if s.source[s.current] != expected {
-		return false
-	}
-	s.advance()
-	return true
-}
-
-func (s *Scanner) addToken(typ TokenType) {
-	s.addTokenLiteral(typ, nil)
-}
-
-func (s *Scanner) addTokenLiteral(typ TokenType, literal interface{}) {
-	lexeme := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, NewToken(typ, lexeme, literal, s.line))
-}
-
-func isAlphaNumeric(c byte) bool {
-	return isAlpha(c) || isDigit(c)
-}
-
-func isAlpha(c byte) bool {
-	if c >= 'a' && c <= 'z' ||
-		c >= 'A' && c <= 'Z' ||
-		c == '_' {
-		return true
-	}
-	return false
-}
-
-func isDigit(c byte) bool {
-	if c >= '0' && c <= '9' {
-		return true
-	}
-	return false
-}
@@ -0,0 +1,21 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// ScanSource scans source and returns its tokens and errors, for fuzzing.
+// It scans with the keywords of the extensions too, to reach all of the parser.
+// It never panics: a panic in the scanner is returned as an error.
+func ScanSource(source []byte) (tokens []token.Token, errs []error) {
+	defer func() {
+		if r := recover(); r != nil {
+			tokens = nil
+			errs = append(errs, fmt.Errorf("scanner panic: %v", r))
+		}
+	}()
+	s := NewWithConfig(string(source), Config{Keywords: Keywords()})
+	return s.ScanTokens()
+}
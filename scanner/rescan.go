@@ -0,0 +1,98 @@
+package scanner
+
+import (
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// Edit describes a change to a source text: the Deleted bytes at Offset are replaced by Inserted.
+type Edit struct {
+	Offset   int
+	Deleted  int
+	Inserted string
+}
+
+// Apply returns source with the edit applied.
+func (e Edit) Apply(source string) string {
+	return source[:e.Offset] + e.Inserted + source[e.Offset+e.Deleted:]
+}
+
+// Rescan updates tokens, the result of scanning source with config, for an edit of source.
+// It scans again from the last token not affected by the edit up to the first token after the edit that
+// starts where an old token started, and reuses the old tokens from there on with their positions shifted.
+// It returns the edited source, its tokens and only the errors found in the part that was scanned again.
+func Rescan(source string, tokens []token.Token, edit Edit, config Config) (string, []token.Token, []error) {
+	newSource := edit.Apply(source)
+	delta := len(edit.Inserted) - edit.Deleted
+	oldEnd := edit.Offset + edit.Deleted
+	newEnd := edit.Offset + len(edit.Inserted)
+
+	// Tokens ending far enough before the edit are not affected by it: the scanner looks at most two characters
+	// past the end of a lexeme, as in 1.5. We scan again from the end of the last of them, where the scanner
+	// is between tokens, so that any comment or white space before the edit is scanned again too.
+	first := 0
+	for first < len(tokens)-1 && tokens[first].EndOffset+2 <= edit.Offset {
+		first++
+	}
+	s := NewWithConfig(newSource, config)
+	if first > 0 {
+		last := tokens[first-1]
+		s.current = last.EndOffset
+		s.line = last.Line
+		s.lineStart = last.StartOffset - last.Col + 1
+		if i := strings.LastIndexByte(last.Lexeme, '\n'); i >= 0 {
+			s.line += strings.Count(last.Lexeme, "\n")
+			s.lineStart = last.StartOffset + i + 1
+		}
+	}
+
+	next := first
+	var resynced token.Token
+	for {
+		scanned := len(s.tokens)
+		if s.isAtEnd() {
+			s.addEOF()
+		} else {
+			s.scanNext()
+		}
+		if len(s.tokens) == scanned {
+			continue
+		}
+		tok := s.tokens[len(s.tokens)-1]
+		if tok.StartOffset < newEnd {
+			continue
+		}
+		for next < len(tokens) && (tokens[next].StartOffset < oldEnd || tokens[next].StartOffset+delta < tok.StartOffset) {
+			next++
+		}
+		if next < len(tokens) && tokens[next].StartOffset+delta == tok.StartOffset {
+			// From here on the text, and so the tokens, are the same as before the edit.
+			resynced = tok
+			s.tokens = s.tokens[:len(s.tokens)-1]
+			break
+		}
+		if tok.Type == token.EOF {
+			// not reached: the old EOF always lines up with the new one
+			break
+		}
+	}
+
+	result := make([]token.Token, 0, first+len(s.tokens)+len(tokens)-next)
+	result = append(result, tokens[:first]...)
+	result = append(result, s.tokens...)
+	if next < len(tokens) {
+		lineDelta := resynced.Line - tokens[next].Line
+		colDelta := resynced.Col - tokens[next].Col
+		for _, tok := range tokens[next:] {
+			if tok.Line == tokens[next].Line {
+				tok.Col += colDelta
+			}
+			tok.Line += lineDelta
+			tok.StartOffset += delta
+			tok.EndOffset += delta
+			result = append(result, tok)
+		}
+	}
+	return newSource, result, s.errors
+}
@@ -0,0 +1,474 @@
+package scanner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gadumitrachioaiei/go-lox/intern"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// classicKeywords are the reserved words of the language as defined in the book.
+var classicKeywords = map[string]token.TokenType{
+	"and":    token.AND,
+	"class":  token.CLASS,
+	"else":   token.ELSE,
+	"false":  token.FALSE,
+	"fun":    token.FUN,
+	"for":    token.FOR,
+	"if":     token.IF,
+	"nil":    token.NIL,
+	"or":     token.OR,
+	"print":  token.PRINT,
+	"return": token.RETURN,
+	"super":  token.SUPER,
+	"this":   token.THIS,
+	"true":   token.TRUE,
+	"var":    token.VAR,
+	"while":  token.WHILE,
+}
+
+// ClassicKeywords returns a copy of the keyword table of classic Lox,
+// which embedders can extend, for example with aliases like "fn" for FUN, and pass in a Config.
+func ClassicKeywords() map[string]token.TokenType {
+	keywords := make(map[string]token.TokenType, len(classicKeywords))
+	for word, typ := range classicKeywords {
+		keywords[word] = typ
+	}
+	return keywords
+}
+
+// extensionKeywords are the reserved words this implementation adds to the book.
+var extensionKeywords = map[string]token.TokenType{
+	// spawn runs a call in a new task
+	"spawn": token.SPAWN,
+}
+
+// Keywords returns a copy of the keyword table of this implementation: the classic keywords and
+// those of its extensions, like spawn.
+func Keywords() map[string]token.TokenType {
+	keywords := ClassicKeywords()
+	for word, typ := range extensionKeywords {
+		keywords[word] = typ
+	}
+	return keywords
+}
+
+// Config configures a Scanner. The zero value scans classic Lox and discards comments.
+type Config struct {
+	// Keywords maps the reserved words to their token types, any other word is an identifier.
+	// When nil, the classic Lox keywords are used, so that code of the book that names a variable
+	// spawn still scans: the extensions, like spawn, need Keywords().
+	Keywords map[string]token.TokenType
+	// EmitComments makes the scanner produce COMMENT tokens instead of discarding comments.
+	// The literal of a comment token is its text without the comment delimiters.
+	EmitComments bool
+	// Interner interns the lexemes of identifiers and strings, and the values of strings, so that every
+	// occurrence of a name or a string constant shares one copy. Scanners can share a table.
+	// When nil, each scanner has its own.
+	Interner *intern.Table
+}
+
+// Error is a scan error about the source text in its span.
+type Error struct {
+	token.Span
+	Message string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("Line: %d, Column: %d, %s", e.Line, e.Col, e.Message)
+}
+
+type Scanner struct {
+	config    Config
+	source    string
+	start     int
+	current   int // points at the character currently being considered
+	line      int
+	lineStart int // offset of the first character of the current line
+	// position of the lexeme being scanned, which can span lines
+	startLine, startColumn int
+	tokens                 []token.Token
+	errors                 []error
+}
+
+// New returns a scanner of classic Lox, with the zero Config.
+func New(source string) Scanner {
+	return NewWithConfig(source, Config{})
+}
+
+func NewWithConfig(source string, config Config) Scanner {
+	if config.Keywords == nil {
+		config.Keywords = classicKeywords
+	}
+	if config.Interner == nil {
+		config.Interner = &intern.Table{}
+	}
+	return Scanner{config: config, source: source, line: 1}
+}
+
+func (s *Scanner) error(message string) {
+	s.errors = append(s.errors, Error{
+		Span: token.Span{
+			StartOffset: s.start,
+			EndOffset:   s.current,
+			Line:        s.startLine,
+			Col:         s.startColumn,
+		},
+		Message: message,
+	})
+}
+
+func (s *Scanner) ScanTokens() ([]token.Token, []error) {
+	for !s.isAtEnd() {
+		s.scanNext()
+	}
+	s.addEOF()
+	return s.tokens, s.errors
+}
+
+// scanNext scans the lexeme starting at the current character, which adds at most one token.
+func (s *Scanner) scanNext() {
+	s.start = s.current
+	s.startLine = s.line
+	s.startColumn = s.start - s.lineStart + 1
+	s.scanToken()
+}
+
+func (s *Scanner) addEOF() {
+	s.tokens = append(s.tokens, token.Token{
+		Type: token.EOF,
+		Span: token.Span{
+			StartOffset: s.current,
+			EndOffset:   s.current,
+			Line:        s.line,
+			Col:         s.current - s.lineStart + 1,
+		},
+	})
+}
+
+// newline records that the character just consumed ended a line.
+func (s *Scanner) newline() {
+	s.line++
+	s.lineStart = s.current
+}
+
+func (s *Scanner) scanToken() {
+	c := s.advance()
+	switch c {
+	// lexems of length 1
+	case '(':
+		s.addToken(token.LEFT_PAREN)
+	case ')':
+		s.addToken(token.RIGHT_PAREN)
+	case '{':
+		s.addToken(token.LEFT_BRACE)
+	case '}':
+		s.addToken(token.RIGHT_BRACE)
+	case ',':
+		s.addToken(token.COMMA)
+	case '.':
+		s.addToken(token.DOT)
+	case '-':
+		s.addToken(token.MINUS)
+	case '+':
+		s.addToken(token.PLUS)
+	case ';':
+		s.addToken(token.SEMICOLON)
+	case '*':
+		s.addToken(token.STAR)
+	// lexems of length 1 or 2
+	case '!':
+		if s.match('=') {
+			s.addToken(token.BANG_EQUAL)
+		} else {
+			s.addToken(token.BANG)
+		}
+	case '=':
+		if s.match('=') {
+			s.addToken(token.EQUAL_EQUAL)
+		} else {
+			s.addToken(token.EQUAL)
+		}
+	case '<':
+		if s.match('=') {
+			s.addToken(token.LESS_EQUAL)
+		} else {
+			s.addToken(token.LESS)
+		}
+	case '>':
+		if s.match('=') {
+			s.addToken(token.GREATER_EQUAL)
+		} else {
+			s.addToken(token.GREATER)
+		}
+	case '?':
+		if s.match('?') {
+			s.addToken(token.QUESTION_QUESTION)
+		} else {
+			s.unexpected()
+		}
+	// handle comment or division:
+	case '/':
+		if s.match('/') {
+			for s.peek() != '\n' && !s.isAtEnd() {
+				s.advance()
+			}
+			s.comment(s.source[s.start+2 : s.current])
+		} else if s.match('*') {
+			s.blockComment()
+		} else {
+			s.addToken(token.SLASH)
+		}
+	// ignore white space
+	case ' ', '\t', '\r':
+	case '\n':
+		s.newline()
+	// handle string literals
+	case '"':
+		s.string()
+	default:
+		if isDigit(c) {
+			s.number()
+		} else if isAlpha(c) {
+			s.identifier()
+		} else {
+			s.unexpected()
+		}
+	}
+}
+
+// unexpected consumes a run of characters that cannot start a token and reports them in a single error.
+func (s *Scanner) unexpected() {
+	for !s.isAtEnd() && !startsToken(s.peek()) {
+		s.advance()
+	}
+	snippet := s.source[s.start:s.current]
+	if utf8.RuneCountInString(snippet) == 1 {
+		s.error(fmt.Sprintf("Unexpected character %q.", snippet))
+	} else {
+		s.error(fmt.Sprintf("Unexpected characters %q.", snippet))
+	}
+}
+
+// blockComment scans a comment between /* and */, which can span lines but does not nest.
+func (s *Scanner) blockComment() {
+	for !(s.peek() == '*' && s.peekNext() == '/') {
+		if s.isAtEnd() {
+			s.error("Unterminated block comment.")
+			return
+		}
+		if s.advance() == '\n' {
+			s.newline()
+		}
+	}
+	s.advance()
+	s.advance()
+	s.comment(s.source[s.start+2 : s.current-2])
+}
+
+func (s *Scanner) comment(text string) {
+	if s.config.EmitComments {
+		s.addTokenLiteral(token.COMMENT, text)
+	}
+}
+
+func (s *Scanner) identifier() {
+	for isAlphaNumeric(s.peek()) {
+		s.advance()
+	}
+	if typ, ok := s.config.Keywords[s.source[s.start:s.current]]; ok {
+		s.addToken(typ)
+	} else {
+		s.addToken(token.IDENTIFIER)
+		s.internLexeme()
+	}
+}
+
+func (s *Scanner) string() {
+	for s.peek() != '"' && !s.isAtEnd() {
+		if s.advance() == '\n' {
+			s.newline()
+		}
+	}
+	if s.isAtEnd() {
+		s.error("Unterminated string.")
+		return
+	}
+	s.advance() // we consume the second quote
+	s.addTokenLiteral(token.STRING, s.config.Interner.Intern(s.source[s.start+1:s.current-1]))
+	s.internLexeme()
+}
+
+// number scans a number literal.
+// Decimal literals may have a fraction and an exponent, like 2.5e-3, hexadecimal (0xFF) and binary (0b1010)
+// literals are integers, and digits may be separated by single underscores, like 1_000_000.
+// A literal with a fraction or an exponent is a float64, otherwise it is an int64.
+func (s *Scanner) number() {
+	s.current = s.start
+	if s.peek() == '0' && strings.IndexByte("xXbB", s.peekNext()) >= 0 {
+		base, isValid := 16, isHexDigit
+		if p := s.peekNext(); p == 'b' || p == 'B' {
+			base, isValid = 2, isBinaryDigit
+		}
+		s.advance()
+		s.advance()
+		digits, ok := s.digits(isValid)
+		if !ok || isAlphaNumeric(s.peek()) {
+			s.malformedNumber()
+			return
+		}
+		n, err := strconv.ParseInt(digits, base, 64)
+		if err != nil {
+			s.error("Integer literal out of range.")
+			return
+		}
+		s.addTokenLiteral(token.NUMBER, n)
+		return
+	}
+	literal, ok := s.digits(isDigit)
+	isFloat := false
+	if s.peek() == '.' && isDigit(s.peekNext()) {
+		s.advance()
+		fraction, fractionOk := s.digits(isDigit)
+		literal, ok, isFloat = literal+"."+fraction, ok && fractionOk, true
+	}
+	if c := s.peek(); c == 'e' || c == 'E' {
+		s.advance()
+		sign := ""
+		if c := s.peek(); c == '+' || c == '-' {
+			sign = string(s.advance())
+		}
+		exponent, exponentOk := s.digits(isDigit)
+		literal, ok, isFloat = literal+"e"+sign+exponent, ok && exponentOk, true
+	}
+	if !ok || isAlphaNumeric(s.peek()) {
+		s.malformedNumber()
+		return
+	}
+	if isFloat {
+		n, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			s.error("Float literal out of range.")
+			return
+		}
+		s.addTokenLiteral(token.NUMBER, n)
+		return
+	}
+	n, err := strconv.ParseInt(literal, 10, 64)
+	if err != nil {
+		s.error("Integer literal out of range.")
+		return
+	}
+	s.addTokenLiteral(token.NUMBER, n)
+}
+
+// digits consumes a run of digits accepted by isValid, together with any underscores,
+// and returns the digits without the underscores.
+// It reports false if the run is empty or an underscore does not sit between two digits.
+func (s *Scanner) digits(isValid func(byte) bool) (string, bool) {
+	start := s.current
+	for isValid(s.peek()) || s.peek() == '_' {
+		s.advance()
+	}
+	run := s.source[start:s.current]
+	if run == "" || run[0] == '_' || run[len(run)-1] == '_' || strings.Contains(run, "__") {
+		return run, false
+	}
+	return strings.ReplaceAll(run, "_", ""), true
+}
+
+// malformedNumber consumes the rest of a bad number literal and reports it.
+func (s *Scanner) malformedNumber() {
+	for isAlphaNumeric(s.peek()) {
+		s.advance()
+	}
+	s.error(fmt.Sprintf("Malformed number literal '%s'.", s.source[s.start:s.current]))
+}
+
+func (s *Scanner) isAtEnd() bool {
+	return s.current >= len(s.source)
+}
+
+func (s *Scanner) advance() byte {
+	c := s.source[s.current]
+	s.current++
+	return c
+}
+
+func (s *Scanner) peek() byte {
+	if s.isAtEnd() {
+		return '\x00'
+	}
+	return s.source[s.current]
+}
+
+func (s *Scanner) peekNext() byte {
+	if s.current+1 >= len(s.source) {
+		return '\x00'
+	}
+	return s.source[s.current+1]
+}
+
+func (s *Scanner) match(expected byte) bool {
+	if s.isAtEnd() {
+		return false
+	}
+	if s.source[s.current] != expected {
+		return false
+	}
+	s.advance()
+	return true
+}
+
+func (s *Scanner) addToken(typ token.TokenType) {
+	s.addTokenLiteral(typ, nil)
+}
+
+func (s *Scanner) addTokenLiteral(typ token.TokenType, literal interface{}) {
+	tok := token.NewToken(typ, s.source[s.start:s.current], literal, s.startLine)
+	tok.Col = s.startColumn
+	tok.StartOffset, tok.EndOffset = s.start, s.current
+	s.tokens = append(s.tokens, tok)
+}
+
+// internLexeme replaces the lexeme of the last token by its interned copy.
+func (s *Scanner) internLexeme() {
+	tok := &s.tokens[len(s.tokens)-1]
+	tok.Lexeme = s.config.Interner.Intern(tok.Lexeme)
+}
+
+func isAlphaNumeric(c byte) bool {
+	return isAlpha(c) || isDigit(c)
+}
+
+func isAlpha(c byte) bool {
+	if c >= 'a' && c <= 'z' ||
+		c >= 'A' && c <= 'Z' ||
+		c == '_' {
+		return true
+	}
+	return false
+}
+
+func isDigit(c byte) bool {
+	if c >= '0' && c <= '9' {
+		return true
+	}
+	return false
+}
+
+// startsToken reports whether c can be the first character of a token, white space or a comment.
+func startsToken(c byte) bool {
+	return isAlphaNumeric(c) || strings.IndexByte("(){},.-+;*!=<>/?\" \t\r\n", c) >= 0
+}
+
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func isBinaryDigit(c byte) bool {
+	return c == '0' || c == '1'
+}
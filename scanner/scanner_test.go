@@ -0,0 +1,266 @@
+package scanner_test
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// FuzzScan checks that the scanner does not panic, and that its tokens are the text of their spans, in
+// order, ending with EOF.
+func FuzzScan(f *testing.F) {
+	for _, seed := range parser.FuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, source []byte) {
+		tokens, errs := scanner.ScanSource(source)
+		for _, err := range errs {
+			if strings.Contains(err.Error(), "scanner panic") {
+				t.Fatal(err)
+			}
+		}
+		if len(tokens) == 0 || tokens[len(tokens)-1].Type != token.EOF {
+			t.Fatalf("the tokens %v do not end with EOF", tokens)
+		}
+		end := 0
+		for _, tok := range tokens[:len(tokens)-1] {
+			if tok.StartOffset < end || tok.EndOffset > len(source) || tok.StartOffset > tok.EndOffset {
+				t.Fatalf("the span %+v of %v is out of order or out of the source", tok.Span, tok)
+			}
+			if text := tok.Text(string(source)); tok.Lexeme != text {
+				t.Fatalf("the lexeme %q of %v is not its text %q", tok.Lexeme, tok, text)
+			}
+			end = tok.EndOffset
+		}
+	})
+}
+
+// FuzzRescan checks that rescanning after an edit gives the tokens and errors of scanning the edited
+// source again.
+func FuzzRescan(f *testing.F) {
+	for _, seed := range parser.FuzzSeeds {
+		f.Add([]byte(seed), uint(len(seed)/2), uint(1), "\"")
+	}
+	f.Fuzz(func(t *testing.T, source []byte, offset, deleted uint, inserted string) {
+		edit := scanner.Edit{Offset: int(offset % uint(len(source)+1)), Inserted: inserted}
+		edit.Deleted = int(deleted % uint(len(source)-edit.Offset+1))
+		config := scanner.Config{Keywords: scanner.Keywords(), EmitComments: offset%2 == 0}
+		s := scanner.NewWithConfig(string(source), config)
+		tokens, errs := s.ScanTokens()
+		checkRescan(t, string(source), tokens, errs, edit, config)
+	})
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		source string
+		types  []token.TokenType
+	}{
+		{"", nil},
+		{"print 1;", []token.TokenType{token.PRINT, token.NUMBER, token.SEMICOLON}},
+		{"a ?? b", []token.TokenType{token.IDENTIFIER, token.QUESTION_QUESTION, token.IDENTIFIER}},
+		{"spawn f();", []token.TokenType{token.SPAWN, token.IDENTIFIER, token.LEFT_PAREN, token.RIGHT_PAREN, token.SEMICOLON}},
+		{"1 // comment\n2", []token.TokenType{token.NUMBER, token.NUMBER}},
+	}
+	for _, test := range tests {
+		tokens, errs := scanner.ScanSource([]byte(test.source))
+		if len(errs) > 0 {
+			t.Errorf("%q: %v", test.source, errs)
+			continue
+		}
+		var types []token.TokenType
+		for _, tok := range tokens[:len(tokens)-1] {
+			types = append(types, tok.Type)
+		}
+		if !equalTypes(types, test.types) {
+			t.Errorf("%q scanned as %v, want %v", test.source, types, test.types)
+		}
+	}
+
+	// With EmitComments, the comments are tokens of their own, with their text as their literal.
+	const source = "1 // line\n/* block\n  comment */ 2 /**/ //"
+	comments := []struct {
+		lexeme, text string
+		line, col    int
+	}{
+		{"// line", " line", 1, 3},
+		{"/* block\n  comment */", " block\n  comment ", 2, 1},
+		{"/**/", "", 3, 16},
+		{"//", "", 3, 21},
+	}
+	s := scanner.NewWithConfig(source, scanner.Config{EmitComments: true})
+	tokens, errs := s.ScanTokens()
+	if len(errs) > 0 {
+		t.Fatalf("%q: %v", source, errs)
+	}
+	var types []token.TokenType
+	var emitted []token.Token
+	for _, tok := range tokens {
+		types = append(types, tok.Type)
+		if tok.Type == token.COMMENT {
+			emitted = append(emitted, tok)
+		}
+	}
+	want := []token.TokenType{token.NUMBER, token.COMMENT, token.COMMENT, token.NUMBER, token.COMMENT, token.COMMENT, token.EOF}
+	if !equalTypes(types, want) {
+		t.Fatalf("%q scanned with EmitComments as %v, want %v", source, types, want)
+	}
+	for i, comment := range comments {
+		tok := emitted[i]
+		if tok.Lexeme != comment.lexeme || tok.Literal != comment.text || tok.Line != comment.line || tok.Col != comment.col {
+			t.Errorf("the comment %q scanned as %q with the text %q at %d:%d, want the text %q at %d:%d",
+				comment.lexeme, tok.Lexeme, tok.Literal, tok.Line, tok.Col, comment.text, comment.line, comment.col)
+		}
+	}
+	s = scanner.New(source)
+	tokens, errs = s.ScanTokens()
+	if len(errs) > 0 || len(tokens) != 3 || tokens[0].Type != token.NUMBER || tokens[1].Type != token.NUMBER || tokens[1].Line != 3 {
+		t.Errorf("%q scanned without EmitComments as %v with %v, want the numbers alone", source, tokens, errs)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		name   string
+		config scanner.Config
+		want   token.TokenType
+	}{
+		{"the zero Config", scanner.Config{}, token.IDENTIFIER},
+		{"ClassicKeywords", scanner.Config{Keywords: scanner.ClassicKeywords()}, token.IDENTIFIER},
+		{"Keywords", scanner.Config{Keywords: scanner.Keywords()}, token.SPAWN},
+	}
+	for _, test := range tests {
+		s := scanner.NewWithConfig("spawn class", test.config)
+		tokens, errs := s.ScanTokens()
+		if len(errs) > 0 {
+			t.Fatalf("%s: %v", test.name, errs)
+		}
+		if tokens[0].Type != test.want || tokens[1].Type != token.CLASS {
+			t.Errorf("with %s, spawn class scanned as %v %v, want %v CLASS", test.name, tokens[0].Type, tokens[1].Type, test.want)
+		}
+	}
+}
+
+func TestScanNumbers(t *testing.T) {
+	tests := []struct {
+		source  string
+		literal interface{}
+	}{
+		{"42", int64(42)},
+		{"1_000", int64(1000)},
+		{"0xFF", int64(255)},
+		{"0b101", int64(5)},
+		{"2.5", 2.5},
+		{"1e3", 1000.0},
+		{"2.5e-1", 0.25},
+	}
+	for _, test := range tests {
+		tokens, errs := scanner.ScanSource([]byte(test.source))
+		if len(errs) > 0 || tokens[0].Type != token.NUMBER || tokens[0].Literal != test.literal {
+			t.Errorf("%q scanned as %v with %v, want the number %v", test.source, tokens, errs, test.literal)
+		}
+	}
+	for _, source := range []string{"1__2", "0x", "1e+", "9223372036854775808", "1_"} {
+		if _, errs := scanner.ScanSource([]byte(source)); len(errs) == 0 {
+			t.Errorf("%q scanned without an error", source)
+		}
+	}
+}
+
+func equalTypes(a, b []token.TokenType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// rescanPieces are the texts the random edits of TestRescan insert and build their sources of: tokens,
+// the starts and ends of strings and comments, and text that does not scan.
+var rescanPieces = []string{
+	"print", "var", "a", "b1", "=", "==", "!", "!=", "<", "<=", "/", "*", "?", "??", ";", "(", ")", " ", "\n",
+	"1", "2.5", ".", "0x1F", "1e3", "1_000", "1__0", "0b", "\"", "\"s\"", "\"a\nb\"", "//", "// c\n", "/*",
+	"*/", "/* c\n */", "@", "#", "é",
+}
+
+func randomText(r *rand.Rand, pieces int) string {
+	var text strings.Builder
+	for i := 0; i < pieces; i++ {
+		text.WriteString(rescanPieces[r.Intn(len(rescanPieces))])
+	}
+	return text.String()
+}
+
+func TestRescan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		config := scanner.Config{Keywords: scanner.Keywords(), EmitComments: i%2 == 1}
+		source := randomText(r, r.Intn(30))
+		s := scanner.NewWithConfig(source, config)
+		tokens, errs := s.ScanTokens()
+		// each edit applies to the result of the one before it, as in an editor
+		for j := 0; j < 10 && !t.Failed(); j++ {
+			edit := scanner.Edit{Offset: r.Intn(len(source) + 1), Inserted: randomText(r, r.Intn(3))}
+			edit.Deleted = r.Intn(len(source) - edit.Offset + 1)
+			source, tokens, errs = checkRescan(t, source, tokens, errs, edit, config)
+		}
+	}
+}
+
+// checkRescan checks that Rescan of tokens, scanned from source with errs, gives the tokens of a full scan
+// of the edited source, and that its errors together with the old ones it keeps are the errors of the full
+// scan. It returns the edited source and the tokens and errors of the full scan.
+func checkRescan(t *testing.T, source string, tokens []token.Token, errs []error, edit scanner.Edit, config scanner.Config) (string, []token.Token, []error) {
+	t.Helper()
+	newSource, got, gotErrs := scanner.Rescan(source, tokens, edit, config)
+	s := scanner.NewWithConfig(newSource, config)
+	want, wantErrs := s.ScanTokens()
+	if newSource != edit.Apply(source) {
+		t.Fatalf("%q with %+v: Rescan returned the source %q", source, edit, newSource)
+	}
+	for i := 0; i < len(got) || i < len(want); i++ {
+		if i >= len(got) || i >= len(want) || got[i].Type != want[i].Type || got[i].Lexeme != want[i].Lexeme || got[i].Span != want[i].Span {
+			t.Fatalf("%q with %+v: Rescan returned\n%v\nwant\n%v", source, edit, got, want)
+		}
+	}
+
+	// Each error of the full scan is one that Rescan found, or an old one before the edit, or one after it
+	// that moved with the text.
+	delta := len(edit.Inserted) - edit.Deleted
+	kept := append([]error(nil), gotErrs...)
+	for _, err := range wantErrs {
+		want := err.(scanner.Error)
+		found := false
+		for i, err := range kept {
+			if err.(scanner.Error) == want {
+				kept = append(kept[:i], kept[i+1:]...)
+				found = true
+				break
+			}
+		}
+		for i := 0; i < len(errs) && !found; i++ {
+			old := errs[i].(scanner.Error)
+			moved := old.StartOffset >= edit.Offset+edit.Deleted && old.StartOffset+delta == want.StartOffset &&
+				old.EndOffset+delta == want.EndOffset && old.Message == want.Message
+			if old == want && old.EndOffset <= edit.Offset || moved {
+				errs = append(errs[:i:i], errs[i+1:]...)
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("%q with %+v: Rescan missed the error %v, it found %v", source, edit, want, gotErrs)
+		}
+	}
+	if len(kept) > 0 {
+		t.Fatalf("%q with %+v: Rescan found the errors %v, which a full scan does not", source, edit, kept)
+	}
+	return newSource, want, wantErrs
+}
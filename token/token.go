@@ -0,0 +1,113 @@
+package token
+
+import "fmt"
+
+//go:generate stringer -type TokenType
+type TokenType int
+
+const (
+	// Single-character tokens.
+	LEFT_PAREN TokenType = iota
+	RIGHT_PAREN
+	LEFT_BRACE
+	RIGHT_BRACE
+	COMMA
+	DOT
+	MINUS
+	PLUS
+	SEMICOLON
+	SLASH
+	STAR
+
+	// One or two character tokens.
+	BANG
+	BANG_EQUAL
+	EQUAL
+	EQUAL_EQUAL
+	GREATER
+	GREATER_EQUAL
+	LESS
+	LESS_EQUAL
+	QUESTION_QUESTION
+
+	// Literals
+	IDENTIFIER
+	STRING
+	NUMBER
+
+	// Comments, only produced when the scanner keeps them.
+	COMMENT
+
+	// Keywords
+	AND
+	CLASS
+	ELSE
+	FALSE
+	FUN
+	FOR
+	IF
+	NIL
+	OR
+	PRINT
+	RETURN
+	SPAWN
+	SUPER
+	THIS
+	TRUE
+	VAR
+	WHILE
+
+	// signals when we parsed all tokens
+	EOF
+)
+
+// Span locates a piece of source text.
+type Span struct {
+	// StartOffset and EndOffset are the byte offsets of the text: it is source[StartOffset:EndOffset].
+	StartOffset int `json:"startOffset"`
+	EndOffset   int `json:"endOffset"`
+	// Line and Col are the 1-based line and byte column of the first character.
+	Line int `json:"line"`
+	Col  int `json:"col"`
+}
+
+// Text returns the text of source covered by the span.
+func (s Span) Text(source string) string {
+	return source[s.StartOffset:s.EndOffset]
+}
+
+// SourceSpan returns the span itself. It is promoted to the AST nodes, which embed their span.
+func (s Span) SourceSpan() Span {
+	return s
+}
+
+// Cover returns the smallest span that contains both s and other.
+func (s Span) Cover(other Span) Span {
+	if other.StartOffset < s.StartOffset {
+		s, other = other, s
+	}
+	if other.EndOffset > s.EndOffset {
+		s.EndOffset = other.EndOffset
+	}
+	return s
+}
+
+type Token struct {
+	Type    TokenType
+	Lexeme  string
+	Literal interface{}
+	Span
+}
+
+func NewToken(typ TokenType, lexeme string, literal interface{}, line int) Token {
+	return Token{
+		Type:    typ,
+		Lexeme:  lexeme,
+		Literal: literal,
+		Span:    Span{Line: line},
+	}
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%s %s %v", t.Type, t.Lexeme, t.Literal)
+}
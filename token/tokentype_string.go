@@ -1,6 +1,6 @@
 // Code generated by "stringer -type TokenType"; DO NOT EDIT.
 
-package main
+package token
 
 import "strconv"
 
@@ -27,31 +27,34 @@ func _() {
 	_ = x[GREATER_EQUAL-16]
 	_ = x[LESS-17]
 	_ = x[LESS_EQUAL-18]
-	_ = x[IDENTIFIER-19]
-	_ = x[STRING-20]
-	_ = x[NUMBER-21]
-	_ = x[AND-22]
-	_ = x[CLASS-23]
-	_ = x[ELSE-24]
-	_ = x[FALSE-25]
-	_ = x[FUN-26]
-	_ = x[FOR-27]
-	_ = x[IF-28]
-	_ = x[NIL-29]
-	_ = x[OR-30]
-	_ = x[PRINT-31]
-	_ = x[RETURN-32]
-	_ = x[SUPER-33]
-	_ = x[THIS-34]
-	_ = x[TRUE-35]
-	_ = x[VAR-36]
-	_ = x[WHILE-37]
-	_ = x[EOF-38]
+	_ = x[QUESTION_QUESTION-19]
+	_ = x[IDENTIFIER-20]
+	_ = x[STRING-21]
+	_ = x[NUMBER-22]
+	_ = x[COMMENT-23]
+	_ = x[AND-24]
+	_ = x[CLASS-25]
+	_ = x[ELSE-26]
+	_ = x[FALSE-27]
+	_ = x[FUN-28]
+	_ = x[FOR-29]
+	_ = x[IF-30]
+	_ = x[NIL-31]
+	_ = x[OR-32]
+	_ = x[PRINT-33]
+	_ = x[RETURN-34]
+	_ = x[SPAWN-35]
+	_ = x[SUPER-36]
+	_ = x[THIS-37]
+	_ = x[TRUE-38]
+	_ = x[VAR-39]
+	_ = x[WHILE-40]
+	_ = x[EOF-41]
 }
 
-const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALIDENTIFIERSTRINGNUMBERANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSUPERTHISTRUEVARWHILEEOF"
+const _TokenType_name = "LEFT_PARENRIGHT_PARENLEFT_BRACERIGHT_BRACECOMMADOTMINUSPLUSSEMICOLONSLASHSTARBANGBANG_EQUALEQUALEQUAL_EQUALGREATERGREATER_EQUALLESSLESS_EQUALQUESTION_QUESTIONIDENTIFIERSTRINGNUMBERCOMMENTANDCLASSELSEFALSEFUNFORIFNILORPRINTRETURNSPAWNSUPERTHISTRUEVARWHILEEOF"
 
-var _TokenType_index = [...]uint8{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 151, 157, 163, 166, 171, 175, 180, 183, 186, 188, 191, 193, 198, 204, 209, 213, 217, 220, 225, 228}
+var _TokenType_index = [...]uint16{0, 10, 21, 31, 42, 47, 50, 55, 59, 68, 73, 77, 81, 91, 96, 107, 114, 127, 131, 141, 158, 168, 174, 180, 187, 190, 195, 199, 204, 207, 210, 212, 215, 217, 222, 228, 233, 238, 242, 246, 249, 254, 257}
 
 func (i TokenType) String() string {
 	if i < 0 || i >= TokenType(len(_TokenType_index)-1) {
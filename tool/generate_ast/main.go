@@ -0,0 +1,215 @@
+// Command generate_ast writes the AST node types, their accept methods and the visitor interfaces,
+// from the compact descriptions below, like GenerateAst in the book.
+//
+// Each description is "Name : Field Type, Field Type", optionally followed by "// comment" that documents
+// the node. Every node also embeds the token.Span of its source text.
+//
+// Typed families get a generic visitor interface instead of an accept method, since methods cannot have
+// type parameters: a generated accept function takes the visitor and switches on the node type.
+//
+// The fields of type Expr or []Expr are the child expressions of a node: for each family it also writes
+// a function that lists them and one that replaces them, which the rewriting functions of the package use.
+// A field type ending in "?" is optional: the field can be nil, and it is left out of the child expressions then.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+)
+
+// base describes a family of nodes: the interface they implement and the visitor they accept.
+type base struct {
+	name    string
+	comment string
+	// suffix is added to the description names to get the type names
+	suffix string
+	// visitorSuffix is added to the type names to get the visitor method names
+	visitorSuffix string
+	visitor       string
+	// result is the type returned by the visitor methods, empty for none
+	result string
+	// typed makes the visitor generic in its result type
+	typed bool
+	nodes []string
+}
+
+var bases = []base{
+	{
+		name:    "Stmt",
+		comment: "Stmt is a statement node. Every node embeds the Span of its source text.",
+		suffix:  "Stmt",
+		visitor: "StmtVisitor",
+		result:  "T",
+		typed:   true,
+		nodes: []string{
+			"Expression : Expr Expr",
+			"Print      : Expr Expr",
+			"Spawn      : Call Expr // SpawnStmt runs Call, an ast.Call, in a new task.",
+			"Var        : Name token.Token, Initializer Expr? // VarStmt declares a variable; its Initializer is nil when it has none.",
+		},
+	},
+	{
+		name:          "Expr",
+		comment:       "Expr is an expression node. Every node embeds the Span of its source text.",
+		visitorSuffix: "Expr",
+		visitor:       "ExprVisitor",
+		result:        "T",
+		typed:         true,
+		nodes: []string{
+			"Binary     : Operator token.Token, Left Expr, Right Expr",
+			"Logical    : Operator token.Token, Left Expr, Right Expr // Logical is a binary expression whose right operand is only evaluated when needed.",
+			"Comparison : Operators []token.Token, Operands []Expr // Comparison is a chain of two or more comparisons: Operands[i] Operators[i] Operands[i+1].",
+			"Unary      : Operator token.Token, Right Expr",
+			"Literal    : Value interface{}",
+			"Grouping   : Expr Expr",
+			"Call       : Callee Expr, Paren token.Token, Arguments []Expr // Call calls Callee with Arguments; Paren is the closing parenthesis, where the errors of the call are reported.",
+			"Variable   : Name token.Token // Variable is the value of the variable Name.",
+			"Assign     : Name token.Token, Value Expr // Assign stores Value in the variable Name, and evaluates to it.",
+			"Get        : Object Expr, Name token.Token // Get is the value of the property Name of Object.",
+			"Set        : Object Expr, Name token.Token, Value Expr // Set stores Value in the property Name of Object, and evaluates to it.",
+		},
+	},
+}
+
+func main() {
+	output := flag.String("output", "ast.go", "file to write")
+	pkg := flag.String("package", "ast", "package of the generated file")
+	flag.Parse()
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"generate_ast\"; DO NOT EDIT.\n\npackage %s\n\nimport (\n\"fmt\"\n\n\"github.com/gadumitrachioaiei/go-lox/token\"\n)\n", *pkg)
+	for _, b := range bases {
+		defineAst(&buf, b)
+		defineChildExprs(&buf, b)
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatalf("formatting generated code: %v\n%s", err, buf.Bytes())
+	}
+	if err := ioutil.WriteFile(*output, source, 0644); err != nil {
+		log.Fatalf("writing %s: %v", *output, err)
+	}
+}
+
+func defineAst(buf *bytes.Buffer, b base) {
+	fmt.Fprintf(buf, "\n// %s\n", b.comment)
+	if b.typed {
+		fmt.Fprintf(buf, "type %s interface {\nis%s()\nSourceSpan() token.Span\n}\n", b.name, b.name)
+	} else {
+		fmt.Fprintf(buf, "type %s interface {\nAccept(visitor %s) %s\nSourceSpan() token.Span\n}\n", b.name, b.visitor, b.result)
+	}
+
+	var methods, cases []string
+	for _, description := range b.nodes {
+		name, fields, comment := parse(description)
+		typeName := name + b.suffix
+		method := "Visit" + typeName + b.visitorSuffix
+		receiver := strings.ToLower(typeName[:1]) + strings.ToLower(b.name)
+
+		fmt.Fprintln(buf)
+		if comment != "" {
+			fmt.Fprintf(buf, "// %s\n", comment)
+		}
+		fmt.Fprintf(buf, "type %s struct {\n", typeName)
+		for _, field := range fields {
+			fmt.Fprintln(buf, strings.TrimSuffix(field, "?"))
+		}
+		fmt.Fprintf(buf, "token.Span\n}\n\n")
+
+		if b.typed {
+			fmt.Fprintf(buf, "func (%s %s) is%s() {}\n", receiver, typeName, b.name)
+			cases = append(cases, fmt.Sprintf("case %s:\nreturn visitor.%s(%s)", typeName, method, strings.ToLower(b.name)))
+		} else {
+			fmt.Fprintf(buf, "func (%s %s) Accept(visitor %s) %s {\n", receiver, typeName, b.visitor, b.result)
+			if b.result != "" {
+				fmt.Fprint(buf, "return ")
+			}
+			fmt.Fprintf(buf, "visitor.%s(%s)\n}\n", method, receiver)
+		}
+
+		methods = append(methods, fmt.Sprintf("%s(%s %s) %s", method, strings.ToLower(b.name), typeName, b.result))
+	}
+
+	sort.Strings(methods)
+	if !b.typed {
+		fmt.Fprintf(buf, "\ntype %s interface {\n%s\n}\n", b.visitor, strings.Join(methods, "\n"))
+		return
+	}
+	fmt.Fprintf(buf, "\ntype %s[%s any] interface {\n%s\n}\n", b.visitor, b.result, strings.Join(methods, "\n"))
+	variable := strings.ToLower(b.name)
+	fmt.Fprintf(buf, "\n// Accept%s calls the method of visitor for the type of %s.\n", b.name, variable)
+	fmt.Fprintf(buf, "func Accept%s[%s any](%s %s, visitor %s[%s]) %s {\n", b.name, b.result, variable, b.name, b.visitor, b.result, b.result)
+	fmt.Fprintf(buf, "switch %s := %s.(type) {\n%s\n}\n", variable, variable, strings.Join(cases, "\n"))
+	fmt.Fprintf(buf, "panic(fmt.Sprintf(\"unknown %s node %%T\", %s))\n}\n", b.name, variable)
+}
+
+// defineChildExprs writes the functions that list and replace the child expressions of the nodes of b.
+func defineChildExprs(buf *bytes.Buffer, b base) {
+	variable := strings.ToLower(b.name)
+	var listCases, replaceCases []string
+	for _, description := range b.nodes {
+		name, fields, _ := parse(description)
+		var singles, list, replace []string
+		all := true
+		for _, field := range fields {
+			parts := strings.Fields(field)
+			switch parts[1] {
+			case "Expr":
+				singles = append(singles, variable+"."+parts[0])
+				list = append(list, fmt.Sprintf("children = append(children, %s.%s)", variable, parts[0]))
+				replace = append(replace, fmt.Sprintf("%s.%s, rest = rest[0], rest[1:]", variable, parts[0]))
+			case "Expr?":
+				all = false
+				list = append(list, fmt.Sprintf("if %[1]s.%[2]s != nil {\nchildren = append(children, %[1]s.%[2]s)\n}", variable, parts[0]))
+				replace = append(replace, fmt.Sprintf("if %[1]s.%[2]s != nil {\n%[1]s.%[2]s, rest = rest[0], rest[1:]\n}", variable, parts[0]))
+			case "[]Expr":
+				all = false
+				list = append(list, fmt.Sprintf("children = append(children, %s.%s...)", variable, parts[0]))
+				replace = append(replace, fmt.Sprintf("%[1]s.%[2]s, rest = append([]Expr(nil), rest[:len(%[1]s.%[2]s)]...), rest[len(%[1]s.%[2]s):]", variable, parts[0]))
+			}
+		}
+		if len(list) == 0 {
+			continue
+		}
+		typeName := name + b.suffix
+		if all {
+			listCases = append(listCases, fmt.Sprintf("case %s:\nreturn []Expr{%s}", typeName, strings.Join(singles, ", ")))
+		} else {
+			listCases = append(listCases, fmt.Sprintf("case %s:\nvar children []Expr\n%s\nreturn children", typeName, strings.Join(list, "\n")))
+		}
+		replaceCases = append(replaceCases, fmt.Sprintf("case %s:\n%s\nreturn %s", typeName, strings.Join(replace, "\n"), variable))
+	}
+
+	fmt.Fprintf(buf, "\n// %sChildExprs returns the child expressions of %s, in the order of their fields.\n", variable, variable)
+	fmt.Fprintf(buf, "func %sChildExprs(%s %s) []Expr {\n", variable, variable, b.name)
+	fmt.Fprintf(buf, "switch %s := %s.(type) {\n%s\n}\nreturn nil\n}\n", variable, variable, strings.Join(listCases, "\n"))
+	fmt.Fprintf(buf, "\n// with%sChildExprs returns a copy of %s with children as its child expressions,\n", b.name, variable)
+	fmt.Fprintf(buf, "// in the order %sChildExprs returns them.\n", variable)
+	fmt.Fprintf(buf, "func with%sChildExprs(%s %s, children []Expr) %s {\nrest := children\n", b.name, variable, b.name, b.name)
+	fmt.Fprintf(buf, "switch %s := %s.(type) {\n%s\n}\nreturn %s\n}\n", variable, variable, strings.Join(replaceCases, "\n"), variable)
+}
+
+// parse splits a node description into its name, its field declarations and its comment.
+func parse(description string) (string, []string, string) {
+	comment := ""
+	if i := strings.Index(description, "//"); i >= 0 {
+		description, comment = description[:i], strings.TrimSpace(description[i+2:])
+	}
+	parts := strings.SplitN(description, ":", 2)
+	if len(parts) != 2 {
+		log.Fatalf("bad node description %q", description)
+	}
+	var fields []string
+	for _, field := range strings.Split(parts[1], ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return strings.TrimSpace(parts[0]), fields, comment
+}
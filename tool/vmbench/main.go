@@ -0,0 +1,81 @@
+// Command vmbench measures how many steps and runs a second the bytecode VM and the tree-walking interpreter
+// run on a generated program of arithmetic, comparisons, logical operators and global variables.
+// A step is a node evaluated by the tree-walker, or an instruction run by the VM.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/vm"
+)
+
+func main() {
+	statements := flag.Int("statements", 10000, "statements of the program")
+	duration := flag.Duration("duration", time.Second, "how long to run each backend")
+	flag.Parse()
+
+	var source strings.Builder
+	source.WriteString("var a = 1; var b = 2.5; var c = 0; var d = false;\n")
+	for i := 0; i < *statements; i++ {
+		switch i % 4 {
+		case 0:
+			source.WriteString("c = c + a * 2 - 1;\n")
+		case 1:
+			source.WriteString("b = b * 0.5 + c / 4;\n")
+		case 2:
+			source.WriteString("d = (c < 100 and a <= c) ?? d;\n")
+		default:
+			source.WriteString("d = d == true or c > 3;\n")
+		}
+	}
+	s := scanner.New(source.String())
+	tokens, errs := s.ScanTokens()
+	if len(errs) > 0 {
+		log.Fatal(errs[0])
+	}
+	program, errs := parser.New(tokens).Parse()
+	if len(errs) > 0 {
+		log.Fatal(errs[0])
+	}
+	chunk, err := vm.Compile(program)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tree := measure(*duration, func(intr interp.Interpreter) error { return intr.Interpret(program) })
+	bytecode := measure(*duration, func(intr interp.Interpreter) error {
+		_, err := vm.New(intr).Run(chunk)
+		return err
+	})
+	// a step of each backend does a different amount of work, so only the runs compare them
+	fmt.Printf("tree %12.0f steps/s %8.1f runs/s\n", tree.steps, tree.runs)
+	fmt.Printf("vm   %12.0f steps/s %8.1f runs/s  %.2fx\n", bytecode.steps, bytecode.runs, bytecode.runs/tree.runs)
+}
+
+// rates are the steps and the runs of the program a second.
+type rates struct {
+	steps, runs float64
+}
+
+// measure runs the program with run, in new globals each time, for about duration.
+func measure(duration time.Duration, run func(intr interp.Interpreter) error) rates {
+	stats := &interp.Stats{}
+	runs := 0
+	start := time.Now()
+	for ; time.Since(start) < duration; runs++ {
+		intr := interp.New()
+		intr.Stats = stats
+		if err := run(intr); err != nil {
+			log.Fatal(err)
+		}
+	}
+	seconds := time.Since(start).Seconds()
+	return rates{steps: float64(stats.Steps) / seconds, runs: float64(runs) / seconds}
+}
@@ -0,0 +1,204 @@
+// Package transpile translates Lox programs to Go source, which runs them with the compiled package.
+// A global becomes a slot, looked up once, and each expression a Go expression: Go evaluates the calls
+// of an expression from left to right, as Lox does.
+package transpile
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/compiled"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// Go returns the source of a Go main package that runs statements, parsed from source, the script at path
+// as its comment names it, with options.
+func Go(path, source string, statements []ast.Stmt, options compiled.Options) ([]byte, error) {
+	t := &translator{slots: make(map[string]bool)}
+	var body bytes.Buffer
+	for _, stmt := range statements {
+		body.WriteString(ast.AcceptStmt[string](stmt, t))
+		body.WriteByte('\n')
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by lox build from %s. DO NOT EDIT.\n\n", path)
+	out.WriteString("package main\n\n")
+	out.WriteString("import (\n")
+	out.WriteString("\t\"github.com/gadumitrachioaiei/go-lox/compiled\"\n")
+	// Go rejects an unused import, and a script like print clock; makes no value itself
+	if t.caches > 0 || bytes.Contains(body.Bytes(), []byte("interp.")) {
+		out.WriteString("\t\"github.com/gadumitrachioaiei/go-lox/interp\"\n")
+	}
+	out.WriteString("\t\"github.com/gadumitrachioaiei/go-lox/token\"\n")
+	out.WriteString(")\n\n")
+	out.WriteString("// source is the script, for the lines of its runtime errors.\n")
+	fmt.Fprintf(&out, "const source = %s\n\n", strconv.Quote(source))
+	out.WriteString("// tokens are the operators and the names of the script, where its runtime errors are reported.\n")
+	out.WriteString("var tokens = [...]token.Token{\n")
+	for _, tok := range t.tokens {
+		fmt.Fprintf(&out, "\t{Type: token.%s, Lexeme: %s, Span: token.Span{StartOffset: %d, EndOffset: %d, Line: %d, Col: %d}},\n",
+			tok.Type, strconv.Quote(tok.Lexeme), tok.StartOffset, tok.EndOffset, tok.Line, tok.Col)
+	}
+	out.WriteString("}\n\n")
+	if t.caches > 0 {
+		out.WriteString("// caches are the caches of the property expressions.\n")
+		fmt.Fprintf(&out, "var caches [%d]interp.PropertyCache\n\n", t.caches)
+	}
+	fmt.Fprintf(&out, "func main() {\n\tcompiled.Main(source, %#v, run)\n}\n\n", options)
+	out.WriteString("func run(p *compiled.Program) {\n")
+	for _, name := range t.names {
+		fmt.Fprintf(&out, "\t%s := p.Slot(%s)\n", slotName(name), strconv.Quote(name))
+	}
+	out.Write(body.Bytes())
+	out.WriteString("}\n")
+	return format.Source(out.Bytes())
+}
+
+// translator returns the Go code of the nodes it visits, and collects what it refers to.
+type translator struct {
+	tokens []token.Token
+	// names are the globals, in the order of their first use, that have a slot
+	names  []string
+	slots  map[string]bool
+	caches int
+}
+
+// token returns the Go expression of tok.
+func (t *translator) token(tok token.Token) string {
+	t.tokens = append(t.tokens, tok)
+	return fmt.Sprintf("tokens[%d]", len(t.tokens)-1)
+}
+
+// slot returns the Go variable of the slot of the global name.
+func (t *translator) slot(name string) string {
+	if !t.slots[name] {
+		t.slots[name] = true
+		t.names = append(t.names, name)
+	}
+	return slotName(name)
+}
+
+// slotName returns the Go variable of the slot of the global name: a Lox identifier is a Go one, and the
+// prefix keeps it apart from the keywords and the other variables of Go.
+func slotName(name string) string {
+	return "global_" + name
+}
+
+// cache returns the Go expression of the cache of a new property expression.
+func (t *translator) cache() string {
+	t.caches++
+	return fmt.Sprintf("&caches[%d]", t.caches-1)
+}
+
+func (t *translator) expr(expr ast.Expr) string {
+	return ast.AcceptExpr[string](expr, t)
+}
+
+// exprs returns the Go expressions of exprs, each of which follows a comma.
+func (t *translator) exprs(exprs []ast.Expr) string {
+	var b strings.Builder
+	for _, expr := range exprs {
+		b.WriteString(", ")
+		b.WriteString(t.expr(expr))
+	}
+	return b.String()
+}
+
+// thunk returns a Go function that evaluates expr, for an operand that may not be evaluated.
+func (t *translator) thunk(expr ast.Expr) string {
+	return "func() interp.Value { return " + t.expr(expr) + " }"
+}
+
+func (t *translator) VisitExpressionStmt(stmt ast.ExpressionStmt) string {
+	return "_ = " + t.expr(stmt.Expr)
+}
+
+func (t *translator) VisitPrintStmt(stmt ast.PrintStmt) string {
+	return "p.Print(" + t.expr(stmt.Expr) + ")"
+}
+
+func (t *translator) VisitSpawnStmt(stmt ast.SpawnStmt) string {
+	call := stmt.Call.(ast.Call)
+	callee := t.expr(call.Callee)
+	return "p.Spawn(" + callee + ", " + t.token(call.Paren) + t.exprs(call.Arguments) + ")"
+}
+
+func (t *translator) VisitVarStmt(stmt ast.VarStmt) string {
+	value := "interp.Nil()"
+	if stmt.Initializer != nil {
+		value = t.expr(stmt.Initializer)
+	}
+	return "p.Define(" + t.slot(stmt.Name.Lexeme) + ", " + value + ")"
+}
+
+func (t *translator) VisitLiteralExpr(expr ast.Literal) string {
+	switch value := expr.Value.(type) {
+	case nil:
+		return "interp.Nil()"
+	case bool:
+		return fmt.Sprintf("interp.Bool(%t)", value)
+	case int64:
+		return fmt.Sprintf("interp.Int(%d)", value)
+	case float64:
+		return "interp.Float(" + strconv.FormatFloat(value, 'g', -1, 64) + ")"
+	case string:
+		return "interp.String(" + strconv.Quote(value) + ")"
+	}
+	panic(fmt.Sprintf("unknown literal: %#v", expr.Value))
+}
+
+func (t *translator) VisitGroupingExpr(expr ast.Grouping) string {
+	return t.expr(expr.Expr)
+}
+
+func (t *translator) VisitVariableExpr(expr ast.Variable) string {
+	return "p.Get(" + t.slot(expr.Name.Lexeme) + ", " + t.token(expr.Name) + ")"
+}
+
+func (t *translator) VisitAssignExpr(expr ast.Assign) string {
+	value := t.expr(expr.Value)
+	return "p.Assign(" + t.slot(expr.Name.Lexeme) + ", " + t.token(expr.Name) + ", " + value + ")"
+}
+
+func (t *translator) VisitUnaryExpr(expr ast.Unary) string {
+	return "p.Unary(" + t.token(expr.Operator) + ", " + t.expr(expr.Right) + ")"
+}
+
+func (t *translator) VisitBinaryExpr(expr ast.Binary) string {
+	left, right := t.expr(expr.Left), t.expr(expr.Right)
+	return "p.Binary(" + t.token(expr.Operator) + ", " + left + ", " + right + ")"
+}
+
+func (t *translator) VisitLogicalExpr(expr ast.Logical) string {
+	left, right := t.expr(expr.Left), t.thunk(expr.Right)
+	return "p.Logical(" + t.token(expr.Operator) + ", " + left + ", " + right + ")"
+}
+
+func (t *translator) VisitComparisonExpr(expr ast.Comparison) string {
+	first := t.expr(expr.Operands[0])
+	var operators, operands []string
+	for i, operator := range expr.Operators {
+		operators = append(operators, t.token(operator))
+		operands = append(operands, t.thunk(expr.Operands[i+1]))
+	}
+	return "p.Comparison(" + first + ", []token.Token{" + strings.Join(operators, ", ") + "}, " + strings.Join(operands, ", ") + ")"
+}
+
+func (t *translator) VisitCallExpr(expr ast.Call) string {
+	callee := t.expr(expr.Callee)
+	return "p.Call(" + callee + ", " + t.token(expr.Paren) + t.exprs(expr.Arguments) + ")"
+}
+
+func (t *translator) VisitGetExpr(expr ast.Get) string {
+	return "p.GetProperty(" + t.expr(expr.Object) + ", " + t.token(expr.Name) + ", " + t.cache() + ")"
+}
+
+func (t *translator) VisitSetExpr(expr ast.Set) string {
+	object, value := t.expr(expr.Object), t.expr(expr.Value)
+	return "p.SetProperty(" + object + ", " + t.token(expr.Name) + ", " + value + ", " + t.cache() + ")"
+}
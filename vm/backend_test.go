@@ -0,0 +1,87 @@
+package vm
+
+import (
+	"strings"
+	"testing"
+)
+
+// backendTests are scripts that both backends must run the same way, with what they print and the end of the
+// message of the error they stop with, "" for none.
+var backendTests = []struct {
+	name, source, want, err string
+}{
+	// variables, as there are no closures to capture them yet: a global is read where it is used, so a
+	// later assignment is seen by the code after it
+	{"globals", "var a = 1; var b = a; a = 2; print a; print b; var a = 3; print a;", "2\n1\n3\n", ""},
+	{"assignment value", "var a; var b; print a = b = 4; print a + b;", "4\n8\n", ""},
+	{"nil default", "var a; print a; print a ?? 1;", "nil\n1\n", ""},
+	{"undefined", "print 1; print nope;", "1\n", "Undefined variable 'nope'."},
+	{"assign undefined", "nope = 1;", "", "Undefined variable 'nope'."},
+
+	// the right operands of and, or and ?? are the only conditional code until there is control flow:
+	// they must not run when the left one decides
+	{"short circuit", "print false and exit(3); print true or exit(3); print 1 ?? exit(3); print nil ?? 2;", "false\ntrue\n1\n2\n", ""},
+	{"short circuit errors", `print false and nope; print "s" or nope; print nil and 1 - "s";`, "false\ns\nnil\n", ""},
+	{"logical values", `print nil or "default"; print 0 and "zero is true"; print false ?? 1;`, "default\nzero is true\nfalse\n", ""},
+
+	// arithmetic and comparisons
+	{"arithmetic", "print 1 + 2 * 3 - 4 / 2; print (1 + 2) * 3; print 7 / 2; print -3 - -3; print 2.5 * 2;", "5\n9\n3.5\n0\n5\n", ""},
+	{"comparison chain", "print 1 < 2 < 3; print 3 > 2 > 2; print 1 <= 1 == true; print 1 == 1.0; print \"a\" == \"a\";", "true\nfalse\ntrue\ntrue\ntrue\n", ""},
+	{"not", "print !nil; print !0; print !!\"\";", "true\nfalse\ntrue\n", ""},
+	{"operand types", `print "a" - 1;`, "", "Operands must be numbers, got string and number for '-'"},
+	{"negate a string", `print -"a";`, "", "Operand must be a number, got string for '-'"},
+	{"division by zero", "print 1; print 1 / 0;", "1\n", "Division by zero for '/'"},
+
+	// strings, which the VM keeps in its values without copying them
+	{"concatenation", `var s = "a"; s = s + "b"; s = s + s; print s; print len(s);`, "abab\n4\n", ""},
+	{"string builtins", `print substr("hello", 1, 3); print replace("a-b-c", "-", "+"); print str(1.5) + "!";`, "el\na+b+c\n1.5!\n", ""},
+	{"builtin errors", `print substr("hello", 3, 1);`, "", "The end of substr must not be before its start, got 3 and 1."},
+
+	// builtins are values that can be passed around and called
+	{"builtin values", "var f = len; print f(\"abc\"); print type(f); print arity(f); print f == len;", "3\nfunction\n1\ntrue\n", ""},
+	{"call a number", "var n = 1; n();", "", "Can only call functions and classes."},
+	{"arguments", "len();", "", "Expected 1 arguments but got 0."},
+
+	// JSON objects and arrays, the only instances without Bind
+	{"json", `var o = jsonParse("{}"); o.a = 1; o.b = o.a + 1; print o.b; print jsonStringify(o, false);`, "2\n{\"a\":1,\"b\":2}\n", ""},
+	{"json property", `var o = jsonParse("{}"); print o.nope;`, "", "Undefined property 'nope'."},
+
+	// tasks pass their values through channels; main waits for each one, so the output is in order
+	{"spawn", `var c = chan(); spawn send(c, 1); print receive(c); spawn send(c, "two"); print receive(c);`, "1\ntwo\n", ""},
+	{"deadlock", "var c = chan(); receive(c);", "", "Deadlock: every task is waiting on a channel."},
+
+	// exit ends the run without printing the rest
+	{"exit", "print 1; exit(0); print 2;", "1\n", "exit status 0"},
+}
+
+func TestBackends(t *testing.T) {
+	for _, test := range backendTests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := runBoth(t, test.source)
+			if got != test.want {
+				t.Errorf("%s printed %q, want %q", test.source, got, test.want)
+			}
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("%s failed with %v", test.source, err)
+			case test.err != "" && (err == nil || !strings.HasSuffix(err.Error(), test.err)):
+				t.Errorf("%s failed with %v, want an error ending with %q", test.source, err, test.err)
+			}
+		})
+	}
+}
+
+// TestBackendsGenerated runs generated programs of arithmetic, comparisons, logical operators and globals
+// on both backends, which must print the same.
+func TestBackendsGenerated(t *testing.T) {
+	for _, statements := range []int{1, 10, 1000} {
+		source := arithmeticSource(statements) + "print a; print b; print c; print d;"
+		if _, err := runBoth(t, source); err != nil {
+			t.Errorf("the program of %d statements failed with %v", statements, err)
+		}
+		source = stringSource(statements) + "print s; print n;"
+		if _, err := runBoth(t, source); err != nil {
+			t.Errorf("the program of %d string statements failed with %v", statements, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package vm runs Lox programs by compiling their syntax tree to bytecode for a stack machine, like clox.
+// It gives the same results and errors as the tree-walking interpreter, whose values and operators it uses.
+package vm
+
+import (
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// OpCode is an instruction of the VM. Its operands, if any, are the bytes that follow it in the chunk.
+//
+//go:generate stringer -type OpCode
+type OpCode byte
+
+const (
+	// OpConstant pushes the constant whose two byte index follows.
+	OpConstant OpCode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+	// OpDefineGlobal pops a value and defines the global named by the constant whose index follows.
+	OpDefineGlobal
+	// OpGetGlobal pushes the value of the global named by the constant whose index follows.
+	OpGetGlobal
+	// OpSetGlobal sets the global named by the constant whose index follows to the value on top of the stack,
+	// which it leaves there: an assignment is an expression.
+	OpSetGlobal
+	// OpGetProperty replaces the instance on top of the stack by its property named by the constant whose
+	// index follows. The two byte index of its site, which has a cache of the property, follows the name.
+	OpGetProperty
+	// OpSetProperty pops a value and the instance below it, sets the property named by the constant whose
+	// index follows to the value, and pushes the value back. Its site follows, as for OpGetProperty.
+	OpSetProperty
+	// OpBinaryConstant is OpConstant followed by a binary operator, whose opcode follows the index of the
+	// constant: it replaces the value on top of the stack by the result of the operator with the constant
+	// as its right operand. Only the peephole pass writes it.
+	OpBinaryConstant
+	// The binary operators pop their right operand, then their left one, and push the result.
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpEqual
+	OpNotEqual
+	OpGreater
+	OpGreaterEqual
+	OpLess
+	OpLessEqual
+	// OpCompareChain is a comparison of a chain, whose comparison opcode follows. It pops its operands and
+	// pushes the right one back below the result, as the left operand of the next comparison.
+	OpCompareChain
+	OpNegate
+	OpNot
+	OpPrint
+	// The jumps move forward by the two byte offset that follows, counted from the end of the instruction.
+	OpJump
+	// OpJumpIfFalse jumps if the value on top of the stack is falsey, which it leaves there.
+	OpJumpIfFalse
+	// OpJumpIfNotNil jumps if the value on top of the stack is not nil, which it leaves there.
+	OpJumpIfNotNil
+	// OpCall calls the value below the arguments, whose one byte count follows, and replaces them all by the result.
+	OpCall
+	// OpSpawn is OpCall in a new task, which it does not wait for, so it leaves nothing on the stack.
+	OpSpawn
+	// OpReturn ends the chunk.
+	OpReturn
+)
+
+// Chunk is compiled code: its instructions, the constants they refer to and the source they come from.
+type Chunk struct {
+	Code      []byte
+	Constants []interp.Value
+	// spans are the spans of the source of Code, where its errors are reported
+	spans spanTable
+	// sites is the number of the sites of the property instructions
+	sites int
+}
+
+// size returns the number of bytes of the instruction op, with its operands.
+func (op OpCode) size() int {
+	switch op {
+	case OpCompareChain, OpCall, OpSpawn:
+		return 2
+	case OpConstant, OpDefineGlobal, OpGetGlobal, OpSetGlobal, OpJump, OpJumpIfFalse, OpJumpIfNotNil:
+		return 3
+	case OpBinaryConstant:
+		return 4
+	case OpGetProperty, OpSetProperty:
+		return 5
+	}
+	return 1
+}
+
+// Span returns the span of the source of the instruction at ip, the token of the expression it runs whose
+// errors it reports, as the tree-walking interpreter would.
+func (c *Chunk) Span(ip int) token.Span {
+	return c.spans.at(ip)
+}
+
+// addConstant returns the index of value in the constants, adding it.
+func (c *Chunk) addConstant(value interp.Value) int {
+	c.Constants = append(c.Constants, value)
+	return len(c.Constants) - 1
+}
@@ -0,0 +1,337 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// CompileError is an error of code the VM cannot run, like a chunk with too many constants.
+type CompileError struct {
+	token.Span
+	Message string
+}
+
+func (ce CompileError) Error() string {
+	return fmt.Sprintf("line %d: %s", ce.Line, ce.Message)
+}
+
+// compiler writes the code of the nodes it visits, in the order they run, to its chunk.
+type compiler struct {
+	chunk *Chunk
+	// spans are the spans of each byte of the code, until they are compressed in the chunk when it is done
+	spans []token.Span
+	// names are the indexes of the constants of the names of globals and properties, which are written once
+	names map[string]int
+	// unoptimized skips the peephole pass, for its tests
+	unoptimized bool
+}
+
+// Compile compiles statements into a chunk.
+func Compile(statements []ast.Stmt) (*Chunk, error) {
+	return compile(&compiler{chunk: &Chunk{}, names: make(map[string]int)}, statements)
+}
+
+func compile(c *compiler, statements []ast.Stmt) (*Chunk, error) {
+	for _, stmt := range statements {
+		if err := ast.AcceptStmt[error](stmt, c); err != nil {
+			return nil, err
+		}
+	}
+	end := token.Span{}
+	if len(statements) > 0 {
+		end = statements[len(statements)-1].SourceSpan()
+	}
+	c.emitOp(end, OpReturn)
+	return c.done(), nil
+}
+
+// CompileExpression compiles expr into a chunk that returns its value.
+func CompileExpression(expr ast.Expr) (*Chunk, error) {
+	c := &compiler{chunk: &Chunk{}, names: make(map[string]int)}
+	if err := c.expr(expr); err != nil {
+		return nil, err
+	}
+	c.emitOp(expr.SourceSpan(), OpReturn)
+	return c.done(), nil
+}
+
+// done returns the chunk, optimized, with its table of spans.
+func (c *compiler) done() *Chunk {
+	if !c.unoptimized {
+		optimize(c.chunk, c.spans)
+	}
+	c.chunk.spans = compressSpans(c.spans)
+	return c.chunk
+}
+
+func (c *compiler) expr(expr ast.Expr) error {
+	return ast.AcceptExpr[error](expr, c)
+}
+
+func (c *compiler) emit(span token.Span, code ...byte) {
+	for _, b := range code {
+		c.chunk.Code = append(c.chunk.Code, b)
+		c.spans = append(c.spans, span)
+	}
+}
+
+func (c *compiler) emitOp(span token.Span, op OpCode) {
+	c.emit(span, byte(op))
+}
+
+// emitIndex writes op followed by the two byte index of a constant.
+func (c *compiler) emitIndex(span token.Span, op OpCode, index int) error {
+	if index > math.MaxUint16 {
+		return CompileError{Span: span, Message: "Too many constants in one chunk."}
+	}
+	c.emit(span, byte(op), byte(index>>8), byte(index))
+	return nil
+}
+
+func (c *compiler) emitConstant(span token.Span, value interp.Value) error {
+	return c.emitIndex(span, OpConstant, c.chunk.addConstant(value))
+}
+
+// emitName writes op followed by the index of the constant of a name.
+func (c *compiler) emitName(op OpCode, name token.Token) error {
+	index, ok := c.names[name.Lexeme]
+	if !ok {
+		index = c.chunk.addConstant(interp.String(name.Lexeme))
+		c.names[name.Lexeme] = index
+	}
+	return c.emitIndex(name.Span, op, index)
+}
+
+// emitProperty writes op followed by the index of the constant of name and the index of a new site.
+func (c *compiler) emitProperty(op OpCode, name token.Token) error {
+	if c.chunk.sites > math.MaxUint16 {
+		return CompileError{Span: name.Span, Message: "Too many properties in one chunk."}
+	}
+	if err := c.emitName(op, name); err != nil {
+		return err
+	}
+	c.emit(name.Span, byte(c.chunk.sites>>8), byte(c.chunk.sites))
+	c.chunk.sites++
+	return nil
+}
+
+// emitJump writes a jump whose offset is patched later, and returns where the offset is.
+func (c *compiler) emitJump(span token.Span, op OpCode) int {
+	c.emit(span, byte(op), 0xff, 0xff)
+	return len(c.chunk.Code) - 2
+}
+
+// patchJump makes the jump whose offset is at offset go to the end of the code.
+func (c *compiler) patchJump(offset int) error {
+	jump := len(c.chunk.Code) - offset - 2
+	if jump > math.MaxUint16 {
+		return CompileError{Span: c.spans[offset], Message: "Too much code to jump over."}
+	}
+	c.chunk.Code[offset] = byte(jump >> 8)
+	c.chunk.Code[offset+1] = byte(jump)
+	return nil
+}
+
+func (c *compiler) VisitExpressionStmt(stmt ast.ExpressionStmt) error {
+	if err := c.expr(stmt.Expr); err != nil {
+		return err
+	}
+	c.emitOp(stmt.Span, OpPop)
+	return nil
+}
+
+func (c *compiler) VisitPrintStmt(stmt ast.PrintStmt) error {
+	if err := c.expr(stmt.Expr); err != nil {
+		return err
+	}
+	c.emitOp(stmt.Span, OpPrint)
+	return nil
+}
+
+func (c *compiler) VisitSpawnStmt(stmt ast.SpawnStmt) error {
+	return c.call(stmt.Call.(ast.Call), OpSpawn)
+}
+
+func (c *compiler) VisitVarStmt(stmt ast.VarStmt) error {
+	if stmt.Initializer == nil {
+		c.emitOp(stmt.Name.Span, OpNil)
+	} else if err := c.expr(stmt.Initializer); err != nil {
+		return err
+	}
+	return c.emitName(OpDefineGlobal, stmt.Name)
+}
+
+func (c *compiler) VisitLiteralExpr(expr ast.Literal) error {
+	switch expr.Value {
+	case nil:
+		c.emitOp(expr.Span, OpNil)
+	case true:
+		c.emitOp(expr.Span, OpTrue)
+	case false:
+		c.emitOp(expr.Span, OpFalse)
+	default:
+		return c.emitConstant(expr.Span, interp.FromGo(expr.Value))
+	}
+	return nil
+}
+
+func (c *compiler) VisitGroupingExpr(expr ast.Grouping) error {
+	return c.expr(expr.Expr)
+}
+
+func (c *compiler) VisitVariableExpr(expr ast.Variable) error {
+	return c.emitName(OpGetGlobal, expr.Name)
+}
+
+func (c *compiler) VisitAssignExpr(expr ast.Assign) error {
+	if err := c.expr(expr.Value); err != nil {
+		return err
+	}
+	return c.emitName(OpSetGlobal, expr.Name)
+}
+
+func (c *compiler) VisitGetExpr(expr ast.Get) error {
+	if err := c.expr(expr.Object); err != nil {
+		return err
+	}
+	return c.emitProperty(OpGetProperty, expr.Name)
+}
+
+func (c *compiler) VisitSetExpr(expr ast.Set) error {
+	if err := c.expr(expr.Object); err != nil {
+		return err
+	}
+	if err := c.expr(expr.Value); err != nil {
+		return err
+	}
+	return c.emitProperty(OpSetProperty, expr.Name)
+}
+
+func (c *compiler) VisitUnaryExpr(expr ast.Unary) error {
+	if err := c.expr(expr.Right); err != nil {
+		return err
+	}
+	switch expr.Operator.Type {
+	case token.MINUS:
+		c.emitOp(expr.Operator.Span, OpNegate)
+	case token.BANG:
+		c.emitOp(expr.Operator.Span, OpNot)
+	default:
+		panic(fmt.Sprintf("unknown unary operator: %v", expr.Operator))
+	}
+	return nil
+}
+
+// binaryOps are the opcodes of the binary operators.
+var binaryOps = map[token.TokenType]OpCode{
+	token.PLUS:          OpAdd,
+	token.MINUS:         OpSubtract,
+	token.STAR:          OpMultiply,
+	token.SLASH:         OpDivide,
+	token.EQUAL_EQUAL:   OpEqual,
+	token.BANG_EQUAL:    OpNotEqual,
+	token.GREATER:       OpGreater,
+	token.GREATER_EQUAL: OpGreaterEqual,
+	token.LESS:          OpLess,
+	token.LESS_EQUAL:    OpLessEqual,
+}
+
+func (c *compiler) VisitBinaryExpr(expr ast.Binary) error {
+	if err := c.expr(expr.Left); err != nil {
+		return err
+	}
+	if err := c.expr(expr.Right); err != nil {
+		return err
+	}
+	op, ok := binaryOps[expr.Operator.Type]
+	if !ok {
+		panic(fmt.Sprintf("unknown binary operator: %v", expr.Operator))
+	}
+	c.emitOp(expr.Operator.Span, op)
+	return nil
+}
+
+// VisitLogicalExpr compiles the right operand so that it only runs when the left one does not decide
+// the result, which is then the left operand, still on the stack.
+func (c *compiler) VisitLogicalExpr(expr ast.Logical) error {
+	if err := c.expr(expr.Left); err != nil {
+		return err
+	}
+	var end int
+	switch expr.Operator.Type {
+	case token.AND:
+		end = c.emitJump(expr.Operator.Span, OpJumpIfFalse)
+	case token.OR:
+		right := c.emitJump(expr.Operator.Span, OpJumpIfFalse)
+		end = c.emitJump(expr.Operator.Span, OpJump)
+		if err := c.patchJump(right); err != nil {
+			return err
+		}
+	case token.QUESTION_QUESTION:
+		end = c.emitJump(expr.Operator.Span, OpJumpIfNotNil)
+	default:
+		panic(fmt.Sprintf("unknown logical operator: %v", expr.Operator))
+	}
+	c.emitOp(expr.Operator.Span, OpPop)
+	if err := c.expr(expr.Right); err != nil {
+		return err
+	}
+	return c.patchJump(end)
+}
+
+// VisitComparisonExpr compiles a chain that stops at the first comparison that is false.
+// A comparison before the last one leaves its right operand below its result, for the next comparison.
+func (c *compiler) VisitComparisonExpr(expr ast.Comparison) error {
+	if err := c.expr(expr.Operands[0]); err != nil {
+		return err
+	}
+	var fails []int
+	for i, operator := range expr.Operators {
+		if err := c.expr(expr.Operands[i+1]); err != nil {
+			return err
+		}
+		if i == len(expr.Operators)-1 {
+			c.emitOp(operator.Span, binaryOps[operator.Type])
+			break
+		}
+		c.emit(operator.Span, byte(OpCompareChain), byte(binaryOps[operator.Type]))
+		fails = append(fails, c.emitJump(operator.Span, OpJumpIfFalse))
+		c.emitOp(operator.Span, OpPop)
+	}
+	end := c.emitJump(expr.Span, OpJump)
+	for _, fail := range fails {
+		if err := c.patchJump(fail); err != nil {
+			return err
+		}
+	}
+	// a false comparison leaves its right operand and false
+	c.emitOp(expr.Span, OpPop)
+	c.emitOp(expr.Span, OpPop)
+	c.emitOp(expr.Span, OpFalse)
+	return c.patchJump(end)
+}
+
+func (c *compiler) VisitCallExpr(expr ast.Call) error {
+	return c.call(expr, OpCall)
+}
+
+// call compiles expr with op, which calls or spawns the callee.
+func (c *compiler) call(expr ast.Call, op OpCode) error {
+	if err := c.expr(expr.Callee); err != nil {
+		return err
+	}
+	for _, argument := range expr.Arguments {
+		if err := c.expr(argument); err != nil {
+			return err
+		}
+	}
+	if len(expr.Arguments) > math.MaxUint8 {
+		return CompileError{Span: expr.Paren.Span, Message: "Too many arguments in one call."}
+	}
+	c.emit(expr.Paren.Span, byte(op), byte(len(expr.Arguments)))
+	return nil
+}
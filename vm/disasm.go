@@ -0,0 +1,91 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gadumitrachioaiei/go-lox/interp"
+)
+
+// Disassemble returns a listing of the code of chunk, compiled from source, which can be "": each
+// instruction on a line, with its offset, its opcode and its operands, the constants and the names they
+// refer to and where the jumps go. Each line of source is given above its first instruction, like
+//
+//	; 1 | print a + 1;
+//	0000 OpGetGlobal         0 'a'
+//	0003 OpBinaryConstant    1 1 OpAdd
+//	0007 OpPrint
+func Disassemble(chunk *Chunk, source string) string {
+	lines := strings.Split(source, "\n")
+	var b strings.Builder
+	line := 0
+	for ip := 0; ip < len(chunk.Code); ip += OpCode(chunk.Code[ip]).size() {
+		if span := chunk.Span(ip); span.Line != line {
+			line = span.Line
+			if line >= 1 && line <= len(lines) && source != "" {
+				fmt.Fprintf(&b, "; %d | %s\n", line, strings.TrimSuffix(lines[line-1], "\r"))
+			} else {
+				fmt.Fprintf(&b, "; %d\n", line)
+			}
+		}
+		b.WriteString(disassembleInstruction(chunk, ip))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// disassembleInstruction returns the line of Disassemble of the instruction at ip.
+func disassembleInstruction(chunk *Chunk, ip int) string {
+	code := chunk.Code
+	op := OpCode(code[ip])
+	if ip+op.size() > len(code) {
+		return fmt.Sprintf("%04d %s, truncated", ip, op)
+	}
+	u16 := func(at int) int { return int(code[at])<<8 | int(code[at+1]) }
+	constant := func(index int) string {
+		if index >= len(chunk.Constants) {
+			return "?"
+		}
+		return formatValue(chunk.Constants[index])
+	}
+	var operands string
+	switch op {
+	case OpConstant, OpDefineGlobal, OpGetGlobal, OpSetGlobal:
+		operands = fmt.Sprintf("%4d %s", u16(ip+1), constant(u16(ip+1)))
+	case OpGetProperty, OpSetProperty:
+		operands = fmt.Sprintf("%4d %s site %d", u16(ip+1), constant(u16(ip+1)), u16(ip+3))
+	case OpBinaryConstant:
+		operands = fmt.Sprintf("%4d %s %s", u16(ip+1), constant(u16(ip+1)), OpCode(code[ip+3]))
+	case OpCompareChain:
+		operands = fmt.Sprintf("%4s %s", "", OpCode(code[ip+1]))
+	case OpJump, OpJumpIfFalse, OpJumpIfNotNil:
+		operands = fmt.Sprintf("%4d -> %04d", u16(ip+1), jumpTarget(code, ip))
+	case OpCall, OpSpawn:
+		operands = fmt.Sprintf("%4d argument%s", code[ip+1], plural(int(code[ip+1])))
+	}
+	return strings.TrimRight(fmt.Sprintf("%04d %-16s %s", ip, op, operands), " ")
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// formatValue returns value as the listings show it, a string quoted to tell it from the other values.
+func formatValue(value interp.Value) string {
+	if value.Kind() == interp.StringKind {
+		return quote(value.AsString())
+	}
+	return value.String()
+}
+
+// quote is strconv.Quote for the strings that need it to show on one line.
+func quote(s string) string {
+	if strings.ContainsAny(s, "\n\r\t'") {
+		return strconv.Quote(s)
+	}
+	return "'" + s + "'"
+}
@@ -0,0 +1,329 @@
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// The .loxc format of a compiled chunk is the magic bytes, the version, then, with the numbers as varints:
+//
+//   - the code, as its length and its bytes;
+//   - the constants, as their count and each one as its kind and its value;
+//   - the number of property sites;
+//   - the line table: the spans of the code, as runs of bytes with the same span, each one its length, then
+//     the start offset and the line of its span, less those of the run before, its length and its column;
+//   - the source the chunk was compiled from, for its runtime errors, as its length and its bytes.
+const magic = "LOXC"
+
+// fileVersion is the version of the .loxc format. It changes with the format and with the opcodes, as
+// a chunk of another version would not run the same.
+const fileVersion = 1
+
+// Marshal returns the .loxc encoding of chunk, compiled from source, which can be "". Only the nil,
+// boolean, number and string constants of the compiler can be encoded.
+func Marshal(chunk *Chunk, source string) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(magic)
+	b.WriteByte(fileVersion)
+	putBytes(&b, chunk.Code)
+	putUvarint(&b, uint64(len(chunk.Constants)))
+	for _, constant := range chunk.Constants {
+		b.WriteByte(byte(constant.Kind()))
+		switch constant.Kind() {
+		case interp.NilKind:
+		case interp.BoolKind:
+			b.WriteByte(boolByte(constant.AsBool()))
+		case interp.IntKind:
+			putVarint(&b, constant.AsInt())
+		case interp.FloatKind:
+			putUvarint(&b, math.Float64bits(constant.AsFloat()))
+		case interp.StringKind:
+			putBytes(&b, []byte(constant.AsString()))
+		default:
+			return nil, fmt.Errorf("cannot encode the constant %v, a %s", constant, constant.Kind())
+		}
+	}
+	putUvarint(&b, uint64(chunk.sites))
+	putUvarint(&b, uint64(len(chunk.spans)))
+	var previous token.Span
+	for i, run := range chunk.spans {
+		span := run.span()
+		putUvarint(&b, uint64(chunk.spans.length(i, len(chunk.Code))))
+		putVarint(&b, int64(span.StartOffset-previous.StartOffset))
+		putVarint(&b, int64(span.Line-previous.Line))
+		putUvarint(&b, uint64(span.EndOffset-span.StartOffset))
+		putUvarint(&b, uint64(span.Col))
+		previous = span
+	}
+	putBytes(&b, []byte(source))
+	return b.Bytes(), nil
+}
+
+// Unmarshal decodes data, in the .loxc format, to a chunk and the source it was compiled from.
+// It checks the code as verify does, so that a corrupt chunk is an error rather than a panic of the VM.
+func Unmarshal(data []byte) (*Chunk, string, error) {
+	if !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, "", errors.New("not a compiled Lox chunk")
+	}
+	data = data[len(magic):]
+	if len(data) == 0 || data[0] != fileVersion {
+		return nil, "", fmt.Errorf("compiled Lox chunk of another version than %d: compile it again", fileVersion)
+	}
+	r := &reader{data: data[1:]}
+	// the code is copied, so that it does not keep data alive
+	chunk := &Chunk{Code: append([]byte(nil), r.bytes()...)}
+	count := r.count()
+	for i := 0; i < count && r.err == nil; i++ {
+		switch kind := interp.Kind(r.byte()); kind {
+		case interp.NilKind:
+			chunk.Constants = append(chunk.Constants, interp.Nil())
+		case interp.BoolKind:
+			chunk.Constants = append(chunk.Constants, interp.Bool(r.byte() == 1))
+		case interp.IntKind:
+			chunk.Constants = append(chunk.Constants, interp.Int(r.varint()))
+		case interp.FloatKind:
+			chunk.Constants = append(chunk.Constants, interp.Float(math.Float64frombits(r.uvarint())))
+		case interp.StringKind:
+			chunk.Constants = append(chunk.Constants, interp.String(string(r.bytes())))
+		default:
+			r.fail(fmt.Errorf("unknown constant kind %d", kind))
+		}
+	}
+	chunk.sites = r.count()
+	runs := r.count()
+	var span token.Span
+	start := 0
+	for i := 0; i < runs && r.err == nil; i++ {
+		length := r.count()
+		span.StartOffset += int(r.varint())
+		span.Line += int(r.varint())
+		span.EndOffset = span.StartOffset + r.count()
+		span.Col = r.count()
+		switch {
+		case length == 0 || start+length > len(chunk.Code):
+			r.fail(errors.New("line table that does not match the code"))
+		case span.StartOffset < 0 || span.EndOffset > math.MaxInt32 || span.Line < 0:
+			r.fail(errors.New("span out of range"))
+		}
+		chunk.spans = append(chunk.spans, newSpanRun(start, span))
+		start += length
+	}
+	source := string(r.bytes())
+	if r.err != nil {
+		return nil, "", fmt.Errorf("corrupt compiled Lox chunk: %v", r.err)
+	}
+	if len(r.data) > 0 {
+		return nil, "", errors.New("corrupt compiled Lox chunk: data after its end")
+	}
+	if start != len(chunk.Code) {
+		return nil, "", errors.New("corrupt compiled Lox chunk: line table shorter than the code")
+	}
+	if err := verify(chunk); err != nil {
+		return nil, "", fmt.Errorf("corrupt compiled Lox chunk: %v", err)
+	}
+	return chunk, source, nil
+}
+
+// verify checks that the instructions of chunk are known, that their operands refer to its constants
+// and sites, that its jumps land on instructions, that it ends with OpReturn, and that no instruction
+// pops more values than the stack has.
+func verify(chunk *Chunk) error {
+	code := chunk.Code
+	starts := make(map[int]bool)
+	var jumps []int
+	last := -1
+	for ip := 0; ip < len(code); ip += OpCode(code[ip]).size() {
+		op := OpCode(code[ip])
+		if op > OpReturn {
+			return fmt.Errorf("unknown opcode %d at %d", op, ip)
+		}
+		if ip+op.size() > len(code) {
+			return fmt.Errorf("truncated instruction at %d", ip)
+		}
+		starts[ip], last = true, ip
+		u16 := func(at int) int { return int(code[at])<<8 | int(code[at+1]) }
+		switch op {
+		case OpConstant, OpBinaryConstant, OpDefineGlobal, OpGetGlobal, OpSetGlobal, OpGetProperty, OpSetProperty:
+			index := u16(ip + 1)
+			if index >= len(chunk.Constants) {
+				return fmt.Errorf("constant %d out of range at %d", index, ip)
+			}
+			if op != OpConstant && op != OpBinaryConstant && chunk.Constants[index].Kind() != interp.StringKind {
+				return fmt.Errorf("name that is not a string at %d", ip)
+			}
+		}
+		switch op {
+		case OpGetProperty, OpSetProperty:
+			if u16(ip+3) >= chunk.sites {
+				return fmt.Errorf("property site out of range at %d", ip)
+			}
+		case OpBinaryConstant, OpCompareChain:
+			operand := code[ip+op.size()-1]
+			if !isBinary(OpCode(operand)) {
+				return fmt.Errorf("operand %d that is not a binary operator at %d", operand, ip)
+			}
+		case OpJump, OpJumpIfFalse, OpJumpIfNotNil:
+			jumps = append(jumps, ip)
+		}
+	}
+	for _, ip := range jumps {
+		if !starts[jumpTarget(code, ip)] {
+			return fmt.Errorf("jump at %d that does not land on an instruction", ip)
+		}
+	}
+	if last < 0 || OpCode(code[last]) != OpReturn {
+		return errors.New("code that does not end with OpReturn")
+	}
+	return checkStack(code)
+}
+
+// checkStack checks that each instruction of code has the operands it pops on the stack. The jumps only go
+// forward, so one pass finds the depth of the stack at each instruction, which must be the same from every
+// instruction that goes to it.
+func checkStack(code []byte) error {
+	// depths are the depths at the targets of the jumps seen so far
+	depths := make(map[int]int)
+	depth, reachable := 0, true
+	for ip := 0; ip < len(code); ip += OpCode(code[ip]).size() {
+		op := OpCode(code[ip])
+		if d, ok := depths[ip]; ok {
+			if reachable && d != depth {
+				return fmt.Errorf("stack of %d and %d values at %d", d, depth, ip)
+			}
+			depth, reachable = d, true
+		}
+		if !reachable {
+			continue
+		}
+		pops, pushes := 0, 0
+		switch op {
+		case OpConstant, OpNil, OpTrue, OpFalse, OpGetGlobal:
+			pushes = 1
+		case OpPop, OpDefineGlobal, OpPrint:
+			pops = 1
+		case OpSetGlobal, OpGetProperty, OpBinaryConstant, OpNegate, OpNot, OpJumpIfFalse, OpJumpIfNotNil:
+			pops, pushes = 1, 1
+		case OpSetProperty:
+			pops, pushes = 2, 1
+		case OpCompareChain:
+			pops, pushes = 2, 2
+		case OpCall:
+			pops, pushes = int(code[ip+1])+1, 1
+		case OpSpawn:
+			pops = int(code[ip+1]) + 1
+		default:
+			if isBinary(op) {
+				pops, pushes = 2, 1
+			}
+		}
+		if depth < pops {
+			return fmt.Errorf("instruction at %d that pops %d values of %d", ip, pops, depth)
+		}
+		depth += pushes - pops
+		switch op {
+		case OpJump, OpJumpIfFalse, OpJumpIfNotNil:
+			target := jumpTarget(code, ip)
+			if d, ok := depths[target]; ok && d != depth {
+				return fmt.Errorf("stack of %d and %d values at %d", d, depth, target)
+			}
+			depths[target] = depth
+		}
+		if op == OpJump || op == OpReturn {
+			reachable = false
+		}
+	}
+	return nil
+}
+
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func putUvarint(b *bytes.Buffer, n uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutUvarint(buf[:], n)])
+}
+
+func putVarint(b *bytes.Buffer, n int64) {
+	var buf [binary.MaxVarintLen64]byte
+	b.Write(buf[:binary.PutVarint(buf[:], n)])
+}
+
+func putBytes(b *bytes.Buffer, data []byte) {
+	putUvarint(b, uint64(len(data)))
+	b.Write(data)
+}
+
+// reader decodes the numbers and the bytes of the .loxc format. After its first error, which it keeps,
+// it returns zeros.
+type reader struct {
+	data []byte
+	err  error
+}
+
+func (r *reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+	r.data = nil
+}
+
+func (r *reader) byte() byte {
+	if len(r.data) == 0 {
+		r.fail(errors.New("unexpected end"))
+		return 0
+	}
+	b := r.data[0]
+	r.data = r.data[1:]
+	return b
+}
+
+func (r *reader) uvarint() uint64 {
+	n, size := binary.Uvarint(r.data)
+	if size <= 0 {
+		r.fail(errors.New("bad number"))
+		return 0
+	}
+	r.data = r.data[size:]
+	return n
+}
+
+func (r *reader) varint() int64 {
+	n, size := binary.Varint(r.data)
+	if size <= 0 {
+		r.fail(errors.New("bad number"))
+		return 0
+	}
+	r.data = r.data[size:]
+	return n
+}
+
+// count returns a number that counts or locates something, which fits an int on any platform.
+func (r *reader) count() int {
+	n := r.uvarint()
+	if n > math.MaxInt32 {
+		r.fail(errors.New("number out of range"))
+		return 0
+	}
+	return int(n)
+}
+
+func (r *reader) bytes() []byte {
+	n := r.count()
+	if n > len(r.data) {
+		r.fail(errors.New("unexpected end"))
+		return nil
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
@@ -0,0 +1,203 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// compileSource compiles source, with the keywords of the extensions.
+func compileSource(t testing.TB, source string) *Chunk {
+	t.Helper()
+	chunk, err := Compile(parse(t, source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return chunk
+}
+
+// runChunk runs chunk in new globals and returns what it printed and its error.
+func runChunk(chunk *Chunk) (string, error) {
+	var out bytes.Buffer
+	intr := interp.New()
+	intr.Stdout = &out
+	_, err := New(intr).Run(chunk)
+	return out.String(), err
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	sources := []string{
+		"",
+		"print 1;",
+		`var a = 1; var b = 2.5; var s = "lox"; print a + b; print s + "!"; print nil ?? true and false;`,
+		"var n = -9223372036854775807 - 1; print n; print 1 < 2 < 3; print 0.1 + 0.2;",
+		"var o = jsonParse(\"{}\");\no.a = 1;\nprint o.a;\nprint o.a + nope;",
+	}
+	for _, source := range sources {
+		chunk := compileSource(t, source)
+		data, err := Marshal(chunk, source)
+		if err != nil {
+			t.Fatalf("%q: %v", source, err)
+		}
+		decoded, decodedSource, err := Unmarshal(data)
+		if err != nil {
+			t.Fatalf("%q: %v", source, err)
+		}
+		if !bytes.Equal(decoded.Code, chunk.Code) {
+			t.Errorf("%q: the code came back as\n%s\nwant\n%s", source, Disassemble(decoded, source), Disassemble(chunk, source))
+		}
+		if len(decoded.Constants) != len(chunk.Constants) {
+			t.Errorf("%q: %d constants came back, want %d", source, len(decoded.Constants), len(chunk.Constants))
+		}
+		for i, constant := range chunk.Constants {
+			if i < len(decoded.Constants) && (decoded.Constants[i].Kind() != constant.Kind() || decoded.Constants[i].Go() != constant.Go()) {
+				t.Errorf("%q: the constant %d came back as %v, want %v", source, i, decoded.Constants[i], constant)
+			}
+		}
+		if !reflect.DeepEqual(decoded.spans, chunk.spans) || decoded.sites != chunk.sites {
+			t.Errorf("%q: the spans came back as %v and %d sites, want %v and %d", source, decoded.spans, decoded.sites, chunk.spans, chunk.sites)
+		}
+		if decodedSource != source {
+			t.Errorf("%q: the source came back as %q", source, decodedSource)
+		}
+		wantOut, wantErr := runChunk(chunk)
+		gotOut, gotErr := runChunk(decoded)
+		if gotOut != wantOut || (gotErr == nil) != (wantErr == nil) || (gotErr != nil && gotErr.Error() != wantErr.Error()) {
+			t.Errorf("%q: the decoded chunk printed %q and failed with %v, want %q and %v", source, gotOut, gotErr, wantOut, wantErr)
+		}
+	}
+}
+
+// encodeWithRun returns the .loxc encoding of code, without constants or sites, and with a line table of
+// one run of length run, which Marshal cannot write when it does not match the code.
+func encodeWithRun(code []byte, run int) []byte {
+	var b bytes.Buffer
+	b.WriteString(magic)
+	b.WriteByte(fileVersion)
+	putBytes(&b, code)
+	putUvarint(&b, 0)
+	putUvarint(&b, 0)
+	putUvarint(&b, 1)
+	putUvarint(&b, uint64(run))
+	putVarint(&b, 0)
+	putVarint(&b, 1)
+	putUvarint(&b, 0)
+	putUvarint(&b, 1)
+	putBytes(&b, nil)
+	return b.Bytes()
+}
+
+func TestUnmarshalRejects(t *testing.T) {
+	op := func(ops ...OpCode) []byte {
+		code := make([]byte, len(ops))
+		for i, op := range ops {
+			code[i] = byte(op)
+		}
+		return code
+	}
+	// chunk returns a chunk of code, with one span for all of it
+	chunk := func(code []byte, constants ...interp.Value) *Chunk {
+		return &Chunk{Code: code, Constants: constants, spans: spanTable{newSpanRun(0, token.Span{Line: 1})}}
+	}
+	tests := []struct {
+		name  string
+		chunk *Chunk
+		want  string
+	}{
+		{"bad opcode", chunk([]byte{byte(OpReturn) + 1}), "unknown opcode"},
+		{"truncated instruction", chunk(op(OpNil, OpConstant)), "truncated instruction at 1"},
+		{"constant out of range", chunk([]byte{byte(OpConstant), 0, 5, byte(OpPrint), byte(OpReturn)}, interp.Int(1)), "constant 5 out of range at 0"},
+		{"name that is not a string", chunk([]byte{byte(OpGetGlobal), 0, 0, byte(OpPrint), byte(OpReturn)}, interp.Int(1)), "name that is not a string at 0"},
+		{"property site out of range", chunk([]byte{byte(OpNil), byte(OpGetProperty), 0, 0, 0, 0, byte(OpPop), byte(OpReturn)}, interp.String("a")), "property site out of range at 1"},
+		{"jump into an operand", chunk([]byte{byte(OpJump), 0, 1, byte(OpConstant), 0, 0, byte(OpPop), byte(OpReturn)}, interp.Int(1)), "jump at 0 that does not land on an instruction"},
+		{"missing OpReturn", chunk(op(OpNil, OpPop)), "code that does not end with OpReturn"},
+		{"empty code", chunk(nil), "line table that does not match the code"},
+		{"stack underflow", chunk(op(OpNil, OpAdd, OpPop, OpReturn)), "instruction at 1 that pops 2 values of 1"},
+		{"stacks that differ at a jump target", chunk([]byte{byte(OpTrue), byte(OpJumpIfFalse), 0, 1, byte(OpNil), byte(OpPop), byte(OpReturn)}), "stack of 1 and 2 values at 5"},
+	}
+	for _, test := range tests {
+		data, err := Marshal(test.chunk, "")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if _, _, err := Unmarshal(data); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: Unmarshal failed with %v, want an error with %q", test.name, err, test.want)
+		}
+	}
+
+	code := op(OpNil, OpPop, OpReturn)
+	if _, _, err := Unmarshal(encodeWithRun(code, len(code))); err != nil {
+		t.Errorf("a line table of the whole code failed with %v", err)
+	}
+	if _, _, err := Unmarshal(encodeWithRun(code, len(code)-1)); err == nil || !strings.Contains(err.Error(), "line table shorter than the code") {
+		t.Errorf("a line table shorter than the code failed with %v", err)
+	}
+	if _, _, err := Unmarshal(encodeWithRun(code, len(code)+1)); err == nil || !strings.Contains(err.Error(), "line table that does not match the code") {
+		t.Errorf("a line table longer than the code failed with %v", err)
+	}
+
+	data, err := Marshal(compileSource(t, "print 1;"), "print 1;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixes := map[string]string{"LOXD": "not a compiled Lox chunk", magic + "\x07": "of another version"}
+	for prefix, want := range prefixes {
+		if _, _, err := Unmarshal(append([]byte(prefix), data[len(prefix):]...)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: Unmarshal failed with %v, want an error with %q", prefix, err, want)
+		}
+	}
+	for _, corrupt := range [][]byte{data[:len(data)-1], append(data[:len(data):len(data)], 0)} {
+		if _, _, err := Unmarshal(corrupt); err == nil || !strings.Contains(err.Error(), "corrupt compiled Lox chunk") {
+			t.Errorf("%d bytes of %d: Unmarshal failed with %v", len(corrupt), len(data), err)
+		}
+	}
+}
+
+// FuzzUnmarshal checks that Unmarshal returns an error rather than a chunk the VM panics on, whatever the
+// data, and that the chunks it returns encode to data it decodes again. It is seeded with the chunks of
+// the seeds of FuzzParse.
+func FuzzUnmarshal(f *testing.F) {
+	for _, seed := range parser.FuzzSeeds {
+		statements, errs := parser.ParseSource([]byte(seed))
+		if len(errs) > 0 {
+			continue
+		}
+		chunk, err := Compile(statements)
+		if err != nil {
+			continue
+		}
+		data, err := Marshal(chunk, seed)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		chunk, source, err := Unmarshal(data)
+		if err != nil {
+			return
+		}
+		again, err := Marshal(chunk, source)
+		if err != nil {
+			t.Fatalf("a decoded chunk does not encode: %v", err)
+		}
+		if _, _, err := Unmarshal(again); err != nil {
+			t.Fatalf("a decoded chunk encodes to data that does not decode: %v", err)
+		}
+		// the code may call any builtin, like sleep, so the run has limits and a deadline
+		intr := interp.New()
+		intr.Stdin, intr.Stdout, intr.Stderr = strings.NewReader(""), io.Discard, io.Discard
+		intr.MaxSteps, intr.MaxMemory = 10000, 1<<20
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		New(intr).RunContext(ctx, chunk)
+	})
+}
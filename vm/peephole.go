@@ -0,0 +1,56 @@
+package vm
+
+import "github.com/gadumitrachioaiei/go-lox/token"
+
+// optimize is a peephole pass over the code of chunk, whose spans are those of each of its bytes. It rewrites instructions in place without
+// moving any, so that no jump offset needs adjusting:
+//
+//   - a jump that lands on a jump that must be taken too goes straight to the target of the second one,
+//     as in a and b and c, where a false a jumps to the second and, then past it;
+//   - a constant followed by a binary operator, as in n + 1, becomes an OpBinaryConstant of the same size,
+//     unless a jump lands on the operator.
+func optimize(chunk *Chunk, spans []token.Span) {
+	code := chunk.Code
+	var starts []int
+	for ip := 0; ip < len(code); ip += OpCode(code[ip]).size() {
+		starts = append(starts, ip)
+	}
+	targets := make(map[int]bool)
+	for _, ip := range starts {
+		op := OpCode(code[ip])
+		if op != OpJump && op != OpJumpIfFalse && op != OpJumpIfNotNil {
+			continue
+		}
+		target := jumpTarget(code, ip)
+		// a conditional jump leaves the value it tested on the stack, so the same jump after it is taken too;
+		// jumps only go forward, so this ends
+		for target < len(code) && (OpCode(code[target]) == OpJump || OpCode(code[target]) == op) {
+			target = jumpTarget(code, target)
+		}
+		offset := target - ip - 3
+		code[ip+1], code[ip+2] = byte(offset>>8), byte(offset)
+		targets[target] = true
+	}
+	for i, ip := range starts[:len(starts)-1] {
+		next := starts[i+1]
+		if OpCode(code[ip]) != OpConstant || !isBinary(OpCode(code[next])) || targets[next] {
+			continue
+		}
+		// the index of the constant stays where it is, and the operator becomes the last operand
+		code[ip] = byte(OpBinaryConstant)
+		// its errors are those of the operator
+		for i := ip; i < next; i++ {
+			spans[i] = spans[next]
+		}
+	}
+}
+
+// jumpTarget returns where the jump at ip goes.
+func jumpTarget(code []byte, ip int) int {
+	return ip + 3 + (int(code[ip+1])<<8 | int(code[ip+2]))
+}
+
+// isBinary reports whether op is the opcode of a binary operator.
+func isBinary(op OpCode) bool {
+	return op >= OpAdd && op <= OpLessEqual
+}
@@ -0,0 +1,57 @@
+package vm
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+// TestOptimize compares the disassembly of each program before and after the peephole pass with the
+// golden file of its name in testdata/peephole, which go test -update rewrites.
+func TestOptimize(t *testing.T) {
+	tests := []struct {
+		name, source string
+	}{
+		{"and_chain", "var a = true; var b = true; var c = false; print a and b and c;"},
+		{"or_chain", "var a = false; var b = false; var c = 1; print a or b or c;"},
+		{"coalesce_chain", "var a; var b; var c = 1; print a ?? b ?? c;"},
+		{"mixed_logical", "var a = true; var b = false; var c = 1; print a and b or c;"},
+		{"binary_constant", "var n = 2; print n + 1; print n * 2 - 3; print 10 / n < 4.5;"},
+		{"constant_before_jump_target", "var x; var y = 1; print y - (x ?? 2);"},
+		{"constant_operands", "print 1 + 2; print 3 * 4 == 12;"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			statements := parse(t, test.source)
+			before, err := compile(&compiler{chunk: &Chunk{}, names: make(map[string]int), unoptimized: true}, statements)
+			if err != nil {
+				t.Fatal(err)
+			}
+			after, err := Compile(statements)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := "; before\n" + Disassemble(before, test.source) + "; after\n" + Disassemble(after, test.source)
+			path := filepath.Join("testdata", "peephole", test.name+".golden")
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("the disassembly of %s is\n%s\nwant\n%s", test.source, got, want)
+			}
+			// the pass must not change what the program does
+			if out, err := runBoth(t, test.source); err != nil {
+				t.Errorf("%s printed %q and failed with %v", test.source, out, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package vm
+
+import (
+	"sort"
+
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// spanTable maps the code of a chunk to the spans of its source: the tokens whose errors its instructions
+// report, like the operator of a binary expression or the name of a variable. The bytes of an instruction,
+// and often the instructions of a token, have the same span, so it keeps runs of code with one span, in
+// the order of the code, rather than one span for each byte.
+type spanTable []spanRun
+
+// spanRun is the span of the code from start up to the start of the next run. Its numbers fit 32 bits, as
+// the code and the source do, which halves the table.
+type spanRun struct {
+	start                             uint32
+	startOffset, endOffset, line, col uint32
+}
+
+// compressSpans returns the table of spans, the span of each byte of the code.
+func compressSpans(spans []token.Span) spanTable {
+	var table spanTable
+	for i, span := range spans {
+		if i == 0 || span != spans[i-1] {
+			table = append(table, newSpanRun(i, span))
+		}
+	}
+	return table
+}
+
+// at returns the span of the byte of the code at ip.
+func (t spanTable) at(ip int) token.Span {
+	if i := t.index(ip, -1); i >= 0 {
+		return t[i].span()
+	}
+	return token.Span{}
+}
+
+// index returns the run of the byte of the code at ip, or -1 for no run. hint is a run before ip, like that
+// of an instruction run before, or -1 to search for it: the jumps only go forward, so the runs after the hint
+// are walked, in as many steps as the runs the code goes through.
+func (t spanTable) index(ip, hint int) int {
+	if hint < 0 || hint >= len(t) || int(t[hint].start) > ip {
+		return sort.Search(len(t), func(i int) bool { return int(t[i].start) > ip }) - 1
+	}
+	for hint+1 < len(t) && int(t[hint+1].start) <= ip {
+		hint++
+	}
+	return hint
+}
+
+func newSpanRun(start int, span token.Span) spanRun {
+	return spanRun{
+		start:       uint32(start),
+		startOffset: uint32(span.StartOffset), endOffset: uint32(span.EndOffset),
+		line: uint32(span.Line), col: uint32(span.Col),
+	}
+}
+
+func (r spanRun) span() token.Span {
+	return token.Span{StartOffset: int(r.startOffset), EndOffset: int(r.endOffset), Line: int(r.line), Col: int(r.col)}
+}
+
+// length returns the number of bytes of the run i of the table of a code of size bytes.
+func (t spanTable) length(i, size int) int {
+	if i+1 < len(t) {
+		return int(t[i+1].start - t[i].start)
+	}
+	return size - int(t[i].start)
+}
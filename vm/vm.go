@@ -0,0 +1,419 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/token"
+)
+
+// VM runs chunks on a stack of values. It runs them with the options and the global variables of its
+// interpreter, so that it can take over from it, as in a REPL.
+type VM struct {
+	intr  interp.Interpreter
+	stack []interp.Value
+	// caches are the caches of the property sites of cached, the last chunk run, and slots are the slots
+	// in its globals of the names of globals, by the index of their constant, plus one so that 0 is not
+	// looked up yet. They are kept for when the chunk is run again with the same globals, as a host may do.
+	cached        *Chunk
+	cachedGlobals *interp.Environment
+	caches        []interp.PropertyCache
+	slots         []int
+	// tracing is 1 while trace, which holds a traceWriter, is to be written to. It is read before each
+	// instruction, so it is atomic for SetTrace to be called while the VM runs.
+	tracing uint32
+	trace   atomic.Value
+}
+
+// traceWriter is the writer of the trace, in a struct as atomic.Value needs one type.
+type traceWriter struct {
+	w io.Writer
+}
+
+// New returns a VM that runs code like intr. When intr has no globals, as its zero value,
+// every run has new globals.
+func New(intr interp.Interpreter) *VM {
+	return &VM{intr: intr}
+}
+
+// SetTrace makes the VM write each instruction to w as it runs it, with the frame it runs in and the
+// stack it finds, like the DEBUG_TRACE_EXECUTION of clox, or stop with a nil w. It can be called at any
+// time, from any goroutine, and takes effect from the next instruction:
+//
+//	0009 OpBinaryConstant    2 1 OpAdd   | script, line 2 | [ 3 ]
+func (vm *VM) SetTrace(w io.Writer) {
+	if w == nil {
+		atomic.StoreUint32(&vm.tracing, 0)
+		return
+	}
+	vm.trace.Store(traceWriter{w})
+	atomic.StoreUint32(&vm.tracing, 1)
+}
+
+// Interpret compiles and runs statements, until the first runtime error, which it returns.
+func (vm *VM) Interpret(statements []ast.Stmt) error {
+	return vm.InterpretContext(context.Background(), statements)
+}
+
+// InterpretContext is Interpret, but it stops with the error of ctx as soon as ctx is done, like
+// interp.Interpreter.InterpretContext.
+func (vm *VM) InterpretContext(ctx context.Context, statements []ast.Stmt) error {
+	chunk, err := Compile(statements)
+	if err != nil {
+		return err
+	}
+	_, err = vm.RunContext(ctx, chunk)
+	return err
+}
+
+// InterpretExpression compiles and evaluates expr and returns its value formatted for printing.
+func (vm *VM) InterpretExpression(expr ast.Expr) (string, error) {
+	value, err := vm.Evaluate(expr)
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+// Evaluate compiles and evaluates expr and returns its value.
+func (vm *VM) Evaluate(expr ast.Expr) (interp.Value, error) {
+	chunk, err := CompileExpression(expr)
+	if err != nil {
+		return interp.Nil(), err
+	}
+	return vm.Run(chunk)
+}
+
+// operators are the tokens of the operators of the opcodes, which the interpreter needs to apply them.
+// They are indexed by opcode, as a map lookup would cost more than most instructions.
+var operators = func() (tokens [256]token.Token) {
+	lexemes := map[token.TokenType]string{
+		token.PLUS: "+", token.MINUS: "-", token.STAR: "*", token.SLASH: "/",
+		token.EQUAL_EQUAL: "==", token.BANG_EQUAL: "!=",
+		token.GREATER: ">", token.GREATER_EQUAL: ">=", token.LESS: "<", token.LESS_EQUAL: "<=",
+	}
+	tokens[OpNegate] = token.Token{Type: token.MINUS, Lexeme: "-"}
+	tokens[OpNot] = token.Token{Type: token.BANG, Lexeme: "!"}
+	for typ, op := range binaryOps {
+		tokens[op] = token.Token{Type: typ, Lexeme: lexemes[typ]}
+	}
+	return tokens
+}()
+
+// Run runs chunk and returns the value left on the stack when it returns, which is nil for statements.
+func (vm *VM) Run(chunk *Chunk) (interp.Value, error) {
+	return vm.RunContext(context.Background(), chunk)
+}
+
+// ctxCheckSteps are the instructions between two checks of the context of a run, as a check costs more
+// than most instructions.
+const ctxCheckSteps = 1024
+
+// RunContext is Run, but it stops with the error of ctx as soon as ctx is done, which it checks every
+// ctxCheckSteps instructions.
+func (vm *VM) RunContext(ctx context.Context, chunk *Chunk) (interp.Value, error) {
+	intr := vm.intr.BeginRunContext(ctx)
+	done := ctx.Done()
+	globals := intr.Globals()
+	vm.stack = vm.stack[:0]
+	code := chunk.Code
+	ip := 0
+	if vm.cached != chunk || vm.cachedGlobals != globals {
+		vm.cached, vm.cachedGlobals = chunk, globals
+		vm.caches = make([]interp.PropertyCache, chunk.sites)
+		vm.slots = make([]int, len(chunk.Constants))
+	}
+	// slot returns the slot of the global of the instruction at ip, looking it up the first time
+	slot := func(ip int) int {
+		index := int(code[ip+1])<<8 | int(code[ip+2])
+		if vm.slots[index] == 0 {
+			vm.slots[index] = globals.Slot(chunk.Constants[index].AsString()) + 1
+		}
+		return vm.slots[index] - 1
+	}
+	// span returns the span of the instruction at ip, from run, the run of spans of the last one
+	run := -1
+	span := func(ip int) token.Span {
+		if run = chunk.spans.index(ip, run); run < 0 {
+			return token.Span{}
+		}
+		return chunk.spans[run].span()
+	}
+	// cache returns the cache of the site of the property instruction at ip
+	cache := func(ip int) *interp.PropertyCache {
+		return &vm.caches[int(code[ip+3])<<8|int(code[ip+4])]
+	}
+	// operator returns the token of the operator of the instruction at ip
+	operator := func(op OpCode, ip int) token.Token {
+		tok := operators[op]
+		tok.Span = span(ip)
+		return tok
+	}
+	// name returns the token of the name of the global or the property of the instruction at ip
+	name := func(ip int) token.Token {
+		index := int(code[ip+1])<<8 | int(code[ip+2])
+		return token.Token{Type: token.IDENTIFIER, Lexeme: chunk.Constants[index].AsString(), Span: span(ip)}
+	}
+	steps, maxSteps := 0, intr.MaxSteps
+	if intr.Stats != nil {
+		defer func() { intr.Stats.Steps += steps }()
+	}
+	for {
+		steps++
+		if maxSteps > 0 && steps > maxSteps {
+			return interp.Nil(), interp.StepBudgetError(maxSteps, span(ip))
+		}
+		if done != nil && steps%ctxCheckSteps == 1 {
+			select {
+			case <-done:
+				return interp.Nil(), ctx.Err()
+			default:
+			}
+		}
+		if atomic.LoadUint32(&vm.tracing) != 0 {
+			vm.traceInstruction(chunk, ip)
+		}
+		op := OpCode(code[ip])
+		switch op {
+		case OpConstant:
+			vm.push(chunk.Constants[int(code[ip+1])<<8|int(code[ip+2])])
+			ip += 3
+		case OpNil:
+			vm.push(interp.Nil())
+			ip++
+		case OpTrue:
+			vm.push(interp.Bool(true))
+			ip++
+		case OpFalse:
+			vm.push(interp.Bool(false))
+			ip++
+		case OpPop:
+			vm.pop()
+			ip++
+		case OpDefineGlobal:
+			globals.DefineSlot(slot(ip), vm.pop())
+			ip += 3
+		case OpGetGlobal:
+			value, err := globals.GetSlot(slot(ip), name(ip))
+			if err != nil {
+				return interp.Nil(), err
+			}
+			vm.push(value)
+			ip += 3
+		case OpSetGlobal:
+			if err := globals.AssignSlot(slot(ip), name(ip), vm.peek(0)); err != nil {
+				return interp.Nil(), err
+			}
+			ip += 3
+		case OpGetProperty:
+			value, err := intr.GetPropertyCached(vm.pop(), name(ip), cache(ip))
+			if err != nil {
+				return interp.Nil(), err
+			}
+			vm.push(value)
+			ip += 5
+		case OpSetProperty:
+			value := vm.pop()
+			if err := intr.SetPropertyCached(vm.pop(), name(ip), value, cache(ip)); err != nil {
+				return interp.Nil(), err
+			}
+			vm.push(value)
+			ip += 5
+		case OpAdd, OpSubtract, OpMultiply, OpDivide, OpEqual, OpNotEqual, OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+			right := vm.pop()
+			left := vm.pop()
+			value, ok := binaryFast(op, left, right)
+			if !ok {
+				var err error
+				if value, err = intr.Binary(operator(op, ip), left, right); err != nil {
+					return interp.Nil(), err
+				}
+			}
+			vm.push(value)
+			ip++
+		case OpBinaryConstant:
+			op := OpCode(code[ip+3])
+			right := chunk.Constants[int(code[ip+1])<<8|int(code[ip+2])]
+			left := vm.peek(0)
+			value, ok := binaryFast(op, left, right)
+			if !ok {
+				var err error
+				if value, err = intr.Binary(operator(op, ip), left, right); err != nil {
+					return interp.Nil(), err
+				}
+			}
+			vm.stack[len(vm.stack)-1] = value
+			ip += 4
+		case OpCompareChain:
+			right := vm.pop()
+			left := vm.pop()
+			value, err := intr.Binary(operator(OpCode(code[ip+1]), ip), left, right)
+			if err != nil {
+				return interp.Nil(), err
+			}
+			vm.push(right)
+			vm.push(value)
+			ip += 2
+		case OpNegate, OpNot:
+			value, err := intr.Unary(operator(op, ip), vm.pop())
+			if err != nil {
+				return interp.Nil(), err
+			}
+			vm.push(value)
+			ip++
+		case OpPrint:
+			fmt.Fprintln(intr.Output(), vm.pop())
+			ip++
+		case OpJump:
+			ip += 3 + (int(code[ip+1])<<8 | int(code[ip+2]))
+		case OpJumpIfFalse:
+			jump := 3
+			if !vm.peek(0).Truthy() {
+				jump += int(code[ip+1])<<8 | int(code[ip+2])
+			}
+			ip += jump
+		case OpJumpIfNotNil:
+			jump := 3
+			if !vm.peek(0).IsNil() {
+				jump += int(code[ip+1])<<8 | int(code[ip+2])
+			}
+			ip += jump
+		case OpCall, OpSpawn:
+			count := int(code[ip+1])
+			arguments := append([]interp.Value(nil), vm.stack[len(vm.stack)-count:]...)
+			callee := vm.stack[len(vm.stack)-count-1]
+			vm.stack = vm.stack[:len(vm.stack)-count-1]
+			paren := token.Token{Type: token.RIGHT_PAREN, Lexeme: ")", Span: span(ip)}
+			if op == OpSpawn {
+				if err := intr.Spawn(callee, arguments, paren); err != nil {
+					return interp.Nil(), err
+				}
+			} else {
+				value, err := intr.Call(callee, arguments, paren)
+				if err != nil {
+					return interp.Nil(), err
+				}
+				vm.push(value)
+			}
+			ip += 2
+		case OpReturn:
+			if len(vm.stack) == 0 {
+				return interp.Nil(), nil
+			}
+			return vm.pop(), nil
+		default:
+			panic(fmt.Sprintf("unknown opcode %d at %d", op, ip))
+		}
+	}
+}
+
+// binaryFast applies the binary operator of op to two numbers, as Interpreter.Binary would, without building
+// its token. It reports false for the other operands and for a division by zero, whose result depends on
+// the options.
+func binaryFast(op OpCode, left, right interp.Value) (interp.Value, bool) {
+	if left.Kind() == interp.IntKind && right.Kind() == interp.IntKind {
+		l, r := left.AsInt(), right.AsInt()
+		// an integer result that overflows is a float, as Binary gives it
+		switch op {
+		case OpAdd:
+			if n, ok := interp.AddInt(l, r); ok {
+				return interp.Int(n), true
+			}
+			return interp.Float(float64(l) + float64(r)), true
+		case OpSubtract:
+			if n, ok := interp.SubInt(l, r); ok {
+				return interp.Int(n), true
+			}
+			return interp.Float(float64(l) - float64(r)), true
+		case OpMultiply:
+			if n, ok := interp.MulInt(l, r); ok {
+				return interp.Int(n), true
+			}
+			return interp.Float(float64(l) * float64(r)), true
+		case OpDivide:
+			if r == 0 {
+				return interp.Value{}, false
+			}
+			if n, ok := interp.DivInt(l, r); ok {
+				return interp.Int(n), true
+			}
+			return interp.Float(float64(l) / float64(r)), true
+		case OpEqual:
+			return interp.Bool(l == r), true
+		case OpNotEqual:
+			return interp.Bool(l != r), true
+		case OpGreater:
+			return interp.Bool(l > r), true
+		case OpGreaterEqual:
+			return interp.Bool(l >= r), true
+		case OpLess:
+			return interp.Bool(l < r), true
+		case OpLessEqual:
+			return interp.Bool(l <= r), true
+		}
+		return interp.Value{}, false
+	}
+	if !left.IsNumber() || !right.IsNumber() {
+		return interp.Value{}, false
+	}
+	// an integer with a float is a float operation
+	l, r := left.AsFloat(), right.AsFloat()
+	switch op {
+	case OpAdd:
+		return interp.Float(l + r), true
+	case OpSubtract:
+		return interp.Float(l - r), true
+	case OpMultiply:
+		return interp.Float(l * r), true
+	case OpDivide:
+		if r == 0 {
+			return interp.Value{}, false
+		}
+		return interp.Float(l / r), true
+	case OpEqual:
+		return interp.Bool(l == r), true
+	case OpNotEqual:
+		return interp.Bool(l != r), true
+	case OpGreater:
+		return interp.Bool(l > r), true
+	case OpGreaterEqual:
+		return interp.Bool(l >= r), true
+	case OpLess:
+		return interp.Bool(l < r), true
+	case OpLessEqual:
+		return interp.Bool(l <= r), true
+	}
+	return interp.Value{}, false
+}
+
+// traceInstruction writes the line of the trace of the instruction at ip. The VM only runs the code of a
+// script, and its calls leave it, so the frame is the script.
+func (vm *VM) traceInstruction(chunk *Chunk, ip int) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-36s | script, line %d |", disassembleInstruction(chunk, ip), chunk.Span(ip).Line)
+	for _, value := range vm.stack {
+		fmt.Fprintf(&b, " [ %s ]", formatValue(value))
+	}
+	b.WriteByte('\n')
+	io.WriteString(vm.trace.Load().(traceWriter).w, b.String())
+}
+
+func (vm *VM) push(value interp.Value) {
+	vm.stack = append(vm.stack, value)
+}
+
+func (vm *VM) pop() interp.Value {
+	value := vm.stack[len(vm.stack)-1]
+	vm.stack = vm.stack[:len(vm.stack)-1]
+	return value
+}
+
+// peek returns the value distance slots below the top of the stack.
+func (vm *VM) peek(distance int) interp.Value {
+	return vm.stack[len(vm.stack)-1-distance]
+}
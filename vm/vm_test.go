@@ -0,0 +1,250 @@
+package vm
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+)
+
+// parse scans and parses source, with the keywords of the extensions.
+func parse(t testing.TB, source string) []ast.Stmt {
+	t.Helper()
+	s := scanner.NewWithConfig(source, scanner.Config{Keywords: scanner.Keywords()})
+	tokens, errs := s.ScanTokens()
+	if len(errs) > 0 {
+		t.Fatalf("scanning %q: %v", source, errs[0])
+	}
+	statements, errs := parser.New(tokens).Parse()
+	if len(errs) > 0 {
+		t.Fatalf("parsing %q: %v", source, errs[0])
+	}
+	return statements
+}
+
+// backends are the two ways to run code, which must print the same and fail the same.
+var backends = []struct {
+	name string
+	run  func(intr interp.Interpreter, statements []ast.Stmt) error
+}{
+	{"tree", func(intr interp.Interpreter, statements []ast.Stmt) error { return intr.Interpret(statements) }},
+	{"vm", func(intr interp.Interpreter, statements []ast.Stmt) error { return New(intr).Interpret(statements) }},
+}
+
+// runBoth runs source on each backend, and returns what it printed and its error, which must be the same.
+func runBoth(t *testing.T, source string) (string, error) {
+	t.Helper()
+	statements := parse(t, source)
+	var outputs []string
+	var errs []error
+	for _, backend := range backends {
+		var out bytes.Buffer
+		intr := interp.New()
+		intr.Stdout = &out
+		err := backend.run(intr, statements)
+		outputs, errs = append(outputs, out.String()), append(errs, err)
+	}
+	if outputs[0] != outputs[1] || (errs[0] == nil) != (errs[1] == nil) || (errs[0] != nil && errs[0].Error() != errs[1].Error()) {
+		t.Errorf("%s: the tree printed %q and failed with %v, the vm printed %q and failed with %v", source, outputs[0], errs[0], outputs[1], errs[1])
+	}
+	return outputs[0], errs[0]
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		source, want string
+	}{
+		{"print 9223372036854775807 + 1;", "9223372036854776000\n"},
+		{"var a = -9223372036854775807; print a - 2;", "-9223372036854776000\n"},
+		{"var a = 4611686018427387904; print a * 2;", "9223372036854776000\n"},
+		{"var m = -9223372036854775807 - 1; print -m; print m / -1; print m / 1;", "9223372036854776000\n9223372036854776000\n-9223372036854775808\n"},
+		{"var a = 9223372036854775806; print a + 1;", "9223372036854775807\n"},
+	}
+	for _, test := range tests {
+		got, err := runBoth(t, test.source)
+		if err != nil || got != test.want {
+			t.Errorf("%s printed %q and failed with %v, want %q", test.source, got, err, test.want)
+		}
+	}
+}
+
+func TestFloatEdges(t *testing.T) {
+	source := "print nan != nan; print nan == nan; print nan < 1; print -0.0 == 0; print negativeZero == 0; print inf; print -inf; print inf - inf;"
+	want := "true\nfalse\nfalse\ntrue\ntrue\nInfinity\n-Infinity\nNaN\n"
+	statements := parse(t, source)
+	for _, backend := range backends {
+		var out bytes.Buffer
+		intr := interp.New()
+		intr.Stdout = &out
+		intr.Globals().Define("nan", interp.Float(math.NaN()))
+		intr.Globals().Define("inf", interp.Float(math.Inf(1)))
+		intr.Globals().Define("negativeZero", interp.Float(math.Copysign(0, -1)))
+		if err := backend.run(intr, statements); err != nil || out.String() != want {
+			t.Errorf("the %s printed %q and failed with %v, want %q", backend.name, out.String(), err, want)
+		}
+	}
+}
+
+// arithmeticSource is the program of tool/vmbench: arithmetic, comparisons, logical operators and globals.
+func arithmeticSource(statements int) string {
+	var source strings.Builder
+	source.WriteString("var a = 1; var b = 2.5; var c = 0; var d = false;\n")
+	for i := 0; i < statements; i++ {
+		switch i % 4 {
+		case 0:
+			source.WriteString("c = c + a * 2 - 1;\n")
+		case 1:
+			source.WriteString("b = b * 0.5 + c / 4;\n")
+		case 2:
+			source.WriteString("d = (c < 100 and a <= c) ?? d;\n")
+		default:
+			source.WriteString("d = d == true or c > 3;\n")
+		}
+	}
+	return source.String()
+}
+
+// stringSource is a program that concatenates strings and calls the string builtins.
+func stringSource(statements int) string {
+	var source strings.Builder
+	source.WriteString(`var s = "lox"; var n = 0;` + "\n")
+	for i := 0; i < statements; i++ {
+		switch i % 3 {
+		case 0:
+			source.WriteString(`s = s + "abc";` + "\n")
+		case 1:
+			source.WriteString("n = n + len(s);\n")
+		default:
+			source.WriteString(`s = substr(replace(s, "a", "b"), 3, len(s));` + "\n")
+		}
+	}
+	return source.String()
+}
+
+// benchmarkRun runs the chunk of source in new globals for each iteration, and reports the instructions
+// it runs.
+func benchmarkRun(b *testing.B, source string) {
+	chunk, err := Compile(parse(b, source))
+	if err != nil {
+		b.Fatal(err)
+	}
+	stats := &interp.Stats{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intr := interp.New()
+		intr.Stats = stats
+		if _, err := New(intr).Run(chunk); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(stats.Steps)/float64(b.N), "steps/op")
+}
+
+func BenchmarkRunArithmetic(b *testing.B) {
+	benchmarkRun(b, arithmeticSource(1000))
+}
+
+func BenchmarkRunStrings(b *testing.B) {
+	benchmarkRun(b, stringSource(1000))
+}
+
+// benchmarkInterpret runs the statements of source on the tree-walking interpreter, in new globals for each
+// iteration, and reports the nodes it evaluates.
+func benchmarkInterpret(b *testing.B, source string) {
+	statements := parse(b, source)
+	stats := &interp.Stats{}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		intr := interp.New()
+		intr.Stats = stats
+		if err := intr.Interpret(statements); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(stats.Steps)/float64(b.N), "steps/op")
+}
+
+func BenchmarkInterpretArithmetic(b *testing.B) {
+	benchmarkInterpret(b, arithmeticSource(1000))
+}
+
+func BenchmarkInterpretStrings(b *testing.B) {
+	benchmarkInterpret(b, stringSource(1000))
+}
+
+func BenchmarkScanAndParse(b *testing.B) {
+	source := arithmeticSource(1000)
+	b.SetBytes(int64(len(source)))
+	for i := 0; i < b.N; i++ {
+		parse(b, source)
+	}
+}
+
+func BenchmarkCompile(b *testing.B) {
+	statements := parse(b, arithmeticSource(1000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Compile(statements); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// contextBackends are the two ways to run code with a context.
+var contextBackends = []struct {
+	name string
+	run  func(ctx context.Context, intr interp.Interpreter, statements []ast.Stmt) error
+}{
+	{"tree", func(ctx context.Context, intr interp.Interpreter, statements []ast.Stmt) error {
+		return intr.InterpretContext(ctx, statements)
+	}},
+	{"vm", func(ctx context.Context, intr interp.Interpreter, statements []ast.Stmt) error {
+		return New(intr).InterpretContext(ctx, statements)
+	}},
+}
+
+func TestContext(t *testing.T) {
+	// cancel stops the run from the code, and the statements after it would take long to run
+	source := `print 1; cancel();` + arithmeticSource(5000) + `print 2;`
+	statements := parse(t, source)
+	for _, backend := range contextBackends {
+		ctx, cancel := context.WithCancel(context.Background())
+		var out bytes.Buffer
+		intr := interp.New()
+		intr.Stdout = &out
+		if err := intr.Register("cancel", cancel); err != nil {
+			t.Fatal(err)
+		}
+		if err := backend.run(ctx, intr, statements); err != context.Canceled || out.String() != "1\n" {
+			t.Errorf("the %s printed %q and failed with %v, want %q and %v", backend.name, out.String(), err, "1\n", context.Canceled)
+		}
+
+		out.Reset()
+		if err := backend.run(ctx, intr, parse(t, "print 1;")); err != context.Canceled || out.String() != "" {
+			t.Errorf("the %s printed %q and failed with %v in a done context", backend.name, out.String(), err)
+		}
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	statements := parse(t, "print 1; sleep(60000); print 2;")
+	for _, backend := range contextBackends {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		var out bytes.Buffer
+		intr := interp.New()
+		intr.Stdout = &out
+		start := time.Now()
+		err := backend.run(ctx, intr, statements)
+		cancel()
+		if err != context.DeadlineExceeded || out.String() != "1\n" || time.Since(start) > 10*time.Second {
+			t.Errorf("the %s printed %q and failed with %v after %v", backend.name, out.String(), err, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,144 @@
+//go:build js && wasm
+
+// Command wasm is the interpreter for JavaScript, as for an in-browser playground. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o lox.wasm ./wasm
+//
+// and load it with the wasm_exec.js of the Go distribution, in lib/wasm. It defines the global function
+//
+//	runLox(source, options) -> {output, errors, exitCode}
+//
+// which runs the Lox code source and returns what it printed, as a string, its errors, as an array
+// of strings formatted like those of the CLI, and the status it gave exit, or 0 if it did not call it.
+// options may be left out; its fields are:
+//
+//	backend        "tree", the default, or "vm"
+//	coerceStrings  like the -coerce-strings flag
+//	maxSteps       like the -max-steps flag, to stop code that runs too long
+//	onPrint        a function called with the output as it is printed, before runLox returns
+//	onError        a function called with each error, as it is reported
+//
+// The tasks of spawn may print after runLox returns, only through onPrint and onError.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall/js"
+
+	"github.com/gadumitrachioaiei/go-lox/ast"
+	"github.com/gadumitrachioaiei/go-lox/diag"
+	"github.com/gadumitrachioaiei/go-lox/interp"
+	"github.com/gadumitrachioaiei/go-lox/parser"
+	"github.com/gadumitrachioaiei/go-lox/scanner"
+	"github.com/gadumitrachioaiei/go-lox/vm"
+)
+
+func main() {
+	js.Global().Set("runLox", js.FuncOf(runLox))
+	// the functions of Go can only be called while its program runs
+	select {}
+}
+
+// backend runs code: the tree-walking interpreter or the bytecode VM.
+type backend interface {
+	Interpret(statements []ast.Stmt) error
+}
+
+func runLox(this js.Value, args []js.Value) interface{} {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return js.Global().Get("Error").New("runLox needs the source to run, a string")
+	}
+	source := args[0].String()
+	options := js.Undefined()
+	if len(args) > 1 {
+		options = args[1]
+	}
+	option := func(name string) js.Value {
+		if options.Type() != js.TypeObject {
+			return js.Undefined()
+		}
+		return options.Get(name)
+	}
+
+	var output bytes.Buffer
+	errs := []interface{}{}
+	onPrint, onError := option("onPrint"), option("onError")
+	report := func(message string) {
+		errs = append(errs, message)
+		if onError.Type() == js.TypeFunction {
+			onError.Invoke(message)
+		}
+	}
+
+	intr := interp.New()
+	intr.Stdout = &output
+	if onPrint.Type() == js.TypeFunction {
+		intr.Stdout = io.MultiWriter(&output, callbackWriter{onPrint})
+	}
+	intr.Stderr = lineWriter{report}
+	if coerce := option("coerceStrings"); coerce.Type() == js.TypeBoolean {
+		intr.CoerceStrings = coerce.Bool()
+	}
+	if steps := option("maxSteps"); steps.Type() == js.TypeNumber {
+		intr.MaxSteps = steps.Int()
+	}
+	var runner backend = intr
+	switch name := option("backend"); {
+	case name.Type() != js.TypeString || name.String() == "tree":
+	case name.String() == "vm":
+		runner = vm.New(intr)
+	default:
+		return js.Global().Get("Error").New(fmt.Sprintf("unknown backend %q", name.String()))
+	}
+
+	exitCode := 0
+	s := scanner.NewWithConfig(source, scanner.Config{Keywords: scanner.Keywords()})
+	tokens, scanErrs := s.ScanTokens()
+	for _, err := range scanErrs {
+		report(diag.Format(source, err))
+	}
+	if len(scanErrs) == 0 {
+		statements, parseErrs := parser.New(tokens).Parse()
+		for _, err := range parseErrs {
+			report(diag.Format(source, err))
+		}
+		if len(parseErrs) == 0 {
+			var exit interp.ExitError
+			if err := runner.Interpret(statements); errors.As(err, &exit) {
+				exitCode = exit.Code
+			} else if err != nil {
+				message := diag.Format(source, err)
+				var re interp.RuntimeError
+				if errors.As(err, &re) && len(re.Trace) > 0 {
+					message += "\n" + strings.TrimSuffix(re.StackTrace(), "\n")
+				}
+				report(message)
+			}
+		}
+	}
+	return map[string]interface{}{"output": output.String(), "errors": errs, "exitCode": exitCode}
+}
+
+// callbackWriter writes by calling a JavaScript function with the text.
+type callbackWriter struct {
+	fn js.Value
+}
+
+func (w callbackWriter) Write(p []byte) (int, error) {
+	w.fn.Invoke(string(p))
+	return len(p), nil
+}
+
+// lineWriter reports each write, a line of diagnostics, as an error without its newline.
+type lineWriter struct {
+	report func(message string)
+}
+
+func (w lineWriter) Write(p []byte) (int, error) {
+	w.report(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}